@@ -1,8 +1,12 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"strings"
+
+	"ordernew/money"
 
 	"github.com/joho/godotenv"
 )
@@ -13,12 +17,43 @@ type Config struct {
 	DatabaseName string
 	JWTSecret   string
 	JWTExpiry   string
+	JWTKeysDir  string
+	JWTSigningKID string
+	RefreshTokenTTL string
 	GinMode     string
 	APIVersion  string
+	CartTTL     string
+	TableTokenSecret string
+	MenuBaseURL string
+	AppBaseURL  string
+	MailDriver  string
+	MailFrom    string
+	MailOutboxDir string
+	SMTPHost    string
+	SMTPPort    string
+	SMTPUsername string
+	SMTPPassword string
+	SMSDriver   string
+	TwilioAccountSID string
+	TwilioAuthToken string
+	TwilioFrom  string
+	TOTPIssuer  string
+	DefaultTimezone string
+	DefaultCurrency string
+	DefaultLocale string
+	StoreSchedulerInterval string
+	PaymentProvider string
+	PaymentWebhookSecret string
 }
 
 var AppConfig *Config
 
+// defaultJWTSecret is the development fallback for JWT_SECRET
+const defaultJWTSecret = "your-secret-key"
+
+// placeholderSecretMarker is part of every example secret shipped in .env
+const placeholderSecretMarker = "change-this-in-production"
+
 // LoadConfig loads environment variables and initializes the application configuration
 func LoadConfig() {
 	// Load .env file
@@ -31,15 +66,75 @@ func LoadConfig() {
 		Port:        getEnv("PORT", "8080"),
 		MongoURI:    getEnv("MONGODB_URI", "mongodb://localhost:27017"),
 		DatabaseName: getEnv("DATABASE_NAME", "ordernew_db"),
-		JWTSecret:   getEnv("JWT_SECRET", "your-secret-key"),
-		JWTExpiry:   getEnv("JWT_EXPIRY", "24h"),
+		JWTSecret:   getEnv("JWT_SECRET", defaultJWTSecret),
+		JWTExpiry:   getEnv("JWT_EXPIRY", "15m"),
+		JWTKeysDir:  getEnv("JWT_KEYS_DIR", ""),
+		JWTSigningKID: getEnv("JWT_SIGNING_KID", ""),
+		RefreshTokenTTL: getEnv("REFRESH_TOKEN_TTL", "720h"),
 		GinMode:     getEnv("GIN_MODE", "debug"),
 		APIVersion:  getEnv("API_VERSION", "v1"),
+		CartTTL:     getEnv("CART_TTL", "2h"),
+		MenuBaseURL: getEnv("MENU_BASE_URL", "http://localhost:3000/menu"),
+		AppBaseURL:  getEnv("APP_BASE_URL", "http://localhost:3000"),
+		MailDriver:  getEnv("MAIL_DRIVER", "log"),
+		MailFrom:    getEnv("MAIL_FROM", "no-reply@localhost"),
+		MailOutboxDir: getEnv("MAIL_OUTBOX_DIR", ""),
+		SMTPHost:    getEnv("SMTP_HOST", ""),
+		SMTPPort:    getEnv("SMTP_PORT", "587"),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		SMSDriver:   getEnv("SMS_DRIVER", "console"),
+		TwilioAccountSID: getEnv("TWILIO_ACCOUNT_SID", ""),
+		TwilioAuthToken: getEnv("TWILIO_AUTH_TOKEN", ""),
+		TwilioFrom:  getEnv("TWILIO_FROM", ""),
+		TOTPIssuer:  getEnv("TOTP_ISSUER", "OrderSystem"),
+		DefaultTimezone: getEnv("DEFAULT_TIMEZONE", "UTC"),
+		DefaultCurrency: getEnv("DEFAULT_CURRENCY", "USD"),
+		DefaultLocale: getEnv("DEFAULT_LOCALE", "en-US"),
+		StoreSchedulerInterval: getEnv("STORE_SCHEDULER_INTERVAL", "1m"),
+		PaymentProvider: getEnv("PAYMENT_PROVIDER", "mock"),
 	}
 
+	// Table QR tokens are signed with their own secret, falling back to the JWT secret
+	AppConfig.TableTokenSecret = getEnv("TABLE_TOKEN_SECRET", AppConfig.JWTSecret)
+	// Payment webhooks too; a real gateway issues its own secret
+	AppConfig.PaymentWebhookSecret = getEnv("PAYMENT_WEBHOOK_SECRET", AppConfig.JWTSecret)
+
 	log.Println("Configuration loaded successfully")
 }
 
+// ValidateConfig rejects settings that are unsafe in production
+func ValidateConfig() error {
+	// JWT_SECRET is also the fallback for the other signing secrets, so it must be set even with asymmetric JWT keys
+	if AppConfig.GinMode == "release" {
+		secrets := []struct{ name, value string }{
+			{"JWT_SECRET", AppConfig.JWTSecret},
+			{"TABLE_TOKEN_SECRET", AppConfig.TableTokenSecret},
+			{"PAYMENT_WEBHOOK_SECRET", AppConfig.PaymentWebhookSecret},
+		}
+		for _, secret := range secrets {
+			if isPlaceholderSecret(secret.value) {
+				return fmt.Errorf("%s must be changed from its default or example value when GIN_MODE=release", secret.name)
+			}
+		}
+	}
+	// Stores and products created without a currency or locale get these
+	currency, err := money.NormalizeCurrency(AppConfig.DefaultCurrency)
+	if err != nil {
+		return fmt.Errorf("DEFAULT_CURRENCY: %w", err)
+	}
+	AppConfig.DefaultCurrency = currency
+	if !money.ValidLocale(AppConfig.DefaultLocale) {
+		return fmt.Errorf("DEFAULT_LOCALE: unknown locale %q", AppConfig.DefaultLocale)
+	}
+	return nil
+}
+
+// isPlaceholderSecret reports whether a secret is the built-in default or one of the examples from .env
+func isPlaceholderSecret(secret string) bool {
+	return secret == defaultJWTSecret || strings.Contains(secret, placeholderSecretMarker)
+}
+
 // getEnv retrieves an environment variable or returns a default value
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
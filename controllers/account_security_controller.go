@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"net/http"
+
+	"ordernew/models"
+	"ordernew/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetMySessions lists the active sessions of the authenticated user
+// @Summary Get my sessions
+// @Description List the devices the current user is logged in on
+// @Tags users
+// @Produce json
+// @Success 200 {array} models.SessionResponse
+// @Router /users/me/sessions [get]
+func (c *UserController) GetMySessions(ctx *gin.Context) {
+	userID, exists := getAuthUserID(ctx)
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	sessions, err := services.GetActiveSessions(userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch sessions",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	currentSessionID := ctx.GetString("session_id")
+	sessionResponses := []models.SessionResponse{}
+	for _, session := range sessions {
+		sessionResponses = append(sessionResponses, session.ToSessionResponse(currentSessionID))
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Sessions retrieved successfully",
+		"count":   len(sessionResponses),
+		"data":    sessionResponses,
+	})
+}
+
+// RevokeMySession logs the authenticated user out of one of their sessions
+// @Summary Revoke a session
+// @Description Log out one device of the current user
+// @Tags users
+// @Param sessionId path string true "Session ID"
+// @Success 200 {object} map[string]string
+// @Router /users/me/sessions/{sessionId} [delete]
+func (c *UserController) RevokeMySession(ctx *gin.Context) {
+	userID, exists := getAuthUserID(ctx)
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	if err := services.RevokeSession(ctx.Param("sessionId"), userID); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Revoke failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Session revoked successfully",
+	})
+}
+
+// GetMyLoginEvents lists recent login attempts on the authenticated user's account
+// @Summary Get my login history
+// @Description List the 50 most recent successful and failed logins of the current user
+// @Tags users
+// @Produce json
+// @Success 200 {array} models.LoginEvent
+// @Router /users/me/login-events [get]
+func (c *UserController) GetMyLoginEvents(ctx *gin.Context) {
+	userID, exists := getAuthUserID(ctx)
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	events, err := services.GetLoginEvents(userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch login history",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Login history retrieved successfully",
+		"count":   len(events),
+		"data":    events,
+	})
+}
+
+// UnlockUser lifts the lock an account got from failed logins
+// @Summary Unlock user
+// @Description Clear a user's failed login count and lock
+// @Tags users
+// @Param id path string true "User ID"
+// @Success 200 {object} models.UserResponse
+// @Router /users/{id}/unlock [post]
+func (c *UserController) UnlockUser(ctx *gin.Context) {
+	user, err := services.UnlockUser(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Unlock failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "User unlocked successfully",
+		"data":    user,
+	})
+}
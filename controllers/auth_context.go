@@ -0,0 +1,30 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// getAuthUserID returns the authenticated user's ID from the context (set by auth middleware)
+func getAuthUserID(c *gin.Context) (primitive.ObjectID, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		return primitive.NilObjectID, false
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(userID.(string))
+	if err != nil {
+		return primitive.NilObjectID, false
+	}
+
+	return objectID, true
+}
+
+// getAuthRole returns the authenticated user's role from the context
+func getAuthRole(c *gin.Context) string {
+	role, exists := c.Get("role")
+	if !exists {
+		return ""
+	}
+	return role.(string)
+}
@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"ordernew/models"
+	"ordernew/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cartTokenHeader carries the session token of an anonymous cart
+const cartTokenHeader = "X-Cart-Token"
+
+// getCartAccess builds the caller's cart credentials from the request
+func getCartAccess(c *gin.Context) services.CartAccess {
+	customerID, _ := getAuthUserID(c)
+	return services.CartAccess{
+		CustomerID: customerID,
+		Token:      c.GetHeader(cartTokenHeader),
+	}
+}
+
+// respondCartError maps cart service errors to HTTP responses
+func respondCartError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrCartAccessDenied):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrCartNotActive), errors.Is(err, services.ErrPromotionUsedUp):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+// CreateCart handles opening a new cart for a store
+func CreateCart(c *gin.Context) {
+	var req models.CreateCartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	customerID, _ := getAuthUserID(c)
+
+	cart, token, err := services.CreateCart(req, customerID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Cart created successfully",
+		"cart_token": token,
+		"data":       localizedCart(c, cart),
+	})
+}
+
+// GetCart handles retrieving a cart priced with the current menu
+func GetCart(c *gin.Context) {
+	cart, err := services.GetCart(c.Param("id"), getCartAccess(c))
+	if err != nil {
+		if errors.Is(err, services.ErrCartAccessDenied) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Cart retrieved successfully",
+		"data":    localizedCart(c, cart),
+	})
+}
+
+// AddCartItem handles adding an item to a cart
+func AddCartItem(c *gin.Context) {
+	var req models.AddCartItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cart, err := services.AddCartItem(c.Param("id"), getCartAccess(c), req)
+	if err != nil {
+		respondCartError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Item added to cart successfully",
+		"data":    localizedCart(c, cart),
+	})
+}
+
+// UpdateCartItem handles updating a cart line
+func UpdateCartItem(c *gin.Context) {
+	var req models.UpdateCartItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cart, err := services.UpdateCartItem(c.Param("id"), c.Param("itemId"), getCartAccess(c), req)
+	if err != nil {
+		respondCartError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Cart item updated successfully",
+		"data":    localizedCart(c, cart),
+	})
+}
+
+// RemoveCartItem handles removing a line from a cart
+func RemoveCartItem(c *gin.Context) {
+	cart, err := services.RemoveCartItem(c.Param("id"), c.Param("itemId"), getCartAccess(c))
+	if err != nil {
+		respondCartError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Cart item removed successfully",
+		"data":    localizedCart(c, cart),
+	})
+}
+
+// ApplyCartPromoCode handles entering a promo code on a cart
+func ApplyCartPromoCode(c *gin.Context) {
+	var req models.ApplyPromoCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cart, err := services.ApplyCartPromoCode(c.Param("id"), getCartAccess(c), req)
+	if err != nil {
+		respondCartError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Promo code applied successfully",
+		"data":    localizedCart(c, cart),
+	})
+}
+
+// RemoveCartPromoCode handles removing the promo code of a cart
+func RemoveCartPromoCode(c *gin.Context) {
+	cart, err := services.RemoveCartPromoCode(c.Param("id"), getCartAccess(c))
+	if err != nil {
+		respondCartError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Promo code removed successfully",
+		"data":    localizedCart(c, cart),
+	})
+}
+
+// CheckoutCart handles turning a cart into an order
+func CheckoutCart(c *gin.Context) {
+	var req models.CheckoutCartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	order, err := services.CheckoutCart(c.Param("id"), getCartAccess(c), req)
+	if err != nil {
+		respondCartError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Order placed successfully",
+		"data":    localizedOrder(c, order),
+	})
+}
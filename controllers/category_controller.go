@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 
 	"ordernew/models"
@@ -19,6 +20,10 @@ func CreateCategory(c *gin.Context) {
 
 	category, err := services.CreateCategory(req)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidAvailability) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -101,6 +106,10 @@ func UpdateCategory(c *gin.Context) {
 
 	category, err := services.UpdateCategory(categoryID, req)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidAvailability) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"net/http"
+
+	"ordernew/models"
+	"ordernew/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateCombo handles combo creation
+func CreateCombo(c *gin.Context) {
+	var req models.CreateComboRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	combo, err := services.CreateCombo(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	respondCombo(c, http.StatusCreated, "Combo created successfully", combo)
+}
+
+// GetCombo handles retrieving a single combo
+func GetCombo(c *gin.Context) {
+	combo, err := services.GetComboByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	respondCombo(c, http.StatusOK, "Combo retrieved successfully", combo)
+}
+
+// GetCombosByStore handles retrieving all combos for a store
+func GetCombosByStore(c *gin.Context) {
+	comboResponses, err := services.GetComboResponsesByStore(c.Param("storeId"), false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Combos retrieved successfully",
+		"count":   len(comboResponses),
+		"data":    comboResponses,
+	})
+}
+
+// GetAvailableCombosByStore handles retrieving the combos of a store that can be ordered (for customers)
+func GetAvailableCombosByStore(c *gin.Context) {
+	comboResponses, err := services.GetComboResponsesByStore(c.Param("storeId"), true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Available combos retrieved successfully",
+		"count":   len(comboResponses),
+		"data":    comboResponses,
+	})
+}
+
+// UpdateCombo handles updating a combo
+func UpdateCombo(c *gin.Context) {
+	var req models.UpdateComboRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	combo, err := services.UpdateCombo(c.Param("id"), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	respondCombo(c, http.StatusOK, "Combo updated successfully", combo)
+}
+
+// DeleteCombo handles deleting a combo
+func DeleteCombo(c *gin.Context) {
+	err := services.DeleteCombo(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Combo deleted successfully",
+	})
+}
+
+// ToggleComboAvailability handles toggling combo availability
+func ToggleComboAvailability(c *gin.Context) {
+	combo, err := services.ToggleComboAvailability(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	respondCombo(c, http.StatusOK, "Combo availability updated successfully", combo)
+}
+
+// respondCombo sends a single combo with its components resolved
+func respondCombo(c *gin.Context, status int, message string, combo *models.Combo) {
+	comboResponses, err := services.BuildComboResponses([]models.Combo{*combo}, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(status, gin.H{
+		"message": message,
+		"data":    comboResponses[0],
+	})
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 
 	"ordernew/models"
@@ -19,6 +20,10 @@ func CreateFoodItem(c *gin.Context) {
 
 	foodItem, err := services.CreateFoodItem(req)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidAvailability) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -60,10 +65,18 @@ func GetFoodItemsByStore(c *gin.Context) {
 		foodItemResponses = append(foodItemResponses, foodItem.ToFoodItemResponse())
 	}
 
+	// Combos are listed next to the regular items
+	comboResponses, err := services.GetComboResponsesByStore(storeID, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Food items retrieved successfully",
 		"count":   len(foodItemResponses),
 		"data":    foodItemResponses,
+		"combos":  comboResponses,
 	})
 }
 
@@ -82,10 +95,18 @@ func GetFoodItemsByCategory(c *gin.Context) {
 		foodItemResponses = append(foodItemResponses, foodItem.ToFoodItemResponse())
 	}
 
+	// Combos are listed next to the regular items
+	comboResponses, err := services.GetComboResponsesByCategory(categoryID, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Food items retrieved successfully",
 		"count":   len(foodItemResponses),
 		"data":    foodItemResponses,
+		"combos":  comboResponses,
 	})
 }
 
@@ -104,10 +125,18 @@ func GetAvailableFoodItemsByStore(c *gin.Context) {
 		foodItemResponses = append(foodItemResponses, foodItem.ToFoodItemResponse())
 	}
 
+	// Combos are listed next to the regular items
+	comboResponses, err := services.GetComboResponsesByStore(storeID, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Available food items retrieved successfully",
 		"count":   len(foodItemResponses),
 		"data":    foodItemResponses,
+		"combos":  comboResponses,
 	})
 }
 
@@ -126,10 +155,18 @@ func GetAvailableFoodItemsByCategory(c *gin.Context) {
 		foodItemResponses = append(foodItemResponses, foodItem.ToFoodItemResponse())
 	}
 
+	// Combos are listed next to the regular items
+	comboResponses, err := services.GetComboResponsesByCategory(categoryID, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Available food items retrieved successfully",
 		"count":   len(foodItemResponses),
 		"data":    foodItemResponses,
+		"combos":  comboResponses,
 	})
 }
 
@@ -145,6 +182,10 @@ func UpdateFoodItem(c *gin.Context) {
 
 	foodItem, err := services.UpdateFoodItem(foodItemID, req)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidAvailability) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
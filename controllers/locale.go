@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"ordernew/config"
+	"ordernew/models"
+	"ordernew/money"
+	"ordernew/services"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/language"
+)
+
+// requestLocale picks the locale prices are formatted in: the locale query
+// parameter, then the caller's preferred Accept-Language, then fallback, and
+// finally DEFAULT_LOCALE
+func requestLocale(c *gin.Context, fallback string) string {
+	if locale := c.Query("locale"); money.ValidLocale(locale) {
+		return locale
+	}
+	if tags, _, err := language.ParseAcceptLanguage(c.GetHeader("Accept-Language")); err == nil && len(tags) > 0 {
+		return tags[0].String()
+	}
+	if fallback != "" {
+		return fallback
+	}
+	return config.AppConfig.DefaultLocale
+}
+
+// localizedCart prices a cart and formats its amounts for the caller
+func localizedCart(c *gin.Context, cart *models.Cart) models.CartResponse {
+	response := services.PriceCart(cart)
+	response.Localize(requestLocale(c, ""))
+	return response
+}
+
+// localizedOrder builds an order response with its amounts formatted for the caller
+func localizedOrder(c *gin.Context, order *models.Order) models.OrderResponse {
+	response := order.ToOrderResponse()
+	response.Localize(requestLocale(c, ""))
+	return response
+}
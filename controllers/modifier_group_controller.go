@@ -0,0 +1,164 @@
+package controllers
+
+import (
+	"net/http"
+
+	"ordernew/models"
+	"ordernew/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateModifierGroup handles modifier group creation
+func CreateModifierGroup(c *gin.Context) {
+	var req models.CreateModifierGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	group, err := services.CreateModifierGroup(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Modifier group created successfully",
+		"data":    group.ToModifierGroupResponse(),
+	})
+}
+
+// GetModifierGroup handles retrieving a single modifier group
+func GetModifierGroup(c *gin.Context) {
+	group, err := services.GetModifierGroupByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Modifier group retrieved successfully",
+		"data":    group.ToModifierGroupResponse(),
+	})
+}
+
+// GetModifierGroupsByStore handles retrieving all modifier groups for a store
+func GetModifierGroupsByStore(c *gin.Context) {
+	groups, err := services.GetModifierGroupsByStore(c.Param("storeId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var groupResponses []models.ModifierGroupResponse
+	for _, group := range groups {
+		groupResponses = append(groupResponses, group.ToModifierGroupResponse())
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Modifier groups retrieved successfully",
+		"count":   len(groupResponses),
+		"data":    groupResponses,
+	})
+}
+
+// GetFoodItemModifierGroups handles retrieving the modifier groups that apply to a food item,
+// including those inherited from its category
+func GetFoodItemModifierGroups(c *gin.Context) {
+	foodItem, err := services.GetFoodItemByID(c.Param("foodItemId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	groups, err := services.GetEffectiveModifierGroups(foodItem)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var groupResponses []models.ModifierGroupResponse
+	for _, group := range groups {
+		groupResponses = append(groupResponses, group.ToModifierGroupResponse())
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Modifier groups retrieved successfully",
+		"count":   len(groupResponses),
+		"data":    groupResponses,
+	})
+}
+
+// UpdateModifierGroup handles updating a modifier group
+func UpdateModifierGroup(c *gin.Context) {
+	var req models.UpdateModifierGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	group, err := services.UpdateModifierGroup(c.Param("id"), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Modifier group updated successfully",
+		"data":    group.ToModifierGroupResponse(),
+	})
+}
+
+// DeleteModifierGroup handles deleting a modifier group
+func DeleteModifierGroup(c *gin.Context) {
+	err := services.DeleteModifierGroup(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Modifier group deleted successfully",
+	})
+}
+
+// SetFoodItemModifierGroups handles attaching modifier groups to a food item
+func SetFoodItemModifierGroups(c *gin.Context) {
+	var req models.AttachModifierGroupsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	foodItem, err := services.SetFoodItemModifierGroups(c.Param("id"), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Food item modifier groups updated successfully",
+		"data":    foodItem.ToFoodItemResponse(),
+	})
+}
+
+// SetCategoryModifierGroups handles attaching modifier groups to a category
+func SetCategoryModifierGroups(c *gin.Context) {
+	var req models.AttachModifierGroupsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category, err := services.SetCategoryModifierGroups(c.Param("id"), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Category modifier groups updated successfully",
+		"data":    category.ToCategoryResponse(),
+	})
+}
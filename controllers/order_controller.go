@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"ordernew/models"
+	"ordernew/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateOrder handles placing a new order
+func CreateOrder(c *gin.Context) {
+	var req models.CreateOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Customer is optional: anonymous QR orders have no user
+	customerID, _ := getAuthUserID(c)
+
+	order, err := services.CreateOrder(req, customerID)
+	if err != nil {
+		if errors.Is(err, services.ErrPromotionUsedUp) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Order placed successfully",
+		"data":    localizedOrder(c, order),
+	})
+}
+
+// GetOrder handles retrieving a single order
+func GetOrder(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	order, err := services.GetOrderByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Only the customer, the store's staff or an admin may view an order
+	if order.CustomerID != userID {
+		if _, _, err := services.AuthorizeStoreAccess(order.StoreID.Hex(), userID, getAuthRole(c), models.PermissionOrdersRead); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have access to this order"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Order retrieved successfully",
+		"data":    localizedOrder(c, order),
+	})
+}
+
+// GetMyOrders handles retrieving orders placed by the authenticated user
+func GetMyOrders(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	orders, err := services.GetOrdersByCustomer(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var orderResponses []models.OrderResponse
+	for _, order := range orders {
+		orderResponses = append(orderResponses, localizedOrder(c, &order))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Your orders retrieved successfully",
+		"count":   len(orderResponses),
+		"data":    orderResponses,
+	})
+}
+
+// GetOrdersByStore handles retrieving all orders for a store (for store staff)
+func GetOrdersByStore(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	storeID := c.Param("storeId")
+
+	store, err := services.GetStoreByID(storeID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if !services.HasStorePermission(store, userID, getAuthRole(c), models.PermissionOrdersRead) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have access to this store's orders"})
+		return
+	}
+
+	orders, err := services.GetOrdersByStore(storeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var orderResponses []models.OrderResponse
+	for _, order := range orders {
+		orderResponses = append(orderResponses, localizedOrder(c, &order))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Orders retrieved successfully",
+		"count":   len(orderResponses),
+		"data":    orderResponses,
+	})
+}
+
+// GetStoreTaxReport handles totalling a store's sales and taxes per rate
+// between the from and to dates (YYYY-MM-DD, both included)
+func GetStoreTaxReport(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	store, err := services.GetStoreByID(c.Param("storeId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if !services.HasStorePermission(store, userID, getAuthRole(c), models.PermissionOrdersRead) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have access to this store's orders"})
+		return
+	}
+
+	report, err := services.GetStoreTaxReport(store, c.Query("from"), c.Query("to"))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidReportRange) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tax report generated successfully",
+		"data":    report,
+	})
+}
+
+// UpdateOrderStatus handles moving an order through its status workflow
+func UpdateOrderStatus(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.UpdateOrderStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	order, err := services.UpdateOrderStatus(c.Param("id"), req, userID, getAuthRole(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrOrderTransitionForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrInvalidOrderTransition):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrOrderStatusConflict), errors.Is(err, services.ErrSplitLocked),
+			errors.Is(err, services.ErrPaymentStateConflict):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Order status updated successfully",
+		"status":  order.Status,
+		"data":    localizedOrder(c, order),
+	})
+}
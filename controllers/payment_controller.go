@@ -0,0 +1,340 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"ordernew/models"
+	"ordernew/payments"
+	"ordernew/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// paymentSignatureHeader carries the provider's signature of a webhook payload
+const paymentSignatureHeader = "X-Payment-Signature"
+
+// respondPaymentError maps payment service errors to HTTP responses
+func respondPaymentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrPaymentNotAllowed):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrOrderNotPayable), errors.Is(err, services.ErrPaymentStateConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrSandboxUnavailable):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+// localizedPayment builds a payment response with its amount formatted for the caller
+func localizedPayment(c *gin.Context, payment *models.Payment) models.PaymentResponse {
+	response := payment.ToPaymentResponse()
+	response.Localize(requestLocale(c, ""))
+	return response
+}
+
+// canAccessOrderPayments reports whether the caller placed the order or may act on the
+// store's orders with the given permission
+func canAccessOrderPayments(c *gin.Context, order *models.Order, userID primitive.ObjectID, permission string) bool {
+	if !order.CustomerID.IsZero() && order.CustomerID == userID {
+		return true
+	}
+	_, _, err := services.AuthorizeStoreAccess(order.StoreID.Hex(), userID, getAuthRole(c), permission)
+	return err == nil
+}
+
+// canAccessSubBillPayments reports whether the caller ordered at the sub-bill's table or
+// may act on the store's orders with the given permission
+func canAccessSubBillPayments(c *gin.Context, bill *models.SubBill, userID primitive.ObjectID, permission string) bool {
+	if services.IsDiningSessionCustomer(bill.SessionID, userID) {
+		return true
+	}
+	_, _, err := services.AuthorizeStoreAccess(bill.StoreID.Hex(), userID, getAuthRole(c), permission)
+	return err == nil
+}
+
+// canAccessPayment reports whether the caller may see or act on a payment, through the
+// order or sub-bill it is for
+func canAccessPayment(c *gin.Context, payment *models.Payment, userID primitive.ObjectID, permission string) (bool, error) {
+	if !payment.SubBillID.IsZero() {
+		bill, err := services.GetSubBillByID(payment.SubBillID.Hex())
+		if err != nil {
+			return false, err
+		}
+		return canAccessSubBillPayments(c, bill, userID, permission), nil
+	}
+	order, err := services.GetOrderByID(payment.OrderID.Hex())
+	if err != nil {
+		return false, err
+	}
+	return canAccessOrderPayments(c, order, userID, permission), nil
+}
+
+// CreatePayment handles starting a payment for an order
+func CreatePayment(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.CreatePaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	payment, clientSecret, err := services.CreatePayment(c.Param("orderId"), req, userID, getAuthRole(c))
+	if err != nil {
+		respondPaymentError(c, err)
+		return
+	}
+
+	response := localizedPayment(c, payment)
+	response.ClientSecret = clientSecret
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Payment created successfully",
+		"data":    response,
+	})
+}
+
+// GetOrderPayments handles retrieving the payment ledger of an order
+func GetOrderPayments(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	order, err := services.GetOrderByID(c.Param("orderId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if !canAccessOrderPayments(c, order, userID, models.PermissionOrdersRead) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have access to this order"})
+		return
+	}
+
+	orderPayments, err := services.GetPaymentsByOrder(order.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	paymentResponses := []models.PaymentResponse{}
+	for _, payment := range orderPayments {
+		paymentResponses = append(paymentResponses, localizedPayment(c, &payment))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Payments retrieved successfully",
+		"count":   len(paymentResponses),
+		"data":    paymentResponses,
+		"order":   localizedOrder(c, order),
+	})
+}
+
+// CreateSubBillPayment handles starting a payment for a sub-bill of a dining session
+func CreateSubBillPayment(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.CreatePaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	payment, clientSecret, err := services.CreateSubBillPayment(c.Param("billId"), req, userID, getAuthRole(c))
+	if err != nil {
+		respondPaymentError(c, err)
+		return
+	}
+
+	response := localizedPayment(c, payment)
+	response.ClientSecret = clientSecret
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Payment created successfully",
+		"data":    response,
+	})
+}
+
+// GetSubBillPayments handles retrieving a sub-bill with its payment ledger
+func GetSubBillPayments(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	bill, err := services.GetSubBillByID(c.Param("billId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if !canAccessSubBillPayments(c, bill, userID, models.PermissionOrdersRead) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have access to this bill"})
+		return
+	}
+
+	billPayments, err := services.GetPaymentsBySubBill(bill.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	paymentResponses := []models.PaymentResponse{}
+	for _, payment := range billPayments {
+		paymentResponses = append(paymentResponses, localizedPayment(c, &payment))
+	}
+
+	billResponse := bill.ToSubBillResponse()
+	billResponse.Localize(requestLocale(c, ""))
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Payments retrieved successfully",
+		"count":   len(paymentResponses),
+		"data":    paymentResponses,
+		"bill":    billResponse,
+	})
+}
+
+// GetPayment handles retrieving a single payment
+func GetPayment(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	payment, err := services.GetPaymentByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	allowed, err := canAccessPayment(c, payment, userID, models.PermissionOrdersRead)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have access to this payment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Payment retrieved successfully",
+		"data":    localizedPayment(c, payment),
+	})
+}
+
+// CapturePayment handles taking the money of an authorized or counter payment
+func CapturePayment(c *gin.Context) {
+	userID, _ := getAuthUserID(c)
+
+	// The body is optional; without it the whole amount is used
+	var req models.CapturePaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	payment, err := services.CapturePayment(c.Param("id"), req, userID)
+	if err != nil {
+		respondPaymentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Payment captured successfully",
+		"data":    localizedPayment(c, payment),
+	})
+}
+
+// VoidPayment handles cancelling a payment that was not captured
+func VoidPayment(c *gin.Context) {
+	userID, _ := getAuthUserID(c)
+
+	payment, err := services.VoidPayment(c.Param("id"), userID)
+	if err != nil {
+		respondPaymentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Payment voided successfully",
+		"data":    localizedPayment(c, payment),
+	})
+}
+
+// RefundPayment handles giving back part or all of a captured payment
+func RefundPayment(c *gin.Context) {
+	userID, _ := getAuthUserID(c)
+
+	// The body is optional; without it the whole amount is used
+	var req models.RefundPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	payment, err := services.RefundPayment(c.Param("id"), req, userID)
+	if err != nil {
+		respondPaymentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Payment refunded successfully",
+		"data":    localizedPayment(c, payment),
+	})
+}
+
+// ConfirmSandboxPayment handles simulating the customer's card confirmation with the mock
+// provider. Only store staff may do this, and only outside release mode.
+func ConfirmSandboxPayment(c *gin.Context) {
+	var req models.ConfirmSandboxPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	payment, err := services.ConfirmSandboxPayment(c.Param("id"), req.Outcome == "succeeded")
+	if err != nil {
+		respondPaymentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Sandbox payment confirmed",
+		"data":    localizedPayment(c, payment),
+	})
+}
+
+// HandlePaymentWebhook handles notifications from the payment provider.
+// Redelivered events are acknowledged without being applied again.
+func HandlePaymentWebhook(c *gin.Context) {
+	payload, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.HandlePaymentWebhook(payload, c.GetHeader(paymentSignatureHeader)); err != nil {
+		if errors.Is(err, payments.ErrInvalidSignature) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"received": true})
+}
@@ -0,0 +1,373 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"ordernew/models"
+	"ordernew/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// profileErrorStatus maps self-service account errors to HTTP status codes
+func profileErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrIncorrectPassword), errors.Is(err, services.ErrUserInactive):
+		return http.StatusUnauthorized
+	case errors.Is(err, services.ErrEmailInUse), errors.Is(err, services.ErrUserOwnsStores):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+// GetMe returns the authenticated user's profile
+// @Summary Get my profile
+// @Tags users
+// @Produce json
+// @Success 200 {object} models.UserResponse
+// @Router /users/me [get]
+func (c *UserController) GetMe(ctx *gin.Context) {
+	userID, exists := getAuthUserID(ctx)
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	user, err := c.userService.GetUserByID(userID.Hex())
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "User not found",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "User retrieved successfully",
+		"data":    user,
+	})
+}
+
+// UpdateMe updates the authenticated user's profile
+// @Summary Update my profile
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param body body models.UpdateProfileRequest true "Profile data"
+// @Success 200 {object} models.UserResponse
+// @Router /users/me [patch]
+func (c *UserController) UpdateMe(ctx *gin.Context) {
+	var req models.UpdateProfileRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	userID, exists := getAuthUserID(ctx)
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	user, err := c.userService.UpdateUser(userID.Hex(), models.UpdateUserRequest{Name: req.Name})
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Update failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Profile updated successfully",
+		"data":    user,
+	})
+}
+
+// ChangeMyPassword changes the authenticated user's password
+// @Summary Change my password
+// @Description Set a new password after confirming the current one; other sessions are signed out
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param body body models.ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} map[string]string
+// @Router /users/me/password [put]
+func (c *UserController) ChangeMyPassword(ctx *gin.Context) {
+	var req models.ChangePasswordRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	userID, exists := getAuthUserID(ctx)
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	if err := c.userService.ChangePassword(userID, req, ctx.GetString("session_id")); err != nil {
+		ctx.JSON(profileErrorStatus(err), gin.H{
+			"error":   "Password change failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Password changed; your other sessions have been signed out",
+	})
+}
+
+// ChangeMyEmail changes the authenticated user's email address
+// @Summary Change my email
+// @Description Move the account to a new email address, which has to be verified again
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param body body models.ChangeEmailRequest true "New email and current password"
+// @Success 200 {object} models.UserResponse
+// @Router /users/me/email [put]
+func (c *UserController) ChangeMyEmail(ctx *gin.Context) {
+	var req models.ChangeEmailRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	userID, exists := getAuthUserID(ctx)
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	user, err := c.userService.ChangeEmail(userID, req)
+	if err != nil {
+		ctx.JSON(profileErrorStatus(err), gin.H{
+			"error":   "Email change failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Email changed; check your inbox to verify the new address",
+		"data":    user,
+	})
+}
+
+// DeleteMe deletes the authenticated user's account
+// @Summary Delete my account
+// @Description Permanently delete the current user's account after confirming the password
+// @Tags users
+// @Accept json
+// @Param body body models.DeleteAccountRequest true "Current password"
+// @Success 200 {object} map[string]string
+// @Router /users/me [delete]
+func (c *UserController) DeleteMe(ctx *gin.Context) {
+	var req models.DeleteAccountRequest
+
+	// Accounts without a password may send no body
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	userID, exists := getAuthUserID(ctx)
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	if err := c.userService.DeleteOwnAccount(userID, req.Password); err != nil {
+		ctx.JSON(profileErrorStatus(err), gin.H{
+			"error":   "Delete failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Account deleted successfully",
+	})
+}
+
+// GetMyAddresses lists the authenticated user's saved delivery addresses
+// @Summary Get my addresses
+// @Tags users
+// @Produce json
+// @Success 200 {array} models.SavedAddress
+// @Router /users/me/addresses [get]
+func (c *UserController) GetMyAddresses(ctx *gin.Context) {
+	userID, exists := getAuthUserID(ctx)
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	addresses, err := services.GetSavedAddresses(userID)
+	if err != nil {
+		ctx.JSON(profileErrorStatus(err), gin.H{
+			"error":   "Failed to fetch addresses",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Addresses retrieved successfully",
+		"count":   len(addresses),
+		"data":    addresses,
+	})
+}
+
+// AddMyAddress saves a delivery address on the authenticated user's account
+// @Summary Add an address
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param body body models.SavedAddressRequest true "Address"
+// @Success 201 {object} models.SavedAddress
+// @Router /users/me/addresses [post]
+func (c *UserController) AddMyAddress(ctx *gin.Context) {
+	var req models.SavedAddressRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	userID, exists := getAuthUserID(ctx)
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	address, err := services.AddSavedAddress(userID, req)
+	if err != nil {
+		ctx.JSON(profileErrorStatus(err), gin.H{
+			"error":   "Failed to save address",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"message": "Address saved successfully",
+		"data":    address,
+	})
+}
+
+// UpdateMyAddress replaces one of the authenticated user's saved addresses
+// @Summary Update an address
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param addressId path string true "Address ID"
+// @Param body body models.SavedAddressRequest true "Address"
+// @Success 200 {object} models.SavedAddress
+// @Router /users/me/addresses/{addressId} [put]
+func (c *UserController) UpdateMyAddress(ctx *gin.Context) {
+	var req models.SavedAddressRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	userID, exists := getAuthUserID(ctx)
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	address, err := services.UpdateSavedAddress(userID, ctx.Param("addressId"), req)
+	if err != nil {
+		ctx.JSON(profileErrorStatus(err), gin.H{
+			"error":   "Failed to update address",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Address updated successfully",
+		"data":    address,
+	})
+}
+
+// DeleteMyAddress removes one of the authenticated user's saved addresses
+// @Summary Delete an address
+// @Tags users
+// @Param addressId path string true "Address ID"
+// @Success 200 {object} map[string]string
+// @Router /users/me/addresses/{addressId} [delete]
+func (c *UserController) DeleteMyAddress(ctx *gin.Context) {
+	userID, exists := getAuthUserID(ctx)
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	if err := services.DeleteSavedAddress(userID, ctx.Param("addressId")); err != nil {
+		ctx.JSON(profileErrorStatus(err), gin.H{
+			"error":   "Failed to delete address",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Address deleted successfully",
+	})
+}
@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"ordernew/models"
+	"ordernew/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondPromotionError maps promotion service errors to HTTP responses
+func respondPromotionError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrPromoCodeTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// CreatePromotion handles promotion creation
+func CreatePromotion(c *gin.Context) {
+	var req models.CreatePromotionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	promotion, err := services.CreatePromotion(req)
+	if err != nil {
+		respondPromotionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Promotion created successfully",
+		"data":    promotion.ToPromotionResponse(),
+	})
+}
+
+// GetPromotion handles retrieving a single promotion
+func GetPromotion(c *gin.Context) {
+	promotion, err := services.GetPromotionByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Promotion retrieved successfully",
+		"data":    promotion.ToPromotionResponse(),
+	})
+}
+
+// GetPromotionsByStore handles retrieving all promotions for a store
+func GetPromotionsByStore(c *gin.Context) {
+	promotions, err := services.GetPromotionsByStore(c.Param("storeId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var promotionResponses []models.PromotionResponse
+	for _, promotion := range promotions {
+		promotionResponses = append(promotionResponses, promotion.ToPromotionResponse())
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Promotions retrieved successfully",
+		"count":   len(promotionResponses),
+		"data":    promotionResponses,
+	})
+}
+
+// UpdatePromotion handles updating a promotion
+func UpdatePromotion(c *gin.Context) {
+	var req models.UpdatePromotionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	promotion, err := services.UpdatePromotion(c.Param("id"), req)
+	if err != nil {
+		respondPromotionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Promotion updated successfully",
+		"data":    promotion.ToPromotionResponse(),
+	})
+}
+
+// DeletePromotion handles deleting a promotion
+func DeletePromotion(c *gin.Context) {
+	err := services.DeletePromotion(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Promotion deleted successfully",
+	})
+}
@@ -1,13 +1,18 @@
 package controllers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"ordernew/models"
 	"ordernew/services"
+	"ordernew/utils"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson/primitive"
+	"github.com/skip2/go-qrcode"
 )
 
 // CreateStore handles store creation
@@ -19,16 +24,18 @@ func CreateStore(c *gin.Context) {
 	}
 
 	// Get owner ID from JWT token (stored in context by auth middleware)
-	userID, exists := c.Get("userID")
+	ownerID, exists := getAuthUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
 
-	ownerID := userID.(primitive.ObjectID)
-
 	store, err := services.CreateStore(req, ownerID)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidOpeningHours) || errors.Is(err, services.ErrInvalidStoreLocale) || errors.Is(err, services.ErrInvalidTaxSettings) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -75,31 +82,25 @@ func GetAllStores(c *gin.Context) {
 	})
 }
 
-// GetMyStores handles retrieving stores owned by the authenticated user
+// GetMyStores handles retrieving stores owned by the authenticated user or
+// where the user is staff
 func GetMyStores(c *gin.Context) {
-	userID, exists := c.Get("userID")
+	userID, exists := getAuthUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
 
-	ownerID := userID.(primitive.ObjectID)
-
-	stores, err := services.GetStoresByOwner(ownerID)
+	stores, err := services.GetMyStores(userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	var storeResponses []models.StoreResponse
-	for _, store := range stores {
-		storeResponses = append(storeResponses, store.ToStoreResponse())
-	}
-
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Your stores retrieved successfully",
-		"count":   len(storeResponses),
-		"data":    storeResponses,
+		"count":   len(stores),
+		"data":    stores,
 	})
 }
 
@@ -115,6 +116,10 @@ func UpdateStore(c *gin.Context) {
 
 	store, err := services.UpdateStore(storeID, req)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidOpeningHours) || errors.Is(err, services.ErrInvalidStoreLocale) || errors.Is(err, services.ErrInvalidTaxSettings) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -161,3 +166,106 @@ func ToggleStoreStatus(c *gin.Context) {
 		"data":    store.ToStoreResponse(),
 	})
 }
+
+// GetStoreQRCodePNG handles rendering the store's menu QR code as a PNG image
+func GetStoreQRCodePNG(c *gin.Context) {
+	size, level, err := parseQROptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	store, err := services.GetStoreByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	png, err := utils.GenerateQRPNG(utils.BuildMenuURL(store.ID.Hex(), ""), size, level)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Data(http.StatusOK, "image/png", png)
+}
+
+// GetStoreQRCodeSVG handles rendering the store's menu QR code as an SVG image
+func GetStoreQRCodeSVG(c *gin.Context) {
+	size, level, err := parseQROptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	store, err := services.GetStoreByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	svg, err := utils.GenerateQRSVG(utils.BuildMenuURL(store.ID.Hex(), ""), size, level)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Data(http.StatusOK, "image/svg+xml", svg)
+}
+
+// parseQROptions reads the size and error-correction level query parameters of QR endpoints
+func parseQROptions(c *gin.Context) (int, qrcode.RecoveryLevel, error) {
+	size := utils.DefaultQRSize
+	if value := c.Query("size"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < utils.MinQRSize || parsed > utils.MaxQRSize {
+			return 0, qrcode.Medium, fmt.Errorf("size must be a number between %d and %d", utils.MinQRSize, utils.MaxQRSize)
+		}
+		size = parsed
+	}
+
+	level, err := utils.ParseQRRecoveryLevel(c.Query("level"))
+	if err != nil {
+		return 0, qrcode.Medium, err
+	}
+
+	return size, level, nil
+}
+
+// GetStoreMenu handles retrieving the full nested customer menu of a store.
+// The response carries an ETag, so clients can revalidate with If-None-Match.
+func GetStoreMenu(c *gin.Context) {
+	store, err := services.GetStoreByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	menu, err := services.GetStoreMenu(store)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	menu.Localize(requestLocale(c, store.Locale))
+
+	body, err := json.Marshal(gin.H{
+		"message": "Menu retrieved successfully",
+		"data":    menu,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Clients must revalidate, but an unchanged menu costs only a 304
+	etag := utils.ETag(body)
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Vary", "Accept-Language")
+	if utils.ETagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
@@ -0,0 +1,168 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"ordernew/models"
+	"ordernew/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondStoreMemberError maps store member service errors to HTTP responses
+func respondStoreMemberError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrStoreAccessDenied), errors.Is(err, services.ErrStoreInviteEmailMismatch):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrStoreMemberExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err.Error() == "member not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+// InviteStoreMember handles inviting a user to a store by email
+func InviteStoreMember(c *gin.Context) {
+	var req models.InviteStoreMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	store, err := services.GetStoreByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	member, err := services.InviteStoreMember(store, req, userID, c.GetString("store_role"))
+	if err != nil {
+		respondStoreMemberError(c, err)
+		return
+	}
+
+	// The invite token is emailed to the invited address, never returned to the inviter
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Invite sent successfully",
+		"data":    member.ToStoreMemberResponse(),
+	})
+}
+
+// GetStoreMembers handles listing the members and pending invites of a store
+func GetStoreMembers(c *gin.Context) {
+	members, err := services.GetStoreMembers(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	memberResponses := []models.StoreMemberResponse{}
+	for _, member := range members {
+		memberResponses = append(memberResponses, member.ToStoreMemberResponse())
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Store members retrieved successfully",
+		"count":   len(memberResponses),
+		"data":    memberResponses,
+	})
+}
+
+// UpdateStoreMember handles changing the role of a store member
+func UpdateStoreMember(c *gin.Context) {
+	var req models.UpdateStoreMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	store, err := services.GetStoreByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	member, err := services.UpdateStoreMemberRole(store.ID, c.Param("memberId"), req, c.GetString("store_role"))
+	if err != nil {
+		respondStoreMemberError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Store member updated successfully",
+		"data":    member.ToStoreMemberResponse(),
+	})
+}
+
+// RemoveStoreMember handles removing a member or revoking an invite
+func RemoveStoreMember(c *gin.Context) {
+	store, err := services.GetStoreByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.RemoveStoreMember(store.ID, c.Param("memberId"), c.GetString("store_role")); err != nil {
+		respondStoreMemberError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Store member removed successfully",
+	})
+}
+
+// GetMyStoreInvites handles listing the open invites sent to the authenticated user
+func GetMyStoreInvites(c *gin.Context) {
+	invites, err := services.GetPendingStoreInvites(c.GetString("email"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	inviteResponses := []models.StoreMemberResponse{}
+	for _, invite := range invites {
+		inviteResponses = append(inviteResponses, invite.ToStoreMemberResponse())
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Store invites retrieved successfully",
+		"count":   len(inviteResponses),
+		"data":    inviteResponses,
+	})
+}
+
+// AcceptStoreInvite handles the authenticated user joining a store with an invite token
+func AcceptStoreInvite(c *gin.Context) {
+	var req models.AcceptStoreInviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	member, err := services.AcceptStoreInvite(req.Token, userID, c.GetString("email"))
+	if err != nil {
+		respondStoreMemberError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Invite accepted successfully",
+		"data":    member.ToStoreMemberResponse(),
+	})
+}
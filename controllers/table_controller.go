@@ -0,0 +1,346 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"ordernew/models"
+	"ordernew/services"
+	"ordernew/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateTable handles table creation
+func CreateTable(c *gin.Context) {
+	var req models.CreateTableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	table, err := services.CreateTable(req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Table created successfully",
+		"data":    table.ToTableResponse(),
+	})
+}
+
+// GetTable handles retrieving a single table
+func GetTable(c *gin.Context) {
+	table, err := services.GetTableByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Table retrieved successfully",
+		"data":    table.ToTableResponse(),
+	})
+}
+
+// GetTablesByStore handles retrieving all tables for a store
+func GetTablesByStore(c *gin.Context) {
+	tables, err := services.GetTablesByStore(c.Param("storeId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var tableResponses []models.TableResponse
+	for _, table := range tables {
+		tableResponses = append(tableResponses, table.ToTableResponse())
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tables retrieved successfully",
+		"count":   len(tableResponses),
+		"data":    tableResponses,
+	})
+}
+
+// UpdateTable handles updating a table
+func UpdateTable(c *gin.Context) {
+	var req models.UpdateTableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	table, err := services.UpdateTable(c.Param("id"), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Table updated successfully",
+		"data":    table.ToTableResponse(),
+	})
+}
+
+// DeleteTable handles deleting a table
+func DeleteTable(c *gin.Context) {
+	err := services.DeleteTable(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Table deleted successfully",
+	})
+}
+
+// RegenerateTableToken handles issuing a new QR code for a table (the old one stops working)
+func RegenerateTableToken(c *gin.Context) {
+	table, err := services.RegenerateTableToken(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Table QR code regenerated successfully",
+		"data":    table.ToTableResponse(),
+	})
+}
+
+// ScanTable handles a scanned table QR code: it opens (or joins) the table's dining session
+func ScanTable(c *gin.Context) {
+	var req models.ScanTableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	table, err := services.ResolveTableToken(req.TableToken)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	store, err := services.GetStoreByID(table.StoreID.Hex())
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := services.OpenDiningSession(table)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Dining session opened successfully",
+		"data": gin.H{
+			"store":   store.ToStoreResponse(),
+			"table":   table.ToTableResponse(),
+			"session": session,
+		},
+	})
+}
+
+// GetTableSession handles retrieving the open dining session of a table with its orders
+func GetTableSession(c *gin.Context) {
+	session, err := services.GetOpenDiningSessionByTable(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := services.BuildDiningSessionResponse(session)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	response.Localize(requestLocale(c, ""))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Dining session retrieved successfully",
+		"data":    response,
+	})
+}
+
+// GetDiningSession handles retrieving a dining session with its orders
+func GetDiningSession(c *gin.Context) {
+	session, err := services.GetDiningSessionByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := services.BuildDiningSessionResponse(session)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	response.Localize(requestLocale(c, ""))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Dining session retrieved successfully",
+		"data":    response,
+	})
+}
+
+// CloseDiningSession handles closing the bill of a dining session
+func CloseDiningSession(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	session, err := services.CloseDiningSession(c.Param("id"), userID)
+	if err != nil {
+		if errors.Is(err, services.ErrSessionBalanceDue) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Dining session closed successfully",
+		"data":    session,
+	})
+}
+
+// SplitDiningSessionBill handles splitting the bill of a dining session into sub-bills
+func SplitDiningSessionBill(c *gin.Context) {
+	var req models.SplitBillRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	bills, err := services.SplitDiningSession(c.Param("id"), req)
+	if err != nil {
+		if errors.Is(err, services.ErrSplitLocked) || errors.Is(err, services.ErrPaymentStateConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	locale := requestLocale(c, "")
+	billResponses := []models.SubBillResponse{}
+	for _, bill := range bills {
+		response := bill.ToSubBillResponse()
+		response.Localize(locale)
+		billResponses = append(billResponses, response)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Bill split successfully",
+		"count":   len(billResponses),
+		"data":    billResponses,
+	})
+}
+
+// RemoveDiningSessionSplit handles going back to a single bill for a dining session
+func RemoveDiningSessionSplit(c *gin.Context) {
+	if err := services.RemoveDiningSessionSplit(c.Param("id")); err != nil {
+		if errors.Is(err, services.ErrSplitLocked) || errors.Is(err, services.ErrPaymentStateConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Bill split removed successfully",
+	})
+}
+
+// GetTableQRCodePNG handles rendering a table's QR code as a PNG image
+func GetTableQRCodePNG(c *gin.Context) {
+	size, level, err := parseQROptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	table, err := services.GetTableByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	png, err := utils.GenerateQRPNG(services.TableMenuURL(table), size, level)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Data(http.StatusOK, "image/png", png)
+}
+
+// GetTableQRCodeSVG handles rendering a table's QR code as an SVG image
+func GetTableQRCodeSVG(c *gin.Context) {
+	size, level, err := parseQROptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	table, err := services.GetTableByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	svg, err := utils.GenerateQRSVG(services.TableMenuURL(table), size, level)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Data(http.StatusOK, "image/svg+xml", svg)
+}
+
+// GetStoreTableQRSheet handles rendering a printable PDF sheet with the QR codes of all tables of a store
+func GetStoreTableQRSheet(c *gin.Context) {
+	store, err := services.GetStoreByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	tables, err := services.GetTablesByStore(store.ID.Hex())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var entries []utils.QRSheetEntry
+	for _, table := range tables {
+		if !table.IsActive {
+			continue
+		}
+		entries = append(entries, utils.QRSheetEntry{
+			Label:   table.Name,
+			Content: services.TableMenuURL(&table),
+		})
+	}
+
+	pdf, err := utils.GenerateQRSheetPDF(store.Name, entries)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="tables-%s.pdf"`, store.ID.Hex()))
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
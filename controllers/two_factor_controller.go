@@ -0,0 +1,301 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"ordernew/models"
+	"ordernew/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// twoFactorErrorStatus maps two-factor service errors to HTTP status codes
+func twoFactorErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidTwoFactorCode), errors.Is(err, services.ErrInvalidTwoFactorChallenge), errors.Is(err, services.ErrUserInactive):
+		return http.StatusUnauthorized
+	case errors.Is(err, services.ErrTwoFactorRequired):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrTwoFactorAlreadyEnabled), errors.Is(err, services.ErrTwoFactorNotEnabled), errors.Is(err, services.ErrTwoFactorSetupNotStarted):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// VerifyTwoFactorLogin completes a login with a two-factor code
+// @Summary Complete two-factor login
+// @Description Exchange a login challenge and a TOTP or recovery code for tokens
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param body body models.TwoFactorLoginRequest true "Challenge token and code"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/2fa/verify [post]
+func (c *UserController) VerifyTwoFactorLogin(ctx *gin.Context) {
+	var req models.TwoFactorLoginRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	result, err := services.CompleteTwoFactorLogin(req.ChallengeToken, req.Code, ctx.Request.UserAgent(), ctx.ClientIP())
+	if err != nil {
+		if respondLoginThrottled(ctx, err) {
+			return
+		}
+		ctx.JSON(twoFactorErrorStatus(err), gin.H{
+			"error":   "Login failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	respondLogin(ctx, result)
+}
+
+// SetupTwoFactor starts two-factor enrolment for the authenticated user
+// @Summary Start two-factor setup
+// @Description Create a TOTP secret and provisioning QR code
+// @Tags auth
+// @Produce json
+// @Success 200 {object} models.TwoFactorSetupResponse
+// @Router /auth/2fa/setup [post]
+func (c *UserController) SetupTwoFactor(ctx *gin.Context) {
+	userID, exists := getAuthUserID(ctx)
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	setup, err := services.BeginTwoFactorSetup(userID)
+	if err != nil {
+		ctx.JSON(twoFactorErrorStatus(err), gin.H{
+			"error":   "Two-factor setup failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Scan the QR code with your authenticator app, then confirm with a code",
+		"data":    setup,
+	})
+}
+
+// EnableTwoFactor confirms two-factor enrolment with a code from the authenticator app
+// @Summary Enable two-factor authentication
+// @Description Confirm the TOTP secret and receive recovery codes
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param body body models.TwoFactorCodeRequest true "TOTP code"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/2fa/enable [post]
+func (c *UserController) EnableTwoFactor(ctx *gin.Context) {
+	var req models.TwoFactorCodeRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	userID, exists := getAuthUserID(ctx)
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	codes, err := services.EnableTwoFactor(userID, req.Code, ctx.GetString("session_id"))
+	if err != nil {
+		ctx.JSON(twoFactorErrorStatus(err), gin.H{
+			"error":   "Two-factor setup failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Recovery codes are only shown once
+	ctx.JSON(http.StatusOK, gin.H{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTwoFactor turns off two-factor authentication for the authenticated user
+// @Summary Disable two-factor authentication
+// @Description Turn off two-factor authentication with a TOTP or recovery code
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param body body models.TwoFactorCodeRequest true "TOTP or recovery code"
+// @Success 200 {object} map[string]string
+// @Router /auth/2fa/disable [post]
+func (c *UserController) DisableTwoFactor(ctx *gin.Context) {
+	var req models.TwoFactorCodeRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	userID, exists := getAuthUserID(ctx)
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	if err := services.DisableTwoFactor(userID, req.Code); err != nil {
+		ctx.JSON(twoFactorErrorStatus(err), gin.H{
+			"error":   "Two-factor update failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// RegenerateRecoveryCodes replaces the authenticated user's recovery codes
+// @Summary Regenerate recovery codes
+// @Description Replace all recovery codes after checking a TOTP code
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param body body models.TwoFactorCodeRequest true "TOTP code"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/2fa/recovery-codes [post]
+func (c *UserController) RegenerateRecoveryCodes(ctx *gin.Context) {
+	var req models.TwoFactorCodeRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	userID, exists := getAuthUserID(ctx)
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	codes, err := services.RegenerateRecoveryCodes(userID, req.Code)
+	if err != nil {
+		ctx.JSON(twoFactorErrorStatus(err), gin.H{
+			"error":   "Two-factor update failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message":        "Recovery codes regenerated",
+		"recovery_codes": codes,
+	})
+}
+
+// ResetUserTwoFactor turns off two-factor authentication for a user who lost access to it
+// @Summary Reset a user's two-factor authentication
+// @Description Admin recovery for users without their authenticator or recovery codes
+// @Tags users
+// @Param id path string true "User ID"
+// @Success 200 {object} map[string]string
+// @Router /users/{id}/2fa [delete]
+func (c *UserController) ResetUserTwoFactor(ctx *gin.Context) {
+	if err := services.ResetTwoFactor(ctx.Param("id")); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Two-factor reset failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Two-factor authentication reset; the user has been signed out",
+	})
+}
+
+// GetSecuritySettings returns the account security settings
+// @Summary Get security settings
+// @Tags admin
+// @Produce json
+// @Success 200 {object} models.SecuritySettings
+// @Router /admin/security-settings [get]
+func (c *UserController) GetSecuritySettings(ctx *gin.Context) {
+	settings, err := services.GetSecuritySettings()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch security settings",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Security settings retrieved successfully",
+		"data":    settings,
+	})
+}
+
+// UpdateSecuritySettings changes the account security settings
+// @Summary Update security settings
+// @Description Require two-factor authentication for admins and store owners
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param body body models.UpdateSecuritySettingsRequest true "Security settings"
+// @Success 200 {object} models.SecuritySettings
+// @Router /admin/security-settings [put]
+func (c *UserController) UpdateSecuritySettings(ctx *gin.Context) {
+	var req models.UpdateSecuritySettingsRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	userID, _ := getAuthUserID(ctx)
+	settings, err := services.UpdateSecuritySettings(req, userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update security settings",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Security settings updated successfully",
+		"data":    settings,
+	})
+}
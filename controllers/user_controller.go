@@ -1,10 +1,14 @@
 package controllers
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
 	"ordernew/models"
 	"ordernew/services"
+	"ordernew/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -75,8 +79,11 @@ func (c *UserController) Login(ctx *gin.Context) {
 		return
 	}
 
-	token, user, err := c.userService.Login(req)
+	result, err := c.userService.Login(req, ctx.Request.UserAgent(), ctx.ClientIP())
 	if err != nil {
+		if respondLoginThrottled(ctx, err) {
+			return
+		}
 		ctx.JSON(http.StatusUnauthorized, gin.H{
 			"error":   "Login failed",
 			"message": err.Error(),
@@ -84,13 +91,381 @@ func (c *UserController) Login(ctx *gin.Context) {
 		return
 	}
 
+	respondLogin(ctx, result)
+}
+
+// respondLoginThrottled answers 429 Too Many Requests when a login was
+// refused because of an account lock or IP throttling
+func respondLoginThrottled(ctx *gin.Context, err error) bool {
+	var throttled *services.LoginThrottleError
+	if !errors.As(err, &throttled) {
+		return false
+	}
+
+	retrySeconds := int64(math.Ceil(throttled.RetryAfter.Seconds()))
+	ctx.Header("Retry-After", strconv.FormatInt(retrySeconds, 10))
+	ctx.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "Too many requests",
+		"message":     err.Error(),
+		"retry_after": retrySeconds,
+	})
+	return true
+}
+
+// respondLogin writes the tokens of a successful login, or the challenge to
+// complete when the user has two-factor authentication enabled
+func respondLogin(ctx *gin.Context, result *models.LoginResult) {
+	if result.Challenge != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"message":             "Two-factor authentication required",
+			"two_factor_required": true,
+			"challenge_token":     result.Challenge.ChallengeToken,
+			"expires_in":          result.Challenge.ExpiresIn,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message":       "Login successful",
+		"token":         result.Tokens.AccessToken,
+		"refresh_token": result.Tokens.RefreshToken,
+		"expires_in":    result.Tokens.ExpiresIn,
+		"data":          result.User,
+	})
+}
+
+// RefreshToken exchanges a refresh token for a new token pair
+// @Summary Refresh tokens
+// @Description Rotate a refresh token and return a new access token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param body body models.RefreshTokenRequest true "Refresh token"
+// @Success 200 {object} models.TokenPair
+// @Router /auth/refresh [post]
+func (c *UserController) RefreshToken(ctx *gin.Context) {
+	var req models.RefreshTokenRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	tokens, err := services.RefreshSession(req.RefreshToken, ctx.Request.UserAgent(), ctx.ClientIP())
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrUserInactive) {
+			status = http.StatusUnauthorized
+		}
+		ctx.JSON(status, gin.H{
+			"error":   "Refresh failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message":       "Token refreshed successfully",
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+	})
+}
+
+// Logout ends the session of the current access token
+// @Summary Logout
+// @Description Revoke the current session
+// @Tags auth
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Router /auth/logout [post]
+func (c *UserController) Logout(ctx *gin.Context) {
+	userID, exists := getAuthUserID(ctx)
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	if err := services.RevokeSession(ctx.GetString("session_id"), userID); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Logout failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Logged out successfully",
+	})
+}
+
+// LogoutAll ends every session of the current user
+// @Summary Logout everywhere
+// @Description Revoke all sessions of the current user
+// @Tags auth
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Router /auth/logout-all [post]
+func (c *UserController) LogoutAll(ctx *gin.Context) {
+	userID, exists := getAuthUserID(ctx)
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	if err := services.RevokeAllSessions(userID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Logout failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Logged out of all sessions successfully",
+	})
+}
+
+// ForgotPassword emails a password reset link
+// @Summary Forgot password
+// @Description Send a password reset link to the email address, if it has an account
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param body body models.ForgotPasswordRequest true "Account email"
+// @Success 200 {object} map[string]string
+// @Router /auth/forgot-password [post]
+func (c *UserController) ForgotPassword(ctx *gin.Context) {
+	var req models.ForgotPasswordRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := services.RequestPasswordReset(req.Email); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Password reset failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Same response whether or not the account exists
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "If an account exists for this email, a password reset link has been sent",
+	})
+}
+
+// ResetPassword sets a new password with a reset token
+// @Summary Reset password
+// @Description Set a new password with the token from the reset email
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param body body models.ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} map[string]string
+// @Router /auth/reset-password [post]
+func (c *UserController) ResetPassword(ctx *gin.Context) {
+	var req models.ResetPasswordRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := services.ResetPassword(req.Token, req.Password); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrInvalidAuthToken) {
+			status = http.StatusBadRequest
+		}
+		ctx.JSON(status, gin.H{
+			"error":   "Password reset failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Password reset successfully. Please log in with your new password",
+	})
+}
+
+// VerifyEmail marks the user's email as verified with a verification token
+// @Summary Verify email
+// @Description Verify an email address with the token from the verification email
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param body body models.VerifyEmailRequest true "Verification token"
+// @Success 200 {object} models.UserResponse
+// @Router /auth/verify-email [post]
+func (c *UserController) VerifyEmail(ctx *gin.Context) {
+	var req models.VerifyEmailRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	user, err := services.VerifyEmail(req.Token)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrInvalidAuthToken) {
+			status = http.StatusBadRequest
+		}
+		ctx.JSON(status, gin.H{
+			"error":   "Verification failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
 	ctx.JSON(http.StatusOK, gin.H{
-		"message": "Login successful",
-		"token":   token,
+		"message": "Email verified successfully",
 		"data":    user,
 	})
 }
 
+// ResendVerificationEmail sends the authenticated user a new verification link
+// @Summary Resend verification email
+// @Description Send a new email verification link to the current user
+// @Tags auth
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Router /auth/resend-verification [post]
+func (c *UserController) ResendVerificationEmail(ctx *gin.Context) {
+	userID, exists := getAuthUserID(ctx)
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	if err := services.ResendVerificationEmail(userID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrEmailAlreadyVerified) {
+			status = http.StatusConflict
+		}
+		ctx.JSON(status, gin.H{
+			"error":   "Verification email failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Verification email sent",
+	})
+}
+
+// RequestOTP texts a login code to a phone number
+// @Summary Request login code
+// @Description Send a one-time login code by SMS
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param body body models.OTPRequest true "Phone number in E.164 format"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/otp/request [post]
+func (c *UserController) RequestOTP(ctx *gin.Context) {
+	var req models.OTPRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	retryAfter, err := services.RequestOTP(req.Phone, ctx.ClientIP())
+	retrySeconds := int64(math.Ceil(retryAfter.Seconds()))
+	if err != nil {
+		if errors.Is(err, services.ErrOTPRateLimited) {
+			ctx.Header("Retry-After", strconv.FormatInt(retrySeconds, 10))
+			ctx.JSON(http.StatusTooManyRequests, gin.H{
+				"error":       "Too many requests",
+				"message":     err.Error(),
+				"retry_after": retrySeconds,
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Code request failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message":     "Login code sent",
+		"retry_after": retrySeconds,
+	})
+}
+
+// VerifyOTP logs in with a phone number and the code texted to it
+// @Summary Verify login code
+// @Description Log in with a one-time code, creating an account for new phone numbers
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param body body models.OTPVerifyRequest true "Phone number and code"
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/otp/verify [post]
+func (c *UserController) VerifyOTP(ctx *gin.Context) {
+	var req models.OTPVerifyRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// A logged in user verifying a code adds the phone number to their account
+	linkUserID, _ := getAuthUserID(ctx)
+
+	result, err := services.VerifyOTP(req.Phone, req.Code, linkUserID, ctx.Request.UserAgent(), ctx.ClientIP())
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, services.ErrInvalidOTP), errors.Is(err, services.ErrOTPTooManyAttempts), errors.Is(err, services.ErrUserInactive):
+			status = http.StatusUnauthorized
+		case errors.Is(err, services.ErrPhoneInUse):
+			status = http.StatusConflict
+		}
+		ctx.JSON(status, gin.H{
+			"error":   "Login failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	respondLogin(ctx, result)
+}
+
 // GetUserByID retrieves a user by ID
 // @Summary Get user by ID
 // @Description Get user details by user ID
@@ -163,8 +538,53 @@ func (c *UserController) UpdateUser(ctx *gin.Context) {
 		return
 	}
 
+	// Role and account status are administrative fields. Nobody may change their own role.
+	role := getAuthRole(ctx)
+	isSelf := ctx.GetString("user_id") == userID
+	if isSelf && req.Role == role {
+		req.Role = ""
+	}
+	if req.Role != "" {
+		if isSelf || !models.RoleHasPermission(role, models.PermissionUsersManageRoles) {
+			ctx.JSON(http.StatusForbidden, gin.H{
+				"error":   "Forbidden",
+				"message": "You are not allowed to change this user's role",
+			})
+			return
+		}
+		if !models.IsKnownRole(req.Role) {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request",
+				"message": "unknown role " + req.Role,
+			})
+			return
+		}
+	}
+	// Users change their own email through PUT /users/me/email, which checks their password
+	if isSelf && req.Email != "" {
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "Use PUT /users/me/email to change your own email address",
+		})
+		return
+	}
+	if req.IsActive != nil && !models.RoleHasPermission(role, models.PermissionUsersUpdate) {
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "You are not allowed to change this user's status",
+		})
+		return
+	}
+
 	user, err := c.userService.UpdateUser(userID, req)
 	if err != nil {
+		if errors.Is(err, services.ErrEmailInUse) {
+			ctx.JSON(http.StatusConflict, gin.H{
+				"error":   "Update failed",
+				"message": err.Error(),
+			})
+			return
+		}
 		ctx.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Update failed",
 			"message": err.Error(),
@@ -216,3 +636,16 @@ func (c *UserController) HelloWorld(ctx *gin.Context) {
 		"status":  "active",
 	})
 }
+
+// GetJWKS publishes the public keys that verify access tokens
+// @Summary JSON Web Key Set
+// @Description Public keys for verifying access tokens, selected by kid
+// @Tags auth
+// @Produce json
+// @Success 200 {object} utils.JWKSet
+// @Router /.well-known/jwks.json [get]
+func (c *UserController) GetJWKS(ctx *gin.Context) {
+	// Verifiers may cache the keys briefly; new keys are published before they sign tokens
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, utils.GetJWKS())
+}
@@ -0,0 +1,30 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LogMailer is a development mailer. It prints messages to the log and, when
+// Dir is set, also writes each one to a file there.
+type LogMailer struct {
+	Dir string
+}
+
+// Send logs a message instead of delivering it
+func (m *LogMailer) Send(msg Message) error {
+	content := fmt.Sprintf("To: %s\nSubject: %s\n\n%s\n", msg.To, msg.Subject, msg.Body)
+	log.Printf("Mail (not sent):\n%s", content)
+
+	if m.Dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s.eml", time.Now().Format("20060102T150405.000000000"))
+	return os.WriteFile(filepath.Join(m.Dir, name), []byte(content), 0o600)
+}
@@ -0,0 +1,42 @@
+package mailer
+
+import (
+	"fmt"
+	"strings"
+
+	"ordernew/config"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// New builds the mailer selected by MAIL_DRIVER: "smtp" sends real mail,
+// "log" (the default) writes messages to the log and MAIL_OUTBOX_DIR
+func New() (Mailer, error) {
+	switch strings.ToLower(config.AppConfig.MailDriver) {
+	case "smtp":
+		if config.AppConfig.SMTPHost == "" {
+			return nil, fmt.Errorf("SMTP_HOST is required when MAIL_DRIVER=smtp")
+		}
+		return &SMTPMailer{
+			Host:     config.AppConfig.SMTPHost,
+			Port:     config.AppConfig.SMTPPort,
+			Username: config.AppConfig.SMTPUsername,
+			Password: config.AppConfig.SMTPPassword,
+			From:     config.AppConfig.MailFrom,
+		}, nil
+	case "", "log":
+		return &LogMailer{Dir: config.AppConfig.MailOutboxDir}, nil
+	default:
+		return nil, fmt.Errorf("unknown MAIL_DRIVER %q", config.AppConfig.MailDriver)
+	}
+}
@@ -5,10 +5,12 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	_ "time/tzdata" // embed time zones for store opening hours
 
 	"ordernew/config"
 	"ordernew/routes"
 	"ordernew/services"
+	"ordernew/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,6 +18,14 @@ import (
 func main() {
 	// Load configuration
 	config.LoadConfig()
+	if err := config.ValidateConfig(); err != nil {
+		log.Fatal("Invalid configuration:", err)
+	}
+
+	// Load the keys that sign access tokens
+	if err := utils.LoadSigningKeys(); err != nil {
+		log.Fatal("Failed to load JWT signing keys:", err)
+	}
 
 	// Set Gin mode
 	gin.SetMode(config.AppConfig.GinMode)
@@ -29,10 +39,57 @@ func main() {
 
 	// Initialize collections
 	services.InitUserCollection()
+	services.InitSessionCollection()
+	services.InitAuthTokenCollection()
+	services.InitOTPCollection()
+	services.InitSettingsCollection()
+	services.InitLoginEventCollection()
 	services.InitProductCollection()
 	services.InitStoreCollection()
+	services.InitStoreMemberCollection()
 	services.InitCategoryCollection()
 	services.InitFoodItemCollection()
+	services.InitModifierGroupCollection()
+	services.InitComboCollection()
+	services.InitTableCollection()
+	services.InitOrderCollection()
+	services.InitCartCollection()
+	services.InitPromotionCollection()
+	services.InitPaymentCollection()
+	services.InitSubBillCollection()
+
+	// Set up the mailer for account emails
+	if err := services.InitMailer(); err != nil {
+		log.Fatal("Failed to set up mailer:", err)
+	}
+
+	// Set up the SMS sender for phone login codes
+	if err := services.InitSMSSender(); err != nil {
+		log.Fatal("Failed to set up SMS sender:", err)
+	}
+
+	// Set up the payment provider for card payments
+	if err := services.InitPaymentProvider(); err != nil {
+		log.Fatal("Failed to set up payment provider:", err)
+	}
+
+	// Accounts created before email verification keep their access
+	if err := services.MigrateUserEmailVerification(); err != nil {
+		log.Println("Warning: email verification migration failed:", err)
+	}
+
+	// Convert legacy opening/closing time strings to weekly schedules
+	if err := services.MigrateStoreOpeningHours(); err != nil {
+		log.Println("Warning: opening hours migration failed:", err)
+	}
+
+	// Convert legacy float prices to minor units; old prices cannot be read until this succeeds
+	if err := services.MigrateMoneyAmounts(); err != nil {
+		log.Fatal("Money migration failed:", err)
+	}
+
+	// Open and close stores according to their opening hours
+	services.StartStoreScheduler()
 
 	// Initialize Gin router
 	router := gin.Default()
@@ -4,6 +4,8 @@ import (
 	"net/http"
 	"strings"
 
+	"ordernew/models"
+	"ordernew/services"
 	"ordernew/utils"
 
 	"github.com/gin-gonic/gin"
@@ -46,20 +48,103 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Set user info in context
-		ctx.Set("user_id", claims.UserID)
-		ctx.Set("email", claims.Email)
-		ctx.Set("role", claims.Role)
+		// The session must not be revoked and the user must still be active
+		user, err := services.AuthenticateSession(claims)
+		if err != nil {
+			ctx.JSON(http.StatusUnauthorized, gin.H{
+				"error":   "Unauthorized",
+				"message": err.Error(),
+			})
+			ctx.Abort()
+			return
+		}
+
+		// Set user info in context, using the current role rather than the one in the token
+		setAuthContext(ctx, user, claims.SessionID)
 
 		ctx.Next()
 	}
 }
 
-// AdminMiddleware checks if user has admin role
+// OptionalAuthMiddleware sets user info in the context when a valid token is
+// present, but lets anonymous requests through (e.g. customers ordering by QR)
+func OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authHeader := ctx.GetHeader("Authorization")
+		parts := strings.Split(authHeader, " ")
+		if len(parts) == 2 && parts[0] == "Bearer" {
+			if claims, err := utils.ValidateToken(parts[1]); err == nil {
+				if user, err := services.AuthenticateSession(claims); err == nil {
+					setAuthContext(ctx, user, claims.SessionID)
+				}
+			}
+		}
+
+		ctx.Next()
+	}
+}
+
+// setAuthContext stores the authenticated user's info in the context
+func setAuthContext(ctx *gin.Context, user *models.User, sessionID string) {
+	ctx.Set("user_id", user.ID.Hex())
+	ctx.Set("email", user.Email)
+	ctx.Set("role", user.Role)
+	ctx.Set("session_id", sessionID)
+	ctx.Set("email_verified", user.EmailVerified)
+	ctx.Set("auth_user", user)
+}
+
+// abortIfTwoFactorSetupRequired stops privileged requests from users that the
+// security settings require to enable two-factor authentication first. If the
+// policy cannot be checked, the request is refused rather than let through.
+func abortIfTwoFactorSetupRequired(ctx *gin.Context) bool {
+	value, _ := ctx.Get("auth_user")
+	user, ok := value.(*models.User)
+	if !ok || user.TwoFactorEnabled {
+		return false
+	}
+	required, err := services.TwoFactorRequired(user)
+	if err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Service Unavailable",
+			"message": "Could not check the two-factor authentication policy, please retry",
+		})
+		ctx.Abort()
+		return true
+	}
+	if !required {
+		return false
+	}
+	ctx.JSON(http.StatusForbidden, gin.H{
+		"error":   "Forbidden",
+		"message": "Two-factor authentication must be enabled for this account",
+	})
+	ctx.Abort()
+	return true
+}
+
+// RequireVerifiedEmail blocks users who have not verified their email address.
+// It must run after AuthMiddleware.
+func RequireVerifiedEmail() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !ctx.GetBool("email_verified") {
+			ctx.JSON(http.StatusForbidden, gin.H{
+				"error":   "Forbidden",
+				"message": "Please verify your email address first",
+			})
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// AdminMiddleware checks if user has a role with every permission (admin)
 func AdminMiddleware() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		role, exists := ctx.Get("role")
-		if !exists || role != "admin" {
+		role, _ := ctx.Get("role")
+		roleName, _ := role.(string)
+		if !models.RoleHasPermission(roleName, models.PermissionAll) {
 			ctx.JSON(http.StatusForbidden, gin.H{
 				"error":   "Forbidden",
 				"message": "Admin access required",
@@ -67,6 +152,9 @@ func AdminMiddleware() gin.HandlerFunc {
 			ctx.Abort()
 			return
 		}
+		if abortIfTwoFactorSetupRequired(ctx) {
+			return
+		}
 		ctx.Next()
 	}
 }
@@ -0,0 +1,234 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"ordernew/models"
+	"ordernew/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// errMissingStoreID is returned when a create request does not name its store
+var errMissingStoreID = errors.New("store_id is required")
+
+// StoreResolver finds the store that owns the resource a request acts on
+type StoreResolver func(ctx *gin.Context) (string, error)
+
+// RequirePermission checks that the authenticated user's role grants a
+// permission (see models.RolePermissions). It must run after AuthMiddleware.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		role, _ := ctx.Get("role")
+		roleName, _ := role.(string)
+		if !models.RoleHasPermission(roleName, permission) {
+			abortForbidden(ctx, permission)
+			return
+		}
+		if abortIfTwoFactorSetupRequired(ctx) {
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// RequireSelfOrPermission lets users act on their own account, named by a path
+// parameter, and otherwise requires a permission. It must run after AuthMiddleware.
+func RequireSelfOrPermission(param, permission string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userID, _ := ctx.Get("user_id")
+		if userID != nil && userID.(string) == ctx.Param(param) {
+			ctx.Next()
+			return
+		}
+
+		role, _ := ctx.Get("role")
+		roleName, _ := role.(string)
+		if !models.RoleHasPermission(roleName, permission) {
+			abortForbidden(ctx, permission)
+			return
+		}
+		if abortIfTwoFactorSetupRequired(ctx) {
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// abortForbidden stops a request that lacks a permission
+func abortForbidden(ctx *gin.Context, permission string) {
+	ctx.JSON(http.StatusForbidden, gin.H{
+		"error":   "Forbidden",
+		"message": "Missing permission " + permission,
+	})
+	ctx.Abort()
+}
+
+// RequireStorePermission checks that the authenticated user holds a permission
+// in the store that owns the requested resource, through their global role or
+// their role in the store (see models.StoreRolePermissions). It must run after
+// AuthMiddleware. The resolved store ID and the caller's store role are set in
+// the context as "store_id" and "store_role".
+func RequireStorePermission(permission string, resolve StoreResolver) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		storeID, err := resolve(ctx)
+		if err != nil {
+			status := http.StatusNotFound
+			if errors.Is(err, errMissingStoreID) {
+				status = http.StatusBadRequest
+			}
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			ctx.Abort()
+			return
+		}
+
+		var userID primitive.ObjectID
+		if value, exists := ctx.Get("user_id"); exists {
+			userID, _ = primitive.ObjectIDFromHex(value.(string))
+		}
+		role, _ := ctx.Get("role")
+		roleName, _ := role.(string)
+
+		_, storeRole, err := services.AuthorizeStoreAccess(storeID, userID, roleName, permission)
+		if err != nil {
+			if errors.Is(err, services.ErrStoreAccessDenied) {
+				ctx.JSON(http.StatusForbidden, gin.H{
+					"error":   "Forbidden",
+					"message": err.Error(),
+				})
+			} else {
+				ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			}
+			ctx.Abort()
+			return
+		}
+
+		if abortIfTwoFactorSetupRequired(ctx) {
+			return
+		}
+
+		ctx.Set("store_id", storeID)
+		ctx.Set("store_role", storeRole)
+		ctx.Next()
+	}
+}
+
+// StoreFromParam resolves the store from a path parameter holding the store ID
+func StoreFromParam(param string) StoreResolver {
+	return func(ctx *gin.Context) (string, error) {
+		return ctx.Param(param), nil
+	}
+}
+
+// StoreFromBody resolves the store from the "store_id" field of a JSON request
+// body. The body is restored so the handler can bind it again.
+func StoreFromBody() StoreResolver {
+	return func(ctx *gin.Context) (string, error) {
+		body, err := io.ReadAll(ctx.Request.Body)
+		if err != nil {
+			return "", err
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		var req struct {
+			StoreID string `json:"store_id"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil || req.StoreID == "" {
+			return "", errMissingStoreID
+		}
+		return req.StoreID, nil
+	}
+}
+
+// StoreOfCategory resolves the store of the category named by a path parameter
+func StoreOfCategory(param string) StoreResolver {
+	return func(ctx *gin.Context) (string, error) {
+		category, err := services.GetCategoryByID(ctx.Param(param))
+		if err != nil {
+			return "", err
+		}
+		return category.StoreID.Hex(), nil
+	}
+}
+
+// StoreOfFoodItem resolves the store of the food item named by a path parameter
+func StoreOfFoodItem(param string) StoreResolver {
+	return func(ctx *gin.Context) (string, error) {
+		foodItem, err := services.GetFoodItemByID(ctx.Param(param))
+		if err != nil {
+			return "", err
+		}
+		return foodItem.StoreID.Hex(), nil
+	}
+}
+
+// StoreOfCombo resolves the store of the combo named by a path parameter
+func StoreOfCombo(param string) StoreResolver {
+	return func(ctx *gin.Context) (string, error) {
+		combo, err := services.GetComboByID(ctx.Param(param))
+		if err != nil {
+			return "", err
+		}
+		return combo.StoreID.Hex(), nil
+	}
+}
+
+// StoreOfModifierGroup resolves the store of the modifier group named by a path parameter
+func StoreOfModifierGroup(param string) StoreResolver {
+	return func(ctx *gin.Context) (string, error) {
+		group, err := services.GetModifierGroupByID(ctx.Param(param))
+		if err != nil {
+			return "", err
+		}
+		return group.StoreID.Hex(), nil
+	}
+}
+
+// StoreOfTable resolves the store of the table named by a path parameter
+func StoreOfTable(param string) StoreResolver {
+	return func(ctx *gin.Context) (string, error) {
+		table, err := services.GetTableByID(ctx.Param(param))
+		if err != nil {
+			return "", err
+		}
+		return table.StoreID.Hex(), nil
+	}
+}
+
+// StoreOfPromotion resolves the store of the promotion named by a path parameter
+func StoreOfPromotion(param string) StoreResolver {
+	return func(ctx *gin.Context) (string, error) {
+		promotion, err := services.GetPromotionByID(ctx.Param(param))
+		if err != nil {
+			return "", err
+		}
+		return promotion.StoreID.Hex(), nil
+	}
+}
+
+// StoreOfPayment resolves the store of the payment named by a path parameter
+func StoreOfPayment(param string) StoreResolver {
+	return func(ctx *gin.Context) (string, error) {
+		payment, err := services.GetPaymentByID(ctx.Param(param))
+		if err != nil {
+			return "", err
+		}
+		return payment.StoreID.Hex(), nil
+	}
+}
+
+// StoreOfDiningSession resolves the store of the dining session named by a path parameter
+func StoreOfDiningSession(param string) StoreResolver {
+	return func(ctx *gin.Context) (string, error) {
+		session, err := services.GetDiningSessionByID(ctx.Param(param))
+		if err != nil {
+			return "", err
+		}
+		return session.StoreID.Hex(), nil
+	}
+}
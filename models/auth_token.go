@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Auth token purposes
+const (
+	AuthTokenPasswordReset     = "password_reset"
+	AuthTokenEmailVerification = "email_verification"
+	AuthTokenTwoFactorLogin    = "two_factor_login"
+)
+
+// AuthToken is a single-use token emailed to a user, such as a password reset
+// link. Only the hash of the token is stored.
+type AuthToken struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Purpose   string             `json:"purpose" bson:"purpose"`
+	TokenHash string             `json:"-" bson:"token_hash"`
+	Email     string             `json:"email" bson:"email"` // address the token was sent to
+	Attempts  int                `json:"attempts" bson:"attempts,omitempty"`
+	ExpiresAt time.Time          `json:"expires_at" bson:"expires_at"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
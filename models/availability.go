@@ -0,0 +1,83 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// AvailabilitySchedule lists the recurring periods in which a category or food
+// item can be ordered, e.g. breakfast 07:00-11:00. An empty schedule means always.
+type AvailabilitySchedule []AvailabilityWindow
+
+// AvailabilityWindow is one recurring period of an availability schedule.
+// Empty Days means every day; empty Start/End mean the start/end of the day.
+// An End at or before Start runs past midnight.
+type AvailabilityWindow struct {
+	Days  []string `json:"days" bson:"days"`
+	Start string   `json:"start" bson:"start"`
+	End   string   `json:"end" bson:"end"`
+}
+
+// IsAvailableAt reports whether t falls inside the schedule. t must already be
+// in the store's time zone.
+func (s AvailabilitySchedule) IsAvailableAt(t time.Time) bool {
+	if len(s) == 0 {
+		return true
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	today := strings.ToLower(t.Weekday().String())
+	yesterday := strings.ToLower(t.AddDate(0, 0, -1).Weekday().String())
+
+	for _, window := range s {
+		start, end, ok := window.minutes()
+		if !ok {
+			continue
+		}
+		if end > start {
+			if window.onDay(today) && minute >= start && minute < end {
+				return true
+			}
+			continue
+		}
+		// Runs past midnight: the evening part today, the early part from yesterday
+		if window.onDay(today) && minute >= start {
+			return true
+		}
+		if window.onDay(yesterday) && minute < end {
+			return true
+		}
+	}
+
+	return false
+}
+
+// onDay reports whether the window applies on the given weekday
+func (w AvailabilityWindow) onDay(day string) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if strings.ToLower(d) == day {
+			return true
+		}
+	}
+	return false
+}
+
+// minutes converts the window to minutes since midnight
+func (w AvailabilityWindow) minutes() (int, int, bool) {
+	start, end := 0, 24*60
+	var err error
+	if w.Start != "" {
+		if start, err = ParseClock(w.Start); err != nil {
+			return 0, 0, false
+		}
+	}
+	if w.End != "" {
+		if end, err = ParseClock(w.End); err != nil {
+			return 0, 0, false
+		}
+	}
+	return start, end, true
+}
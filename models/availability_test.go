@@ -0,0 +1,49 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAvailabilityScheduleIsAvailableAt(t *testing.T) {
+	breakfast := AvailabilityWindow{Start: "07:00", End: "11:00"}
+	weekendBrunch := AvailabilityWindow{Days: []string{"Saturday", "sunday"}, Start: "10:00", End: "14:00"}
+	lateFriday := AvailabilityWindow{Days: []string{"friday"}, Start: "22:00", End: "03:00"}
+	allDayMonday := AvailabilityWindow{Days: []string{"monday"}}
+
+	// 2026-10-16 is a Friday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, time.October, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		schedule AvailabilitySchedule
+		t        time.Time
+		want     bool
+	}{
+		{"empty schedule is always available", nil, at(16, 3, 0), true},
+		{"inside a daily window", AvailabilitySchedule{breakfast}, at(16, 7, 0), true},
+		{"end is exclusive", AvailabilitySchedule{breakfast}, at(16, 11, 0), false},
+		{"before a daily window", AvailabilitySchedule{breakfast}, at(16, 6, 59), false},
+		{"day names ignore case", AvailabilitySchedule{weekendBrunch}, at(17, 12, 0), true},
+		{"other days are unavailable", AvailabilitySchedule{weekendBrunch}, at(16, 12, 0), false},
+		{"any window matches", AvailabilitySchedule{breakfast, weekendBrunch}, at(18, 13, 0), true},
+		{"overnight window before midnight", AvailabilitySchedule{lateFriday}, at(16, 23, 0), true},
+		{"overnight window after midnight", AvailabilitySchedule{lateFriday}, at(17, 2, 59), true},
+		{"overnight window ends", AvailabilitySchedule{lateFriday}, at(17, 3, 0), false},
+		{"overnight window only runs from its day", AvailabilitySchedule{lateFriday}, at(16, 1, 0), false},
+		{"overnight window starts only on its day", AvailabilitySchedule{lateFriday}, at(17, 23, 0), false},
+		{"no start or end means all day", AvailabilitySchedule{allDayMonday}, at(19, 0, 0), true},
+		{"all day ends at midnight", AvailabilitySchedule{allDayMonday}, at(20, 0, 0), false},
+		{"invalid window is ignored", AvailabilitySchedule{{Start: "7am", End: "11:00"}}, at(16, 8, 0), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.schedule.IsAvailableAt(tt.t); got != tt.want {
+				t.Errorf("IsAvailableAt(%s) = %v, want %v", tt.t.Format("Mon 2006-01-02 15:04"), got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package models
+
+import (
+	"time"
+
+	"ordernew/money"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Cart statuses
+const (
+	CartStatusActive      = "active"
+	CartStatusCheckingOut = "checking_out"
+	CartStatusCheckedOut  = "checked_out"
+)
+
+// Cart represents a customer's shopping cart for a single store.
+// Anonymous carts are accessed with the session token returned on creation.
+type Cart struct {
+	ID               primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	StoreID          primitive.ObjectID `json:"store_id" bson:"store_id"`
+	CustomerID       primitive.ObjectID `json:"customer_id,omitzero" bson:"customer_id,omitempty"`
+	SessionTokenHash string             `json:"-" bson:"session_token_hash"`
+	TableToken       string             `json:"-" bson:"table_token,omitempty"`
+	TableID          primitive.ObjectID `json:"table_id,omitzero" bson:"table_id,omitempty"`
+	Items            []CartItem         `json:"items" bson:"items"`
+	Status           string             `json:"status" bson:"status"`
+	OrderID          primitive.ObjectID `json:"order_id,omitzero" bson:"order_id,omitempty"`
+	PromoCode        string             `json:"promo_code,omitempty" bson:"promo_code,omitempty"`
+	ExpiresAt        time.Time          `json:"expires_at" bson:"expires_at"`
+	CreatedAt        time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt        time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// CartItem represents a line in a cart: either a food item or a combo.
+// Prices are not stored; carts are priced live.
+type CartItem struct {
+	ID                primitive.ObjectID   `json:"id" bson:"_id"`
+	FoodItemID        primitive.ObjectID   `json:"food_item_id,omitzero" bson:"food_item_id,omitempty"`
+	ModifierOptionIDs []primitive.ObjectID `json:"modifier_option_ids" bson:"modifier_option_ids"`
+	ComboID           primitive.ObjectID   `json:"combo_id,omitzero" bson:"combo_id,omitempty"`
+	ComboSelections   []ComboSelection     `json:"combo_selections,omitempty" bson:"combo_selections,omitempty"`
+	Quantity          int                  `json:"quantity" bson:"quantity"`
+	Notes             string               `json:"notes" bson:"notes"`
+	AddedAt           time.Time            `json:"added_at" bson:"added_at"`
+}
+
+// CreateCartRequest represents data for opening a cart
+type CreateCartRequest struct {
+	StoreID    string `json:"store_id" binding:"required"`
+	TableToken string `json:"table_token"`
+}
+
+// AddCartItemRequest represents data for adding a food item or a combo to a cart
+type AddCartItemRequest struct {
+	FoodItemID        string                  `json:"food_item_id" binding:"required_without=ComboID"`
+	ModifierOptionIDs []string                `json:"modifier_option_ids"`
+	ComboID           string                  `json:"combo_id"`
+	ComboSelections   []ComboSelectionRequest `json:"combo_selections" binding:"dive"`
+	Quantity          int                     `json:"quantity" binding:"required,gt=0"`
+	Notes             string                  `json:"notes"`
+}
+
+// UpdateCartItemRequest represents data for updating a cart line.
+// ModifierOptionIDs and ComboSelections replace the current choices when sent.
+type UpdateCartItemRequest struct {
+	Quantity          *int                    `json:"quantity" binding:"omitempty,gt=0"`
+	Notes             *string                 `json:"notes"`
+	ModifierOptionIDs []string                `json:"modifier_option_ids"`
+	ComboSelections   []ComboSelectionRequest `json:"combo_selections" binding:"dive"`
+}
+
+// CheckoutCartRequest represents data for turning a cart into an order
+type CheckoutCartRequest struct {
+	OrderType     string `json:"order_type" binding:"omitempty,oneof=dine_in takeaway"`
+	Notes         string `json:"notes"`
+	CustomerName  string `json:"customer_name"`
+	CustomerPhone string `json:"customer_phone"`
+}
+
+// CartItemResponse represents a cart line priced with the current menu
+type CartItemResponse struct {
+	ID                 primitive.ObjectID  `json:"id"`
+	FoodItemID         primitive.ObjectID  `json:"food_item_id,omitzero"`
+	ComboID            primitive.ObjectID  `json:"combo_id,omitzero"`
+	Name               string              `json:"name"`
+	Image              string              `json:"image"`
+	Price              money.Amount        `json:"price"`
+	Modifiers          []OrderItemModifier `json:"modifiers"`
+	ComboItems         []OrderComboItem    `json:"combo_items,omitempty"`
+	UnitPrice          money.Amount        `json:"unit_price"`
+	Quantity           int                 `json:"quantity"`
+	LineTotal          money.Amount        `json:"line_total"`
+	LineTotalFormatted string              `json:"line_total_formatted,omitempty"`
+	Discounts          []LineDiscount      `json:"discounts,omitempty"`
+	Discount           money.Amount        `json:"discount,omitempty"`
+	TaxCategory        string              `json:"tax_category,omitempty"`
+	Taxes              []LineTax           `json:"taxes,omitempty"`
+	Notes              string              `json:"notes"`
+	IsAvailable        bool                `json:"is_available"`
+	UnavailableReason  string              `json:"unavailable_reason,omitempty"`
+}
+
+// CartResponse represents the cart data sent in responses
+type CartResponse struct {
+	ID                     primitive.ObjectID    `json:"id"`
+	StoreID                primitive.ObjectID    `json:"store_id"`
+	CustomerID             primitive.ObjectID    `json:"customer_id,omitzero"`
+	TableID                primitive.ObjectID    `json:"table_id,omitzero"`
+	Items                  []CartItemResponse    `json:"items"`
+	ItemCount              int                   `json:"item_count"`
+	Currency               string                `json:"currency"`
+	Subtotal               money.Amount          `json:"subtotal"`
+	SubtotalFormatted      string                `json:"subtotal_formatted,omitempty"`
+	PromoCode              string                `json:"promo_code,omitempty"`
+	PromoCodeError         string                `json:"promo_code_error,omitempty"` // why the entered code does not apply right now
+	Discounts              []AppliedPromotion    `json:"discounts"`
+	DiscountTotal          money.Amount          `json:"discount_total"`
+	DiscountTotalFormatted string                `json:"discount_total_formatted,omitempty"`
+	PricesIncludeTax       bool                  `json:"prices_include_tax"`
+	ServiceCharge          *AppliedServiceCharge `json:"service_charge,omitempty"`
+	Taxes                  []TaxBreakdown        `json:"taxes"`
+	TaxTotal               money.Amount          `json:"tax_total"`
+	TaxTotalFormatted      string                `json:"tax_total_formatted,omitempty"`
+	Total                  money.Amount          `json:"total"`
+	TotalFormatted         string                `json:"total_formatted,omitempty"`
+	Status                 string                `json:"status"`
+	OrderID                primitive.ObjectID    `json:"order_id,omitzero"`
+	ExpiresAt              time.Time             `json:"expires_at"`
+	CreatedAt              time.Time             `json:"created_at"`
+	UpdatedAt              time.Time             `json:"updated_at"`
+}
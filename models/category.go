@@ -15,6 +15,8 @@ type Category struct {
 	Image       string             `json:"image" bson:"image"`
 	DisplayOrder int               `json:"display_order" bson:"display_order"`
 	IsActive    bool               `json:"is_active" bson:"is_active"`
+	ModifierGroupIDs []primitive.ObjectID `json:"modifier_group_ids" bson:"modifier_group_ids"`
+	Availability AvailabilitySchedule `json:"availability" bson:"availability,omitempty"` // day-parts, empty = always
 	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
 	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
 }
@@ -26,6 +28,7 @@ type CreateCategoryRequest struct {
 	Description  string `json:"description"`
 	Image        string `json:"image"`
 	DisplayOrder int    `json:"display_order"`
+	Availability AvailabilitySchedule `json:"availability"`
 }
 
 // UpdateCategoryRequest represents data for updating a category
@@ -35,6 +38,7 @@ type UpdateCategoryRequest struct {
 	Image        string `json:"image"`
 	DisplayOrder *int   `json:"display_order"`
 	IsActive     *bool  `json:"is_active"`
+	Availability AvailabilitySchedule `json:"availability"` // replaces the schedule; [] clears it
 }
 
 // CategoryResponse represents the category data sent in responses
@@ -46,6 +50,8 @@ type CategoryResponse struct {
 	Image       string             `json:"image"`
 	DisplayOrder int               `json:"display_order"`
 	IsActive    bool               `json:"is_active"`
+	ModifierGroupIDs []primitive.ObjectID `json:"modifier_group_ids"`
+	Availability AvailabilitySchedule `json:"availability"`
 	CreatedAt   time.Time          `json:"created_at"`
 	UpdatedAt   time.Time          `json:"updated_at"`
 }
@@ -60,6 +66,8 @@ func (c *Category) ToCategoryResponse() CategoryResponse {
 		Image:       c.Image,
 		DisplayOrder: c.DisplayOrder,
 		IsActive:    c.IsActive,
+		ModifierGroupIDs: c.ModifierGroupIDs,
+		Availability: c.Availability,
 		CreatedAt:   c.CreatedAt,
 		UpdatedAt:   c.UpdatedAt,
 	}
@@ -0,0 +1,194 @@
+package models
+
+import (
+	"time"
+
+	"ordernew/money"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Combo represents a bundle of food items sold at a fixed price, e.g.
+// burger + fries + drink. Each slot is one component; a slot with several
+// options lets the customer swap that component.
+type Combo struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	StoreID      primitive.ObjectID `json:"store_id" bson:"store_id"`
+	CategoryID   primitive.ObjectID `json:"category_id,omitzero" bson:"category_id,omitempty"`
+	Name         string             `json:"name" bson:"name"`
+	Description  string             `json:"description" bson:"description"`
+	Price        money.Amount       `json:"price" bson:"price"` // minor units of the store currency
+	Image        string             `json:"image" bson:"image"`
+	Slots        []ComboSlot        `json:"slots" bson:"slots"`
+	IsAvailable  bool               `json:"is_available" bson:"is_available"`
+	IsActive     bool               `json:"is_active" bson:"is_active"`
+	DisplayOrder int                `json:"display_order" bson:"display_order"`
+	Tags         []string           `json:"tags" bson:"tags"`
+	TaxCategory  string             `json:"tax_category" bson:"tax_category,omitempty"` // empty = the store's default category
+	CreatedAt    time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// ComboSlot represents one component of a combo. Optional slots may be left out.
+type ComboSlot struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id"`
+	Name       string             `json:"name" bson:"name"`
+	IsRequired bool               `json:"is_required" bson:"is_required"`
+	Options    []ComboSlotOption  `json:"options" bson:"options"`
+}
+
+// ComboSlotOption represents a food item that can fill a combo slot.
+// PriceDelta is added to the combo price when this option is chosen.
+type ComboSlotOption struct {
+	FoodItemID primitive.ObjectID `json:"food_item_id" bson:"food_item_id"`
+	PriceDelta money.Amount       `json:"price_delta" bson:"price_delta"`
+}
+
+// ComboSelection is the food item chosen for a combo slot
+type ComboSelection struct {
+	SlotID     primitive.ObjectID `json:"slot_id" bson:"slot_id"`
+	FoodItemID primitive.ObjectID `json:"food_item_id" bson:"food_item_id"`
+}
+
+// CreateComboRequest represents data for creating a combo
+type CreateComboRequest struct {
+	StoreID      string             `json:"store_id" binding:"required"`
+	CategoryID   string             `json:"category_id"`
+	Name         string             `json:"name" binding:"required"`
+	Description  string             `json:"description"`
+	Price        money.Amount       `json:"price" binding:"required,gt=0"`
+	Image        string             `json:"image"`
+	Slots        []ComboSlotRequest `json:"slots" binding:"required,min=1,dive"`
+	DisplayOrder int                `json:"display_order"`
+	Tags         []string           `json:"tags"`
+	TaxCategory  string             `json:"tax_category" binding:"max=50"`
+}
+
+// UpdateComboRequest represents data for updating a combo.
+// When Slots is sent it replaces all slots; slots sent with an ID keep that ID.
+type UpdateComboRequest struct {
+	CategoryID   string             `json:"category_id"`
+	Name         string             `json:"name"`
+	Description  string             `json:"description"`
+	Price        *money.Amount      `json:"price" binding:"omitempty,gt=0"`
+	Image        string             `json:"image"`
+	Slots        []ComboSlotRequest `json:"slots" binding:"omitempty,min=1,dive"`
+	IsAvailable  *bool              `json:"is_available"`
+	IsActive     *bool              `json:"is_active"`
+	DisplayOrder *int               `json:"display_order"`
+	Tags         []string           `json:"tags"`
+	TaxCategory  *string            `json:"tax_category" binding:"omitempty,max=50"` // "" resets it to the store default
+}
+
+// ComboSlotRequest represents a combo slot in create/update requests.
+// Slots are required unless IsRequired is sent as false.
+type ComboSlotRequest struct {
+	ID         string                   `json:"id"`
+	Name       string                   `json:"name" binding:"required"`
+	IsRequired *bool                    `json:"is_required"`
+	Options    []ComboSlotOptionRequest `json:"options" binding:"required,min=1,dive"`
+}
+
+// ComboSlotOptionRequest represents a combo slot option in create/update requests
+type ComboSlotOptionRequest struct {
+	FoodItemID string       `json:"food_item_id" binding:"required"`
+	PriceDelta money.Amount `json:"price_delta"`
+}
+
+// ComboSelectionRequest represents the food item chosen for a combo slot in cart and order requests
+type ComboSelectionRequest struct {
+	SlotID     string `json:"slot_id" binding:"required"`
+	FoodItemID string `json:"food_item_id" binding:"required"`
+}
+
+// ComboResponse represents the combo data sent in responses, with the
+// components resolved from the current menu
+type ComboResponse struct {
+	ID             primitive.ObjectID  `json:"id"`
+	StoreID        primitive.ObjectID  `json:"store_id"`
+	CategoryID     primitive.ObjectID  `json:"category_id,omitzero"`
+	Name           string              `json:"name"`
+	Description    string              `json:"description"`
+	Price          money.Amount        `json:"price"`
+	PriceFormatted string              `json:"price_formatted,omitempty"`
+	Image          string              `json:"image"`
+	Slots          []ComboSlotResponse `json:"slots"`
+	IsAvailable    bool                `json:"is_available"`
+	IsActive       bool                `json:"is_active"`
+	DisplayOrder   int                 `json:"display_order"`
+	Tags           []string            `json:"tags"`
+	TaxCategory    string              `json:"tax_category"`
+	CreatedAt      time.Time           `json:"created_at"`
+	UpdatedAt      time.Time           `json:"updated_at"`
+}
+
+// ComboSlotResponse represents a combo slot with its options resolved
+type ComboSlotResponse struct {
+	ID          primitive.ObjectID        `json:"id"`
+	Name        string                    `json:"name"`
+	IsRequired  bool                      `json:"is_required"`
+	Options     []ComboSlotOptionResponse `json:"options"`
+	IsAvailable bool                      `json:"is_available"`
+}
+
+// ComboSlotOptionResponse represents a combo slot option with its food item details
+type ComboSlotOptionResponse struct {
+	FoodItemID  primitive.ObjectID `json:"food_item_id"`
+	Name        string             `json:"name"`
+	Image       string             `json:"image"`
+	PriceDelta  money.Amount       `json:"price_delta"`
+	IsAvailable bool               `json:"is_available"`
+}
+
+// ToComboResponse converts Combo to ComboResponse using the given food items.
+// A slot is available when at least one of its options is; the combo is
+// available only when every required slot is.
+func (c *Combo) ToComboResponse(foodItems map[primitive.ObjectID]FoodItem) ComboResponse {
+	response := ComboResponse{
+		ID:           c.ID,
+		StoreID:      c.StoreID,
+		CategoryID:   c.CategoryID,
+		Name:         c.Name,
+		Description:  c.Description,
+		Price:        c.Price,
+		Image:        c.Image,
+		Slots:        []ComboSlotResponse{},
+		IsAvailable:  c.IsActive && c.IsAvailable,
+		IsActive:     c.IsActive,
+		DisplayOrder: c.DisplayOrder,
+		Tags:         c.Tags,
+		TaxCategory:  c.TaxCategory,
+		CreatedAt:    c.CreatedAt,
+		UpdatedAt:    c.UpdatedAt,
+	}
+
+	for _, slot := range c.Slots {
+		slotResponse := ComboSlotResponse{
+			ID:         slot.ID,
+			Name:       slot.Name,
+			IsRequired: slot.IsRequired,
+			Options:    []ComboSlotOptionResponse{},
+		}
+		for _, option := range slot.Options {
+			optionResponse := ComboSlotOptionResponse{
+				FoodItemID: option.FoodItemID,
+				PriceDelta: option.PriceDelta,
+			}
+			if foodItem, ok := foodItems[option.FoodItemID]; ok {
+				optionResponse.Name = foodItem.Name
+				optionResponse.Image = foodItem.Image
+				optionResponse.IsAvailable = foodItem.IsActive && foodItem.IsAvailable
+			}
+			if optionResponse.IsAvailable {
+				slotResponse.IsAvailable = true
+			}
+			slotResponse.Options = append(slotResponse.Options, optionResponse)
+		}
+		if slot.IsRequired && !slotResponse.IsAvailable {
+			response.IsAvailable = false
+		}
+		response.Slots = append(response.Slots, slotResponse)
+	}
+
+	return response
+}
@@ -3,6 +3,8 @@ package models
 import (
 	"time"
 
+	"ordernew/money"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -13,7 +15,7 @@ type FoodItem struct {
 	CategoryID  primitive.ObjectID `json:"category_id" bson:"category_id" binding:"required"`
 	Name        string             `json:"name" bson:"name" binding:"required"`
 	Description string             `json:"description" bson:"description"`
-	Price       float64            `json:"price" bson:"price" binding:"required,gt=0"`
+	Price       money.Amount       `json:"price" bson:"price" binding:"required,gt=0"` // minor units of the store currency
 	Image       string             `json:"image" bson:"image"`
 	IsVeg       bool               `json:"is_veg" bson:"is_veg"`
 	IsAvailable bool               `json:"is_available" bson:"is_available"`
@@ -21,6 +23,9 @@ type FoodItem struct {
 	PrepTime    int                `json:"prep_time" bson:"prep_time"` // in minutes
 	DisplayOrder int               `json:"display_order" bson:"display_order"`
 	Tags        []string           `json:"tags" bson:"tags"` // e.g., "spicy", "bestseller", "new"
+	TaxCategory string             `json:"tax_category" bson:"tax_category,omitempty"` // empty = the store's default category
+	ModifierGroupIDs []primitive.ObjectID `json:"modifier_group_ids" bson:"modifier_group_ids"`
+	Availability AvailabilitySchedule `json:"availability" bson:"availability,omitempty"` // day-parts, empty = always
 	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
 	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
 }
@@ -31,12 +36,14 @@ type CreateFoodItemRequest struct {
 	CategoryID   string   `json:"category_id" binding:"required"`
 	Name         string   `json:"name" binding:"required"`
 	Description  string   `json:"description"`
-	Price        float64  `json:"price" binding:"required,gt=0"`
+	Price        money.Amount `json:"price" binding:"required,gt=0"`
 	Image        string   `json:"image"`
 	IsVeg        bool     `json:"is_veg"`
 	PrepTime     int      `json:"prep_time"`
 	DisplayOrder int      `json:"display_order"`
 	Tags         []string `json:"tags"`
+	TaxCategory  string   `json:"tax_category" binding:"max=50"`
+	Availability AvailabilitySchedule `json:"availability"`
 }
 
 // UpdateFoodItemRequest represents data for updating a food item
@@ -44,7 +51,7 @@ type UpdateFoodItemRequest struct {
 	CategoryID   string    `json:"category_id"`
 	Name         string    `json:"name"`
 	Description  string    `json:"description"`
-	Price        *float64  `json:"price" binding:"omitempty,gt=0"`
+	Price        *money.Amount `json:"price" binding:"omitempty,gt=0"`
 	Image        string    `json:"image"`
 	IsVeg        *bool     `json:"is_veg"`
 	IsAvailable  *bool     `json:"is_available"`
@@ -52,6 +59,8 @@ type UpdateFoodItemRequest struct {
 	PrepTime     *int      `json:"prep_time"`
 	DisplayOrder *int      `json:"display_order"`
 	Tags         []string  `json:"tags"`
+	TaxCategory  *string   `json:"tax_category" binding:"omitempty,max=50"` // "" resets it to the store default
+	Availability AvailabilitySchedule `json:"availability"` // replaces the schedule; [] clears it
 }
 
 // FoodItemResponse represents the food item data sent in responses
@@ -61,7 +70,8 @@ type FoodItemResponse struct {
 	CategoryID  primitive.ObjectID `json:"category_id"`
 	Name        string             `json:"name"`
 	Description string             `json:"description"`
-	Price       float64            `json:"price"`
+	Price       money.Amount       `json:"price"`
+	PriceFormatted string         `json:"price_formatted,omitempty"`
 	Image       string             `json:"image"`
 	IsVeg       bool               `json:"is_veg"`
 	IsAvailable bool               `json:"is_available"`
@@ -69,6 +79,9 @@ type FoodItemResponse struct {
 	PrepTime    int                `json:"prep_time"`
 	DisplayOrder int               `json:"display_order"`
 	Tags        []string           `json:"tags"`
+	TaxCategory string             `json:"tax_category"`
+	ModifierGroupIDs []primitive.ObjectID `json:"modifier_group_ids"`
+	Availability AvailabilitySchedule `json:"availability"`
 	CreatedAt   time.Time          `json:"created_at"`
 	UpdatedAt   time.Time          `json:"updated_at"`
 }
@@ -89,6 +102,9 @@ func (f *FoodItem) ToFoodItemResponse() FoodItemResponse {
 		PrepTime:    f.PrepTime,
 		DisplayOrder: f.DisplayOrder,
 		Tags:        f.Tags,
+		TaxCategory: f.TaxCategory,
+		ModifierGroupIDs: f.ModifierGroupIDs,
+		Availability: f.Availability,
 		CreatedAt:   f.CreatedAt,
 		UpdatedAt:   f.UpdatedAt,
 	}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Login methods
+const (
+	LoginMethodPassword  = "password"
+	LoginMethodOTP       = "otp"
+	LoginMethodTwoFactor = "two_factor"
+)
+
+// Login failure reasons
+const (
+	LoginFailureInvalidCredentials = "invalid_credentials"
+	LoginFailureUnknownEmail       = "unknown_email"
+	LoginFailureLocked             = "locked"
+	LoginFailureInactive           = "inactive"
+	LoginFailureInvalidCode        = "invalid_code"
+	LoginFailureRateLimited        = "rate_limited"
+)
+
+// LoginEvent records a login attempt, successful or not
+type LoginEvent struct {
+	ID            primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID        primitive.ObjectID `json:"user_id,omitempty" bson:"user_id,omitempty"` // unset for unknown emails
+	Email         string             `json:"email,omitempty" bson:"email,omitempty"`
+	Phone         string             `json:"phone,omitempty" bson:"phone,omitempty"`
+	Method        string             `json:"method" bson:"method"`
+	Success       bool               `json:"success" bson:"success"`
+	FailureReason string             `json:"failure_reason,omitempty" bson:"failure_reason,omitempty"`
+	IPAddress     string             `json:"ip_address" bson:"ip_address"`
+	UserAgent     string             `json:"user_agent" bson:"user_agent"`
+	CreatedAt     time.Time          `json:"created_at" bson:"created_at"`
+}
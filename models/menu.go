@@ -0,0 +1,16 @@
+package models
+
+// StoreMenu is the full customer menu of a store: the store with its active
+// categories and, nested in each, the items and combos that can be ordered now
+type StoreMenu struct {
+	Store      StoreResponse   `json:"store"`
+	Categories []MenuCategory  `json:"categories"`
+	Combos     []ComboResponse `json:"combos"` // combos not listed in a category
+}
+
+// MenuCategory is a category of a store menu with its orderable items and combos
+type MenuCategory struct {
+	CategoryResponse
+	Items  []FoodItemResponse `json:"items"`
+	Combos []ComboResponse    `json:"combos"`
+}
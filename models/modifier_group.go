@@ -0,0 +1,101 @@
+package models
+
+import (
+	"time"
+
+	"ordernew/money"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ModifierGroup represents a set of choices for food items, e.g. "Size: pick 1"
+// or "Toppings: up to 5". MaxSelect 0 means there is no upper limit.
+type ModifierGroup struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	StoreID      primitive.ObjectID `json:"store_id" bson:"store_id"`
+	Name         string             `json:"name" bson:"name"`
+	Description  string             `json:"description" bson:"description"`
+	MinSelect    int                `json:"min_select" bson:"min_select"`
+	MaxSelect    int                `json:"max_select" bson:"max_select"`
+	Options      []ModifierOption   `json:"options" bson:"options"`
+	DisplayOrder int                `json:"display_order" bson:"display_order"`
+	IsActive     bool               `json:"is_active" bson:"is_active"`
+	CreatedAt    time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// ModifierOption represents a single choice inside a modifier group
+type ModifierOption struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id"`
+	Name        string             `json:"name" bson:"name"`
+	PriceDelta  money.Amount       `json:"price_delta" bson:"price_delta"` // minor units of the store currency
+	IsAvailable bool               `json:"is_available" bson:"is_available"`
+}
+
+// CreateModifierGroupRequest represents data for creating a modifier group
+type CreateModifierGroupRequest struct {
+	StoreID      string                  `json:"store_id" binding:"required"`
+	Name         string                  `json:"name" binding:"required"`
+	Description  string                  `json:"description"`
+	MinSelect    int                     `json:"min_select" binding:"gte=0"`
+	MaxSelect    int                     `json:"max_select" binding:"gte=0"`
+	Options      []ModifierOptionRequest `json:"options" binding:"required,min=1,dive"`
+	DisplayOrder int                     `json:"display_order"`
+}
+
+// UpdateModifierGroupRequest represents data for updating a modifier group.
+// When Options is sent it replaces the whole list; options sent with an ID keep that ID.
+type UpdateModifierGroupRequest struct {
+	Name         string                  `json:"name"`
+	Description  string                  `json:"description"`
+	MinSelect    *int                    `json:"min_select" binding:"omitempty,gte=0"`
+	MaxSelect    *int                    `json:"max_select" binding:"omitempty,gte=0"`
+	Options      []ModifierOptionRequest `json:"options" binding:"omitempty,min=1,dive"`
+	DisplayOrder *int                    `json:"display_order"`
+	IsActive     *bool                   `json:"is_active"`
+}
+
+// ModifierOptionRequest represents a modifier option in create/update requests
+type ModifierOptionRequest struct {
+	ID          string       `json:"id"`
+	Name        string       `json:"name" binding:"required"`
+	PriceDelta  money.Amount `json:"price_delta"`
+	IsAvailable *bool        `json:"is_available"`
+}
+
+// AttachModifierGroupsRequest represents the modifier groups attached to a food item or category
+type AttachModifierGroupsRequest struct {
+	ModifierGroupIDs []string `json:"modifier_group_ids"`
+}
+
+// ModifierGroupResponse represents the modifier group data sent in responses
+type ModifierGroupResponse struct {
+	ID           primitive.ObjectID `json:"id"`
+	StoreID      primitive.ObjectID `json:"store_id"`
+	Name         string             `json:"name"`
+	Description  string             `json:"description"`
+	MinSelect    int                `json:"min_select"`
+	MaxSelect    int                `json:"max_select"`
+	Options      []ModifierOption   `json:"options"`
+	DisplayOrder int                `json:"display_order"`
+	IsActive     bool               `json:"is_active"`
+	CreatedAt    time.Time          `json:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at"`
+}
+
+// ToModifierGroupResponse converts ModifierGroup to ModifierGroupResponse
+func (g *ModifierGroup) ToModifierGroupResponse() ModifierGroupResponse {
+	return ModifierGroupResponse{
+		ID:           g.ID,
+		StoreID:      g.StoreID,
+		Name:         g.Name,
+		Description:  g.Description,
+		MinSelect:    g.MinSelect,
+		MaxSelect:    g.MaxSelect,
+		Options:      g.Options,
+		DisplayOrder: g.DisplayOrder,
+		IsActive:     g.IsActive,
+		CreatedAt:    g.CreatedAt,
+		UpdatedAt:    g.UpdatedAt,
+	}
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Store schedule states, used by the scheduler to detect open/close transitions
+const (
+	ScheduleStateOpen   = "open"
+	ScheduleStateClosed = "closed"
+)
+
+// OverrideDateLayout is the date format of opening hours overrides
+const OverrideDateLayout = "2006-01-02"
+
+// Weekdays lists the keys of a weekly schedule
+var Weekdays = []string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
+
+// OpeningHours represents the weekly opening schedule of a store, with
+// overrides for holidays and special days. Times are in the store's time zone.
+type OpeningHours struct {
+	Weekly    map[string][]TimeInterval `json:"weekly" bson:"weekly"`
+	Overrides []ScheduleOverride        `json:"overrides" bson:"overrides"`
+}
+
+// TimeInterval represents an opening interval in "HH:MM" format.
+// A Close at or before Open runs past midnight; "24:00" closes at midnight.
+type TimeInterval struct {
+	Open  string `json:"open" bson:"open"`
+	Close string `json:"close" bson:"close"`
+}
+
+// ScheduleOverride replaces the weekly hours on a single date (YYYY-MM-DD).
+// A closed override, or one without intervals, closes the store all day.
+type ScheduleOverride struct {
+	Date      string         `json:"date" bson:"date"`
+	Closed    bool           `json:"closed" bson:"closed"`
+	Intervals []TimeInterval `json:"intervals" bson:"intervals"`
+	Note      string         `json:"note" bson:"note"`
+}
+
+// IsOpenAt reports whether t falls inside the schedule. t must already be in
+// the store's time zone.
+func (h *OpeningHours) IsOpenAt(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+
+	for _, interval := range h.intervalsOn(t) {
+		openAt, closeAt, ok := interval.minutes()
+		if !ok {
+			continue
+		}
+		if closeAt > openAt {
+			if minute >= openAt && minute < closeAt {
+				return true
+			}
+		} else if minute >= openAt {
+			// Runs past midnight
+			return true
+		}
+	}
+
+	// Intervals from the previous day that run past midnight
+	for _, interval := range h.intervalsOn(t.AddDate(0, 0, -1)) {
+		openAt, closeAt, ok := interval.minutes()
+		if ok && closeAt <= openAt && minute < closeAt {
+			return true
+		}
+	}
+
+	return false
+}
+
+// intervalsOn returns the opening intervals of the day of t, applying overrides
+func (h *OpeningHours) intervalsOn(t time.Time) []TimeInterval {
+	date := t.Format(OverrideDateLayout)
+	for _, override := range h.Overrides {
+		if override.Date == date {
+			if override.Closed {
+				return nil
+			}
+			return override.Intervals
+		}
+	}
+	return h.Weekly[strings.ToLower(t.Weekday().String())]
+}
+
+// minutes converts the interval to minutes since midnight
+func (i TimeInterval) minutes() (int, int, bool) {
+	openAt, err := ParseClock(i.Open)
+	if err != nil {
+		return 0, 0, false
+	}
+	closeAt, err := ParseClock(i.Close)
+	if err != nil {
+		return 0, 0, false
+	}
+	return openAt, closeAt, true
+}
+
+// ParseClock converts an "HH:MM" time of day to minutes since midnight.
+// "24:00" is accepted as the end of the day.
+func ParseClock(value string) (int, error) {
+	var hour, minute int
+	if len(value) != 5 || value[2] != ':' {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	if _, err := fmt.Sscanf(value, "%02d:%02d", &hour, &minute); err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	if hour == 24 && minute == 0 {
+		return 24 * 60, nil
+	}
+	if hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return hour*60 + minute, nil
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseClock(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{"00:00", 0, false},
+		{"09:30", 570, false},
+		{"23:59", 1439, false},
+		{"24:00", 1440, false},
+		{"24:01", 0, true},
+		{"25:00", 0, true},
+		{"12:60", 0, true},
+		{"9:30", 0, true},
+		{"09-30", 0, true},
+		{"ab:cd", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseClock(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseClock(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseClock(%q) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpeningHoursIsOpenAt(t *testing.T) {
+	hours := &OpeningHours{
+		Weekly: map[string][]TimeInterval{
+			"monday":   {{Open: "09:00", Close: "17:00"}},
+			"thursday": {{Open: "11:00", Close: "14:00"}, {Open: "18:00", Close: "24:00"}},
+			"friday":   {{Open: "18:00", Close: "02:00"}},
+			"saturday": {{Open: "bad", Close: "12:00"}},
+		},
+		Overrides: []ScheduleOverride{
+			{Date: "2026-10-12", Closed: true, Note: "Holiday"},
+			{Date: "2026-10-24", Intervals: []TimeInterval{{Open: "10:00", Close: "01:00"}}},
+			{Date: "2026-10-26", Intervals: []TimeInterval{}},
+			{Date: "2026-10-30", Closed: true},
+		},
+	}
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, time.October, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name string
+		t    time.Time
+		want bool
+	}{
+		{"opening minute is open", at(19, 9, 0), true},
+		{"closing minute is closed", at(19, 17, 0), false},
+		{"day without hours", at(20, 12, 0), false},
+		{"between two intervals", at(15, 15, 0), false},
+		{"second interval", at(15, 19, 0), true},
+		{"24:00 closes at midnight", at(16, 0, 0), false},
+		{"overnight range before midnight", at(16, 23, 30), true},
+		{"overnight range after midnight", at(17, 1, 59), true},
+		{"overnight range closes", at(17, 2, 0), false},
+		{"invalid interval is ignored", at(17, 11, 0), false},
+		{"closed override", at(12, 10, 0), false},
+		{"override replaces the weekly hours", at(24, 10, 0), true},
+		{"overnight override runs into the next day", at(25, 0, 30), true},
+		{"override without intervals is closed", at(26, 10, 0), false},
+		{"closed override stops the overnight range", at(31, 1, 0), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hours.IsOpenAt(tt.t); got != tt.want {
+				t.Errorf("IsOpenAt(%s) = %v, want %v", tt.t.Format("Mon 2006-01-02 15:04"), got, tt.want)
+			}
+		})
+	}
+}
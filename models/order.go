@@ -0,0 +1,227 @@
+package models
+
+import (
+	"time"
+
+	"ordernew/money"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Order types
+const (
+	OrderTypeDineIn   = "dine_in"
+	OrderTypeTakeaway = "takeaway"
+)
+
+// Order statuses
+const (
+	OrderStatusPlaced    = "placed"
+	OrderStatusAccepted  = "accepted"
+	OrderStatusPreparing = "preparing"
+	OrderStatusReady     = "ready"
+	OrderStatusServed    = "served"
+	OrderStatusPickedUp  = "picked_up"
+	OrderStatusCompleted = "completed"
+	OrderStatusCancelled = "cancelled"
+	OrderStatusRejected  = "rejected"
+)
+
+// Order actors, used to guard status transitions
+const (
+	OrderActorCustomer = "customer"
+	OrderActorStore    = "store"
+	OrderActorPayment  = "payment" // the order moved on because it was paid
+)
+
+// Order represents a customer order placed at a store
+type Order struct {
+	ID               primitive.ObjectID    `json:"id" bson:"_id,omitempty"`
+	StoreID          primitive.ObjectID    `json:"store_id" bson:"store_id"`
+	CustomerID       primitive.ObjectID    `json:"customer_id,omitzero" bson:"customer_id,omitempty"`
+	CustomerName     string                `json:"customer_name" bson:"customer_name"`
+	CustomerPhone    string                `json:"customer_phone" bson:"customer_phone"`
+	OrderType        string                `json:"order_type" bson:"order_type"`
+	TableID          primitive.ObjectID    `json:"table_id,omitzero" bson:"table_id,omitempty"`
+	TableName        string                `json:"table_name,omitempty" bson:"table_name,omitempty"`
+	SessionID        primitive.ObjectID    `json:"session_id,omitzero" bson:"session_id,omitempty"`
+	Items            []OrderItem           `json:"items" bson:"items"`
+	Notes            string                `json:"notes" bson:"notes"`
+	Currency         string                `json:"currency" bson:"currency"` // the store currency when the order was placed
+	Subtotal         money.Amount          `json:"subtotal" bson:"subtotal"`
+	PromoCode        string                `json:"promo_code,omitempty" bson:"promo_code,omitempty"`
+	Discounts        []AppliedPromotion    `json:"discounts" bson:"discounts"` // one entry per promotion
+	DiscountTotal    money.Amount          `json:"discount_total" bson:"discount_total"`
+	PricesIncludeTax bool                  `json:"prices_include_tax" bson:"prices_include_tax"`
+	ServiceCharge    *AppliedServiceCharge `json:"service_charge,omitempty" bson:"service_charge,omitempty"`
+	Taxes            []TaxBreakdown        `json:"taxes" bson:"taxes"` // one entry per tax rate
+	TaxTotal         money.Amount          `json:"tax_total" bson:"tax_total"`
+	Total            money.Amount          `json:"total" bson:"total"`
+	PaymentStatus    string                `json:"payment_status" bson:"payment_status"` // kept in step with the order's payments
+	AmountPaid       money.Amount          `json:"amount_paid" bson:"amount_paid"`       // captured less refunded
+	Status           string                `json:"status" bson:"status"`
+	StatusHistory    []OrderStatusChange   `json:"status_history" bson:"status_history"`
+	CreatedAt        time.Time             `json:"created_at" bson:"created_at"`
+	UpdatedAt        time.Time             `json:"updated_at" bson:"updated_at"`
+}
+
+// OrderItem represents a line item of an order: either a food item or a combo.
+// Name, Price and the taxes are copied from the menu at order time so later
+// menu edits do not change past orders.
+type OrderItem struct {
+	FoodItemID         primitive.ObjectID  `json:"food_item_id,omitzero" bson:"food_item_id,omitempty"`
+	ComboID            primitive.ObjectID  `json:"combo_id,omitzero" bson:"combo_id,omitempty"`
+	Name               string              `json:"name" bson:"name"`
+	Price              money.Amount        `json:"price" bson:"price"`
+	Modifiers          []OrderItemModifier `json:"modifiers,omitempty" bson:"modifiers,omitempty"`
+	ComboItems         []OrderComboItem    `json:"combo_items,omitempty" bson:"combo_items,omitempty"`
+	UnitPrice          money.Amount        `json:"unit_price" bson:"unit_price"`
+	Quantity           int                 `json:"quantity" bson:"quantity"`
+	LineTotal          money.Amount        `json:"line_total" bson:"line_total"`
+	LineTotalFormatted string              `json:"line_total_formatted,omitempty" bson:"-"`
+	Discounts          []LineDiscount      `json:"discounts,omitempty" bson:"discounts,omitempty"`
+	Discount           money.Amount        `json:"discount,omitempty" bson:"discount,omitempty"` // sum of the discounts; taxes are on the line total less this
+	TaxCategory        string              `json:"tax_category,omitempty" bson:"tax_category,omitempty"`
+	Taxes              []LineTax           `json:"taxes,omitempty" bson:"taxes,omitempty"`
+	Notes              string              `json:"notes" bson:"notes"`
+}
+
+// OrderComboItem is a snapshot of the food item chosen for a combo slot
+type OrderComboItem struct {
+	SlotID     primitive.ObjectID `json:"slot_id" bson:"slot_id"`
+	SlotName   string             `json:"slot_name" bson:"slot_name"`
+	FoodItemID primitive.ObjectID `json:"food_item_id" bson:"food_item_id"`
+	Name       string             `json:"name" bson:"name"`
+	PriceDelta money.Amount       `json:"price_delta" bson:"price_delta"`
+}
+
+// OrderItemModifier is a snapshot of a modifier option chosen for a line item
+type OrderItemModifier struct {
+	GroupID    primitive.ObjectID `json:"group_id" bson:"group_id"`
+	GroupName  string             `json:"group_name" bson:"group_name"`
+	OptionID   primitive.ObjectID `json:"option_id" bson:"option_id"`
+	OptionName string             `json:"option_name" bson:"option_name"`
+	PriceDelta money.Amount       `json:"price_delta" bson:"price_delta"`
+}
+
+// OrderStatusChange records a single status transition of an order
+type OrderStatusChange struct {
+	From      string             `json:"from" bson:"from"`
+	To        string             `json:"to" bson:"to"`
+	ChangedBy primitive.ObjectID `json:"changed_by,omitzero" bson:"changed_by,omitempty"`
+	Actor     string             `json:"actor" bson:"actor"`
+	Reason    string             `json:"reason" bson:"reason"`
+	ChangedAt time.Time          `json:"changed_at" bson:"changed_at"`
+}
+
+// CreateOrderRequest represents data for placing an order
+type CreateOrderRequest struct {
+	StoreID       string                   `json:"store_id" binding:"required"`
+	OrderType     string                   `json:"order_type" binding:"omitempty,oneof=dine_in takeaway"`
+	TableToken    string                   `json:"table_token"`
+	Items         []CreateOrderItemRequest `json:"items" binding:"required,min=1,dive"`
+	PromoCode     string                   `json:"promo_code"`
+	Notes         string                   `json:"notes"`
+	CustomerName  string                   `json:"customer_name"`
+	CustomerPhone string                   `json:"customer_phone"`
+}
+
+// CreateOrderItemRequest represents a single line item in an order request.
+// Set either FoodItemID or ComboID.
+type CreateOrderItemRequest struct {
+	FoodItemID        string                  `json:"food_item_id" binding:"required_without=ComboID"`
+	ModifierOptionIDs []string                `json:"modifier_option_ids"`
+	ComboID           string                  `json:"combo_id"`
+	ComboSelections   []ComboSelectionRequest `json:"combo_selections" binding:"dive"`
+	Quantity          int                     `json:"quantity" binding:"required,gt=0"`
+	Notes             string                  `json:"notes"`
+}
+
+// UpdateOrderStatusRequest represents data for moving an order to a new status
+type UpdateOrderStatusRequest struct {
+	Status string `json:"status" binding:"required"`
+	Reason string `json:"reason"`
+}
+
+// OrderResponse represents the order data sent in responses
+type OrderResponse struct {
+	ID                     primitive.ObjectID    `json:"id"`
+	StoreID                primitive.ObjectID    `json:"store_id"`
+	CustomerID             primitive.ObjectID    `json:"customer_id,omitzero"`
+	CustomerName           string                `json:"customer_name"`
+	CustomerPhone          string                `json:"customer_phone"`
+	OrderType              string                `json:"order_type"`
+	TableID                primitive.ObjectID    `json:"table_id,omitzero"`
+	TableName              string                `json:"table_name,omitempty"`
+	SessionID              primitive.ObjectID    `json:"session_id,omitzero"`
+	Items                  []OrderItem           `json:"items"`
+	Notes                  string                `json:"notes"`
+	Currency               string                `json:"currency"`
+	Subtotal               money.Amount          `json:"subtotal"`
+	SubtotalFormatted      string                `json:"subtotal_formatted,omitempty"`
+	PromoCode              string                `json:"promo_code,omitempty"`
+	Discounts              []AppliedPromotion    `json:"discounts"`
+	DiscountTotal          money.Amount          `json:"discount_total"`
+	DiscountTotalFormatted string                `json:"discount_total_formatted,omitempty"`
+	PricesIncludeTax       bool                  `json:"prices_include_tax"`
+	ServiceCharge          *AppliedServiceCharge `json:"service_charge,omitempty"`
+	Taxes                  []TaxBreakdown        `json:"taxes"`
+	TaxTotal               money.Amount          `json:"tax_total"`
+	TaxTotalFormatted      string                `json:"tax_total_formatted,omitempty"`
+	Total                  money.Amount          `json:"total"`
+	TotalFormatted         string                `json:"total_formatted,omitempty"`
+	PaymentStatus          string                `json:"payment_status"`
+	AmountPaid             money.Amount          `json:"amount_paid"`
+	AmountPaidFormatted    string                `json:"amount_paid_formatted,omitempty"`
+	BalanceDue             money.Amount          `json:"balance_due"`
+	BalanceDueFormatted    string                `json:"balance_due_formatted,omitempty"`
+	Status                 string                `json:"status"`
+	StatusHistory          []OrderStatusChange   `json:"status_history"`
+	CreatedAt              time.Time             `json:"created_at"`
+	UpdatedAt              time.Time             `json:"updated_at"`
+}
+
+// ToOrderResponse converts Order to OrderResponse
+func (o *Order) ToOrderResponse() OrderResponse {
+	// Orders placed before payments existed have no payment status
+	paymentStatus := o.PaymentStatus
+	if paymentStatus == "" {
+		paymentStatus = OrderPaymentUnpaid
+	}
+
+	return OrderResponse{
+		ID:               o.ID,
+		StoreID:          o.StoreID,
+		CustomerID:       o.CustomerID,
+		CustomerName:     o.CustomerName,
+		CustomerPhone:    o.CustomerPhone,
+		OrderType:        o.OrderType,
+		TableID:          o.TableID,
+		TableName:        o.TableName,
+		SessionID:        o.SessionID,
+		Items:            o.Items,
+		Notes:            o.Notes,
+		Currency:         o.Currency,
+		Subtotal:         o.Subtotal,
+		PromoCode:        o.PromoCode,
+		Discounts:        o.Discounts,
+		DiscountTotal:    o.DiscountTotal,
+		PricesIncludeTax: o.PricesIncludeTax,
+		ServiceCharge:    o.ServiceCharge,
+		Taxes:            o.Taxes,
+		TaxTotal:         o.TaxTotal,
+		Total:            o.Total,
+		PaymentStatus:    paymentStatus,
+		AmountPaid:       o.AmountPaid,
+		BalanceDue:       o.BalanceDue(),
+		Status:           o.Status,
+		StatusHistory:    o.StatusHistory,
+		CreatedAt:        o.CreatedAt,
+		UpdatedAt:        o.UpdatedAt,
+	}
+}
+
+// BalanceDue returns what is left to pay on the order
+func (o *Order) BalanceDue() money.Amount {
+	return max(o.Total-o.AmountPaid, 0)
+}
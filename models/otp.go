@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OTPCode is a login code sent to a phone number by SMS. Only a keyed hash of
+// the code is stored. Codes stay in the collection for an hour after they are
+// sent, to rate limit requests per phone number and IP address.
+type OTPCode struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Phone      string             `json:"phone" bson:"phone"`
+	CodeHash   string             `json:"-" bson:"code_hash"`
+	IPAddress  string             `json:"ip_address" bson:"ip_address"`
+	Attempts   int                `json:"attempts" bson:"attempts"`
+	ExpiresAt  time.Time          `json:"expires_at" bson:"expires_at"`
+	ConsumedAt *time.Time         `json:"consumed_at,omitempty" bson:"consumed_at,omitempty"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// OTPRequest represents a request for a login code
+type OTPRequest struct {
+	Phone string `json:"phone" binding:"required,e164"`
+}
+
+// OTPVerifyRequest represents a login code being redeemed
+type OTPVerifyRequest struct {
+	Phone string `json:"phone" binding:"required,e164"`
+	Code  string `json:"code" binding:"required,len=6,numeric"`
+}
@@ -0,0 +1,166 @@
+package models
+
+import (
+	"time"
+
+	"ordernew/money"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Payment methods
+const (
+	PaymentMethodCard         = "card"           // through the payment provider
+	PaymentMethodCash         = "cash"           // taken by staff, recorded as captured
+	PaymentMethodPayAtCounter = "pay_at_counter" // the customer pays at the counter; staff capture it then
+)
+
+// Payment statuses
+const (
+	PaymentStatusPending           = "pending"    // waiting for the customer or the counter
+	PaymentStatusAuthorized        = "authorized" // funds held, not yet taken
+	PaymentStatusCaptured          = "captured"
+	PaymentStatusPartiallyRefunded = "partially_refunded"
+	PaymentStatusRefunded          = "refunded"
+	PaymentStatusVoided            = "voided"
+	PaymentStatusFailed            = "failed"
+)
+
+// Payment statuses of an order, worked out from its payments
+const (
+	OrderPaymentUnpaid        = "unpaid"
+	OrderPaymentAuthorized    = "authorized" // nothing taken yet, but payments are authorized
+	OrderPaymentPartiallyPaid = "partially_paid"
+	OrderPaymentPaid          = "paid"
+	OrderPaymentRefunded      = "refunded"
+)
+
+// Payment ledger entry types
+const (
+	PaymentEventCreated    = "created"
+	PaymentEventAuthorized = "authorized"
+	PaymentEventCaptured   = "captured"
+	PaymentEventVoided     = "voided"
+	PaymentEventRefunded   = "refunded"
+	PaymentEventFailed     = "failed"
+)
+
+// Payment is one attempt to pay for an order or a sub-bill, in full or in part.
+// Its events are the ledger of everything that happened to it.
+type Payment struct {
+	ID                primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	OrderID           primitive.ObjectID `json:"order_id,omitzero" bson:"order_id,omitempty"`       // set for order payments
+	SubBillID         primitive.ObjectID `json:"sub_bill_id,omitzero" bson:"sub_bill_id,omitempty"` // or for sub-bill payments
+	StoreID           primitive.ObjectID `json:"store_id" bson:"store_id"`
+	CustomerID        primitive.ObjectID `json:"customer_id,omitzero" bson:"customer_id,omitempty"`
+	Method            string             `json:"method" bson:"method"`
+	Provider          string             `json:"provider,omitempty" bson:"provider,omitempty"`                       // card payments only
+	ProviderPaymentID string             `json:"provider_payment_id,omitempty" bson:"provider_payment_id,omitempty"` // the provider's intent ID
+	Currency          string             `json:"currency" bson:"currency"`
+	Amount            money.Amount       `json:"amount" bson:"amount"`
+	AmountCaptured    money.Amount       `json:"amount_captured" bson:"amount_captured"`
+	AmountRefunded    money.Amount       `json:"amount_refunded" bson:"amount_refunded"`
+	Status            string             `json:"status" bson:"status"`
+	FailureReason     string             `json:"failure_reason,omitempty" bson:"failure_reason,omitempty"`
+	Events            []PaymentEvent     `json:"events" bson:"events"`
+	CreatedBy         primitive.ObjectID `json:"created_by,omitzero" bson:"created_by,omitempty"`
+	CreatedAt         time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt         time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// PaymentEvent is an entry of a payment's ledger
+type PaymentEvent struct {
+	Type      string             `json:"type" bson:"type"`
+	Amount    money.Amount       `json:"amount" bson:"amount"`
+	Reference string             `json:"reference,omitempty" bson:"reference,omitempty"` // provider event or refund ID
+	Actor     primitive.ObjectID `json:"actor,omitzero" bson:"actor,omitempty"`          // empty for provider webhooks
+	Note      string             `json:"note,omitempty" bson:"note,omitempty"`
+	At        time.Time          `json:"at" bson:"at"`
+}
+
+// PaymentWebhookEvent records a processed provider webhook, so a redelivered one is ignored
+type PaymentWebhookEvent struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"`
+	Provider    string             `bson:"provider"`
+	EventID     string             `bson:"event_id"`
+	Type        string             `bson:"type"`
+	ProcessedAt time.Time          `bson:"processed_at"`
+}
+
+// CreatePaymentRequest represents data for paying an order or a sub-bill
+type CreatePaymentRequest struct {
+	Method string       `json:"method" binding:"required,oneof=card cash pay_at_counter"`
+	Amount money.Amount `json:"amount" binding:"gte=0"` // 0 = the balance due
+}
+
+// CapturePaymentRequest represents data for capturing a payment
+type CapturePaymentRequest struct {
+	Amount money.Amount `json:"amount" binding:"gte=0"` // 0 = the whole amount
+}
+
+// RefundPaymentRequest represents data for refunding a payment
+type RefundPaymentRequest struct {
+	Amount money.Amount `json:"amount" binding:"gte=0"` // 0 = everything not yet refunded
+	Reason string       `json:"reason"`
+}
+
+// ConfirmSandboxPaymentRequest represents the outcome of a simulated card confirmation
+type ConfirmSandboxPaymentRequest struct {
+	Outcome string `json:"outcome" binding:"required,oneof=succeeded failed"`
+}
+
+// PaymentResponse represents the payment data sent in responses
+type PaymentResponse struct {
+	ID                primitive.ObjectID `json:"id"`
+	OrderID           primitive.ObjectID `json:"order_id,omitzero"`
+	SubBillID         primitive.ObjectID `json:"sub_bill_id,omitzero"`
+	StoreID           primitive.ObjectID `json:"store_id"`
+	CustomerID        primitive.ObjectID `json:"customer_id,omitzero"`
+	Method            string             `json:"method"`
+	Provider          string             `json:"provider,omitempty"`
+	ProviderPaymentID string             `json:"provider_payment_id,omitempty"`
+	ClientSecret      string             `json:"client_secret,omitempty"` // only when the payment is created
+	Currency          string             `json:"currency"`
+	Amount            money.Amount       `json:"amount"`
+	AmountFormatted   string             `json:"amount_formatted,omitempty"`
+	AmountCaptured    money.Amount       `json:"amount_captured"`
+	AmountRefunded    money.Amount       `json:"amount_refunded"`
+	Status            string             `json:"status"`
+	FailureReason     string             `json:"failure_reason,omitempty"`
+	Events            []PaymentEvent     `json:"events"`
+	CreatedAt         time.Time          `json:"created_at"`
+	UpdatedAt         time.Time          `json:"updated_at"`
+}
+
+// ToPaymentResponse converts Payment to PaymentResponse
+func (p *Payment) ToPaymentResponse() PaymentResponse {
+	return PaymentResponse{
+		ID:                p.ID,
+		OrderID:           p.OrderID,
+		SubBillID:         p.SubBillID,
+		StoreID:           p.StoreID,
+		CustomerID:        p.CustomerID,
+		Method:            p.Method,
+		Provider:          p.Provider,
+		ProviderPaymentID: p.ProviderPaymentID,
+		Currency:          p.Currency,
+		Amount:            p.Amount,
+		AmountCaptured:    p.AmountCaptured,
+		AmountRefunded:    p.AmountRefunded,
+		Status:            p.Status,
+		FailureReason:     p.FailureReason,
+		Events:            p.Events,
+		CreatedAt:         p.CreatedAt,
+		UpdatedAt:         p.UpdatedAt,
+	}
+}
+
+// IsOpen reports whether a payment may still be captured or voided
+func (p *Payment) IsOpen() bool {
+	return p.Status == PaymentStatusPending || p.Status == PaymentStatusAuthorized
+}
+
+// Net returns what the payment has taken and kept: captured less refunded
+func (p *Payment) Net() money.Amount {
+	return p.AmountCaptured - p.AmountRefunded
+}
@@ -0,0 +1,109 @@
+package models
+
+// Global user roles
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// PermissionAll grants every permission
+const PermissionAll = "*"
+
+// Global permissions, granted by a user's role
+const (
+	PermissionUsersRead        = "users:read"
+	PermissionUsersUpdate      = "users:update"
+	PermissionUsersDelete      = "users:delete"
+	PermissionUsersManageRoles = "users:manage_roles"
+	PermissionProductsRead     = "products:read"
+	PermissionProductsWrite    = "products:write"
+	PermissionStoresCreate     = "stores:create"
+)
+
+// Store permissions, granted by a user's role in a store
+const (
+	PermissionStoreUpdate        = "store:update"
+	PermissionStoreDelete        = "store:delete"
+	PermissionMenuWrite          = "menu:write"
+	PermissionTablesRead         = "tables:read"
+	PermissionTablesWrite        = "tables:write"
+	PermissionSessionsManage     = "sessions:manage"
+	PermissionOrdersRead         = "orders:read"
+	PermissionOrdersUpdateStatus = "orders:update_status"
+	PermissionStaffManage        = "staff:manage"
+	PermissionPromotionsManage   = "promotions:manage"
+	PermissionPaymentsManage     = "payments:manage"
+	PermissionPaymentsRefund     = "payments:refund"
+)
+
+// RolePermissions bundles the global permissions of each user role
+var RolePermissions = map[string][]string{
+	RoleAdmin: {PermissionAll},
+	RoleUser: {
+		PermissionProductsRead,
+		PermissionProductsWrite,
+		PermissionStoresCreate,
+	},
+}
+
+// StoreRolePermissions bundles the store permissions of each store role
+var StoreRolePermissions = map[string][]string{
+	StoreRoleOwner: {PermissionAll},
+	StoreRoleManager: {
+		PermissionStoreUpdate,
+		PermissionMenuWrite,
+		PermissionTablesRead,
+		PermissionTablesWrite,
+		PermissionSessionsManage,
+		PermissionOrdersRead,
+		PermissionOrdersUpdateStatus,
+		PermissionStaffManage,
+		PermissionPromotionsManage,
+		PermissionPaymentsManage,
+		PermissionPaymentsRefund,
+	},
+	StoreRoleCashier: {
+		PermissionTablesRead,
+		PermissionSessionsManage,
+		PermissionOrdersRead,
+		PermissionOrdersUpdateStatus,
+		PermissionPaymentsManage,
+	},
+	StoreRoleKitchen: {
+		PermissionOrdersRead,
+		PermissionOrdersUpdateStatus,
+	},
+	StoreRoleWaiter: {
+		PermissionTablesRead,
+		PermissionSessionsManage,
+		PermissionOrdersRead,
+		PermissionOrdersUpdateStatus,
+		PermissionPaymentsManage,
+	},
+}
+
+// IsKnownRole reports whether role is a global user role
+func IsKnownRole(role string) bool {
+	_, ok := RolePermissions[role]
+	return ok
+}
+
+// RoleHasPermission reports whether a global user role grants a permission
+func RoleHasPermission(role, permission string) bool {
+	return hasPermission(RolePermissions[role], permission)
+}
+
+// StoreRoleHasPermission reports whether a store role grants a permission
+func StoreRoleHasPermission(role, permission string) bool {
+	return hasPermission(StoreRolePermissions[role], permission)
+}
+
+// hasPermission reports whether a permission bundle contains a permission
+func hasPermission(permissions []string, permission string) bool {
+	for _, p := range permissions {
+		if p == PermissionAll || p == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package models
+
+import "ordernew/money"
+
+// The Localize methods fill in the *_formatted fields of a response, which
+// show its amounts the way the given locale writes them, e.g. "$12.99" or
+// "12,99 €". Amounts themselves are always integers in minor units.
+
+// Localize formats the price of a food item in the store currency
+func (r *FoodItemResponse) Localize(currency, locale string) {
+	r.PriceFormatted = money.Format(r.Price, currency, locale)
+}
+
+// Localize formats the price of a combo in the store currency
+func (r *ComboResponse) Localize(currency, locale string) {
+	r.PriceFormatted = money.Format(r.Price, currency, locale)
+}
+
+// Localize formats every price on a store menu in the store currency
+func (m *StoreMenu) Localize(locale string) {
+	currency := m.Store.Currency
+	for i := range m.Categories {
+		for j := range m.Categories[i].Items {
+			m.Categories[i].Items[j].Localize(currency, locale)
+		}
+		for j := range m.Categories[i].Combos {
+			m.Categories[i].Combos[j].Localize(currency, locale)
+		}
+	}
+	for i := range m.Combos {
+		m.Combos[i].Localize(currency, locale)
+	}
+}
+
+// Localize formats the line totals and totals of a cart
+func (r *CartResponse) Localize(locale string) {
+	for i := range r.Items {
+		if r.Items[i].IsAvailable {
+			r.Items[i].LineTotalFormatted = money.Format(r.Items[i].LineTotal, r.Currency, locale)
+		}
+	}
+	r.SubtotalFormatted = money.Format(r.Subtotal, r.Currency, locale)
+	r.DiscountTotalFormatted = money.Format(r.DiscountTotal, r.Currency, locale)
+	r.TaxTotalFormatted = money.Format(r.TaxTotal, r.Currency, locale)
+	r.TotalFormatted = money.Format(r.Total, r.Currency, locale)
+}
+
+// Localize formats the line totals and totals of an order
+func (r *OrderResponse) Localize(locale string) {
+	// Items are shared with the order, so format a copy
+	items := make([]OrderItem, len(r.Items))
+	copy(items, r.Items)
+	for i := range items {
+		items[i].LineTotalFormatted = money.Format(items[i].LineTotal, r.Currency, locale)
+	}
+	r.Items = items
+	r.SubtotalFormatted = money.Format(r.Subtotal, r.Currency, locale)
+	r.DiscountTotalFormatted = money.Format(r.DiscountTotal, r.Currency, locale)
+	r.TaxTotalFormatted = money.Format(r.TaxTotal, r.Currency, locale)
+	r.TotalFormatted = money.Format(r.Total, r.Currency, locale)
+	r.AmountPaidFormatted = money.Format(r.AmountPaid, r.Currency, locale)
+	r.BalanceDueFormatted = money.Format(r.BalanceDue, r.Currency, locale)
+}
+
+// Localize formats the amount of a payment
+func (r *PaymentResponse) Localize(locale string) {
+	r.AmountFormatted = money.Format(r.Amount, r.Currency, locale)
+}
+
+// Localize formats the orders, sub-bills, running total and balance of a dining session
+func (r *DiningSessionResponse) Localize(locale string) {
+	for i := range r.Orders {
+		r.Orders[i].Localize(locale)
+	}
+	for i := range r.Bills {
+		r.Bills[i].Localize(locale)
+	}
+	r.TotalFormatted = money.Format(r.Total, r.Currency, locale)
+	r.AmountPaidFormatted = money.Format(r.AmountPaid, r.Currency, locale)
+	r.BalanceDueFormatted = money.Format(r.BalanceDue, r.Currency, locale)
+}
+
+// Localize formats the total and amount due of a sub-bill
+func (r *SubBillResponse) Localize(locale string) {
+	r.TotalFormatted = money.Format(r.Total, r.Currency, locale)
+	r.AmountDueFormatted = money.Format(r.AmountDue, r.Currency, locale)
+}
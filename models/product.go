@@ -3,6 +3,8 @@ package models
 import (
 	"time"
 
+	"ordernew/money"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -11,7 +13,8 @@ type Product struct {
 	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
 	Name        string             `json:"name" bson:"name" binding:"required"`
 	Description string             `json:"description" bson:"description"`
-	Price       float64            `json:"price" bson:"price" binding:"required,gt=0"`
+	Price       money.Amount       `json:"price" bson:"price" binding:"required,gt=0"` // minor units of Currency
+	Currency    string             `json:"currency" bson:"currency"`
 	Quantity    int                `json:"quantity" bson:"quantity" binding:"required,gte=0"`
 	Category    string             `json:"category" bson:"category"`
 	SKU         string             `json:"sku" bson:"sku"`
@@ -23,23 +26,24 @@ type Product struct {
 
 // CreateProductRequest represents data for creating a product
 type CreateProductRequest struct {
-	Name        string  `json:"name" binding:"required"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price" binding:"required,gt=0"`
-	Quantity    int     `json:"quantity" binding:"required,gte=0"`
-	Category    string  `json:"category"`
-	SKU         string  `json:"sku" binding:"required"`
+	Name        string       `json:"name" binding:"required"`
+	Description string       `json:"description"`
+	Price       money.Amount `json:"price" binding:"required,gt=0"`
+	Currency    string       `json:"currency"`
+	Quantity    int          `json:"quantity" binding:"required,gte=0"`
+	Category    string       `json:"category"`
+	SKU         string       `json:"sku" binding:"required"`
 }
 
 // UpdateProductRequest represents data for updating a product
 type UpdateProductRequest struct {
-	Name        string   `json:"name"`
-	Description string   `json:"description"`
-	Price       *float64 `json:"price" binding:"omitempty,gt=0"`
-	Quantity    *int     `json:"quantity" binding:"omitempty,gte=0"`
-	Category    string   `json:"category"`
-	SKU         string   `json:"sku"`
-	IsActive    *bool    `json:"is_active"`
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	Price       *money.Amount `json:"price" binding:"omitempty,gt=0"`
+	Quantity    *int          `json:"quantity" binding:"omitempty,gte=0"`
+	Category    string        `json:"category"`
+	SKU         string        `json:"sku"`
+	IsActive    *bool         `json:"is_active"`
 }
 
 // ProductResponse represents the product data sent in responses
@@ -47,7 +51,8 @@ type ProductResponse struct {
 	ID          primitive.ObjectID `json:"id"`
 	Name        string             `json:"name"`
 	Description string             `json:"description"`
-	Price       float64            `json:"price"`
+	Price       money.Amount       `json:"price"`
+	Currency    string             `json:"currency"`
 	Quantity    int                `json:"quantity"`
 	Category    string             `json:"category"`
 	SKU         string             `json:"sku"`
@@ -64,6 +69,7 @@ func (p *Product) ToProductResponse() ProductResponse {
 		Name:        p.Name,
 		Description: p.Description,
 		Price:       p.Price,
+		Currency:    p.Currency,
 		Quantity:    p.Quantity,
 		Category:    p.Category,
 		SKU:         p.SKU,
@@ -0,0 +1,174 @@
+package models
+
+import (
+	"time"
+
+	"ordernew/money"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Promotion effects
+const (
+	PromotionPercentOff    = "percent_off"    // a percentage off each qualifying line
+	PromotionAmountOff     = "amount_off"     // a fixed amount off the qualifying lines together
+	PromotionFreeItem      = "free_item"      // buy N qualifying items, get M of them free
+	PromotionPriceOverride = "price_override" // qualifying items sell at a set unit price
+)
+
+// Promotion is a discount rule of a store. Promotions with a code apply
+// only when the customer enters it; the others apply automatically.
+type Promotion struct {
+	ID           primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	StoreID      primitive.ObjectID  `json:"store_id" bson:"store_id"`
+	Name         string              `json:"name" bson:"name"`
+	Description  string              `json:"description" bson:"description"`
+	Code         string              `json:"code,omitempty" bson:"code,omitempty"` // upper case; empty = automatic
+	Conditions   PromotionConditions `json:"conditions" bson:"conditions"`
+	Effect       PromotionEffect     `json:"effect" bson:"effect"`
+	Priority     int                 `json:"priority" bson:"priority"`             // higher applies first
+	UsageLimit   int                 `json:"usage_limit" bson:"usage_limit"`       // orders in total; 0 = unlimited
+	PerUserLimit int                 `json:"per_user_limit" bson:"per_user_limit"` // orders per customer; 0 = unlimited
+	UsageCount   int                 `json:"usage_count" bson:"usage_count"`
+	IsActive     bool                `json:"is_active" bson:"is_active"`
+	CreatedAt    time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt    time.Time           `json:"updated_at" bson:"updated_at"`
+}
+
+// PromotionConditions limit when a promotion applies and to which lines.
+// Lines qualify when they match any of the categories and any of the tags;
+// with neither set, every line qualifies.
+type PromotionConditions struct {
+	CategoryIDs []primitive.ObjectID `json:"category_ids" bson:"category_ids"`
+	Tags        []string             `json:"tags" bson:"tags"` // matched against the tags of food items and combos
+	StartsAt    *time.Time           `json:"starts_at" bson:"starts_at,omitempty"`
+	EndsAt      *time.Time           `json:"ends_at" bson:"ends_at,omitempty"`
+	Schedule    AvailabilitySchedule `json:"schedule" bson:"schedule,omitempty"` // recurring hours, e.g. happy hour; empty = always
+	MinSubtotal money.Amount         `json:"min_subtotal" bson:"min_subtotal"`   // spend on qualifying lines, before discounts
+}
+
+// PromotionEffect is what a promotion takes off
+type PromotionEffect struct {
+	Type         string       `json:"type" bson:"type" binding:"required,oneof=percent_off amount_off free_item price_override"`
+	Percent      float64      `json:"percent,omitempty" bson:"percent,omitempty"`             // percent_off
+	Amount       money.Amount `json:"amount,omitempty" bson:"amount,omitempty"`               // amount_off: amount off; price_override: unit price
+	BuyQuantity  int          `json:"buy_quantity,omitempty" bson:"buy_quantity,omitempty"`   // free_item: items paid for
+	FreeQuantity int          `json:"free_quantity,omitempty" bson:"free_quantity,omitempty"` // free_item: items then free, default 1
+}
+
+// PromotionConditionsRequest represents promotion conditions in create/update requests
+type PromotionConditionsRequest struct {
+	CategoryIDs []string             `json:"category_ids"`
+	Tags        []string             `json:"tags"`
+	StartsAt    *time.Time           `json:"starts_at"`
+	EndsAt      *time.Time           `json:"ends_at"`
+	Schedule    AvailabilitySchedule `json:"schedule"`
+	MinSubtotal money.Amount         `json:"min_subtotal" binding:"gte=0"`
+}
+
+// CreatePromotionRequest represents data for creating a promotion
+type CreatePromotionRequest struct {
+	StoreID      string                     `json:"store_id" binding:"required"`
+	Name         string                     `json:"name" binding:"required"`
+	Description  string                     `json:"description"`
+	Code         string                     `json:"code" binding:"max=32"`
+	Conditions   PromotionConditionsRequest `json:"conditions"`
+	Effect       PromotionEffect            `json:"effect" binding:"required"`
+	Priority     int                        `json:"priority"`
+	UsageLimit   int                        `json:"usage_limit" binding:"gte=0"`
+	PerUserLimit int                        `json:"per_user_limit" binding:"gte=0"`
+}
+
+// UpdatePromotionRequest represents data for updating a promotion.
+// Conditions and Effect replace the current ones when sent.
+type UpdatePromotionRequest struct {
+	Name         string                      `json:"name"`
+	Description  string                      `json:"description"`
+	Code         *string                     `json:"code" binding:"omitempty,max=32"` // "" makes the promotion automatic
+	Conditions   *PromotionConditionsRequest `json:"conditions"`
+	Effect       *PromotionEffect            `json:"effect"`
+	Priority     *int                        `json:"priority"`
+	UsageLimit   *int                        `json:"usage_limit" binding:"omitempty,gte=0"`
+	PerUserLimit *int                        `json:"per_user_limit" binding:"omitempty,gte=0"`
+	IsActive     *bool                       `json:"is_active"`
+}
+
+// ApplyPromoCodeRequest represents data for entering a promo code on a cart
+type ApplyPromoCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// PromotionResponse represents the promotion data sent in responses
+type PromotionResponse struct {
+	ID           primitive.ObjectID  `json:"id"`
+	StoreID      primitive.ObjectID  `json:"store_id"`
+	Name         string              `json:"name"`
+	Description  string              `json:"description"`
+	Code         string              `json:"code,omitempty"`
+	Conditions   PromotionConditions `json:"conditions"`
+	Effect       PromotionEffect     `json:"effect"`
+	Priority     int                 `json:"priority"`
+	UsageLimit   int                 `json:"usage_limit"`
+	PerUserLimit int                 `json:"per_user_limit"`
+	UsageCount   int                 `json:"usage_count"`
+	IsActive     bool                `json:"is_active"`
+	CreatedAt    time.Time           `json:"created_at"`
+	UpdatedAt    time.Time           `json:"updated_at"`
+}
+
+// ToPromotionResponse converts Promotion to PromotionResponse
+func (p *Promotion) ToPromotionResponse() PromotionResponse {
+	return PromotionResponse{
+		ID:           p.ID,
+		StoreID:      p.StoreID,
+		Name:         p.Name,
+		Description:  p.Description,
+		Code:         p.Code,
+		Conditions:   p.Conditions,
+		Effect:       p.Effect,
+		Priority:     p.Priority,
+		UsageLimit:   p.UsageLimit,
+		PerUserLimit: p.PerUserLimit,
+		UsageCount:   p.UsageCount,
+		IsActive:     p.IsActive,
+		CreatedAt:    p.CreatedAt,
+		UpdatedAt:    p.UpdatedAt,
+	}
+}
+
+// IsRunningAt reports whether the promotion's date window and schedule allow it at t.
+// t must already be in the store's time zone.
+func (p *Promotion) IsRunningAt(t time.Time) bool {
+	if p.Conditions.StartsAt != nil && t.Before(*p.Conditions.StartsAt) {
+		return false
+	}
+	if p.Conditions.EndsAt != nil && !t.Before(*p.Conditions.EndsAt) {
+		return false
+	}
+	return p.Conditions.Schedule.IsAvailableAt(t)
+}
+
+// PromotionUsage counts the orders a customer placed with a promotion,
+// for per-customer usage limits
+type PromotionUsage struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	PromotionID primitive.ObjectID `json:"promotion_id" bson:"promotion_id"`
+	UserID      primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Count       int                `json:"count" bson:"count"`
+	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// LineDiscount is the part of a line's price a promotion took off
+type LineDiscount struct {
+	PromotionID primitive.ObjectID `json:"promotion_id" bson:"promotion_id"`
+	Name        string             `json:"name" bson:"name"`
+	Amount      money.Amount       `json:"amount" bson:"amount"`
+}
+
+// AppliedPromotion totals the discount of one promotion over a cart or order
+type AppliedPromotion struct {
+	PromotionID primitive.ObjectID `json:"promotion_id" bson:"promotion_id"`
+	Name        string             `json:"name" bson:"name"`
+	Code        string             `json:"code,omitempty" bson:"code,omitempty"`
+	Amount      money.Amount       `json:"amount" bson:"amount"`
+}
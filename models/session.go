@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Session is a login of a user on one device. It holds the current refresh
+// token, which is replaced on every refresh. Access tokens carry the session ID
+// and stop working once the session is revoked.
+type Session struct {
+	ID                       primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID                   primitive.ObjectID `json:"user_id" bson:"user_id"`
+	RefreshTokenHash         string             `json:"-" bson:"refresh_token_hash"`
+	PreviousRefreshTokenHash string             `json:"-" bson:"previous_refresh_token_hash,omitempty"` // to detect reuse of a rotated token
+	UserAgent                string             `json:"user_agent" bson:"user_agent"`
+	IPAddress                string             `json:"ip_address" bson:"ip_address"`
+	ExpiresAt                time.Time          `json:"expires_at" bson:"expires_at"`
+	RevokedAt                *time.Time         `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+	LastUsedAt               time.Time          `json:"last_used_at" bson:"last_used_at"`
+	CreatedAt                time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt                time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// TokenPair is an access token with the refresh token used to renew it
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"` // access token lifetime in seconds
+}
+
+// RefreshTokenRequest represents a refresh token being exchanged for new tokens
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// SessionResponse represents a session in the list of a user's active sessions
+type SessionResponse struct {
+	ID         primitive.ObjectID `json:"id"`
+	UserAgent  string             `json:"user_agent"`
+	IPAddress  string             `json:"ip_address"`
+	Current    bool               `json:"current"` // the session making the request
+	LastUsedAt time.Time          `json:"last_used_at"`
+	ExpiresAt  time.Time          `json:"expires_at"`
+	CreatedAt  time.Time          `json:"created_at"`
+}
+
+// ToSessionResponse converts Session to SessionResponse
+func (s *Session) ToSessionResponse(currentSessionID string) SessionResponse {
+	return SessionResponse{
+		ID:         s.ID,
+		UserAgent:  s.UserAgent,
+		IPAddress:  s.IPAddress,
+		Current:    s.ID.Hex() == currentSessionID,
+		LastUsedAt: s.LastUsedAt,
+		ExpiresAt:  s.ExpiresAt,
+		CreatedAt:  s.CreatedAt,
+	}
+}
@@ -8,21 +8,25 @@ import (
 
 // Store represents a restaurant/cafe in the system
 type Store struct {
-	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	Name        string             `json:"name" bson:"name" binding:"required"`
-	Description string             `json:"description" bson:"description"`
-	Address     Address            `json:"address" bson:"address"`
-	Phone       string             `json:"phone" bson:"phone" binding:"required"`
-	Email       string             `json:"email" bson:"email" binding:"omitempty,email"`
-	OwnerID     primitive.ObjectID `json:"owner_id" bson:"owner_id"`
-	Logo        string             `json:"logo" bson:"logo"`
-	IsOpen      bool               `json:"is_open" bson:"is_open"`
-	IsActive    bool               `json:"is_active" bson:"is_active"`
-	OpeningTime string             `json:"opening_time" bson:"opening_time"`
-	ClosingTime string             `json:"closing_time" bson:"closing_time"`
-	QRCodeData  string             `json:"qr_code_data" bson:"qr_code_data"`
-	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
-	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
+	ID            primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name          string             `json:"name" bson:"name" binding:"required"`
+	Description   string             `json:"description" bson:"description"`
+	Address       Address            `json:"address" bson:"address"`
+	Phone         string             `json:"phone" bson:"phone" binding:"required"`
+	Email         string             `json:"email" bson:"email" binding:"omitempty,email"`
+	OwnerID       primitive.ObjectID `json:"owner_id" bson:"owner_id"`
+	Logo          string             `json:"logo" bson:"logo"`
+	IsOpen        bool               `json:"is_open" bson:"is_open"`
+	IsActive      bool               `json:"is_active" bson:"is_active"`
+	Timezone      string             `json:"timezone" bson:"timezone"` // IANA name, e.g. "Europe/London"
+	Currency      string             `json:"currency" bson:"currency"` // ISO 4217 code; menu prices are in its minor unit
+	Locale        string             `json:"locale" bson:"locale"`     // BCP 47 tag used to format prices, e.g. "en-US"
+	OpeningHours  *OpeningHours      `json:"opening_hours" bson:"opening_hours,omitempty"`
+	Tax           *TaxSettings       `json:"tax" bson:"tax,omitempty"`          // nil = prices are not taxed
+	ScheduleState string             `json:"-" bson:"schedule_state,omitempty"` // last state applied by the scheduler
+	QRCodeData    string             `json:"qr_code_data" bson:"qr_code_data"`
+	CreatedAt     time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt     time.Time          `json:"updated_at" bson:"updated_at"`
 }
 
 // Address represents store address
@@ -36,65 +40,100 @@ type Address struct {
 
 // CreateStoreRequest represents data for creating a store
 type CreateStoreRequest struct {
-	Name        string  `json:"name" binding:"required"`
-	Description string  `json:"description"`
-	Address     Address `json:"address"`
-	Phone       string  `json:"phone" binding:"required"`
-	Email       string  `json:"email" binding:"omitempty,email"`
-	OpeningTime string  `json:"opening_time"`
-	ClosingTime string  `json:"closing_time"`
+	Name         string        `json:"name" binding:"required"`
+	Description  string        `json:"description"`
+	Address      Address       `json:"address"`
+	Phone        string        `json:"phone" binding:"required"`
+	Email        string        `json:"email" binding:"omitempty,email"`
+	Timezone     string        `json:"timezone"`
+	Currency     string        `json:"currency"`
+	Locale       string        `json:"locale"`
+	OpeningHours *OpeningHours `json:"opening_hours"`
+	Tax          *TaxSettings  `json:"tax"`
 }
 
-// UpdateStoreRequest represents data for updating a store
+// UpdateStoreRequest represents data for updating a store.
+// The currency is fixed at creation, since prices are stored in its minor unit.
 type UpdateStoreRequest struct {
-	Name        string   `json:"name"`
-	Description string   `json:"description"`
-	Address     *Address `json:"address"`
-	Phone       string   `json:"phone"`
-	Email       string   `json:"email" binding:"omitempty,email"`
-	Logo        string   `json:"logo"`
-	IsOpen      *bool    `json:"is_open"`
-	IsActive    *bool    `json:"is_active"`
-	OpeningTime string   `json:"opening_time"`
-	ClosingTime string   `json:"closing_time"`
+	Name              string        `json:"name"`
+	Description       string        `json:"description"`
+	Address           *Address      `json:"address"`
+	Phone             string        `json:"phone"`
+	Email             string        `json:"email" binding:"omitempty,email"`
+	Logo              string        `json:"logo"`
+	IsOpen            *bool         `json:"is_open"`
+	IsActive          *bool         `json:"is_active"`
+	Timezone          string        `json:"timezone"`
+	Locale            string        `json:"locale"`
+	OpeningHours      *OpeningHours `json:"opening_hours"`       // replaces the whole schedule
+	ClearOpeningHours bool          `json:"clear_opening_hours"` // removes the schedule; the store is then opened and closed by hand
+	Tax               *TaxSettings  `json:"tax"`                 // replaces the whole tax configuration
+	ClearTax          bool          `json:"clear_tax"`           // removes the tax configuration; prices are then not taxed
 }
 
 // StoreResponse represents the store data sent in responses
 type StoreResponse struct {
-	ID          primitive.ObjectID `json:"id"`
-	Name        string             `json:"name"`
-	Description string             `json:"description"`
-	Address     Address            `json:"address"`
-	Phone       string             `json:"phone"`
-	Email       string             `json:"email"`
-	OwnerID     primitive.ObjectID `json:"owner_id"`
-	Logo        string             `json:"logo"`
-	IsOpen      bool               `json:"is_open"`
-	IsActive    bool               `json:"is_active"`
-	OpeningTime string             `json:"opening_time"`
-	ClosingTime string             `json:"closing_time"`
-	QRCodeData  string             `json:"qr_code_data"`
-	CreatedAt   time.Time          `json:"created_at"`
-	UpdatedAt   time.Time          `json:"updated_at"`
+	ID           primitive.ObjectID `json:"id"`
+	Name         string             `json:"name"`
+	Description  string             `json:"description"`
+	Address      Address            `json:"address"`
+	Phone        string             `json:"phone"`
+	Email        string             `json:"email"`
+	OwnerID      primitive.ObjectID `json:"owner_id"`
+	Logo         string             `json:"logo"`
+	IsOpen       bool               `json:"is_open"`
+	IsActive     bool               `json:"is_active"`
+	Timezone     string             `json:"timezone"`
+	Currency     string             `json:"currency"`
+	Locale       string             `json:"locale"`
+	OpeningHours *OpeningHours      `json:"opening_hours"`
+	Tax          *TaxSettings       `json:"tax"`
+	QRCodeData   string             `json:"qr_code_data"`
+	CreatedAt    time.Time          `json:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at"`
 }
 
 // ToStoreResponse converts Store to StoreResponse
 func (s *Store) ToStoreResponse() StoreResponse {
 	return StoreResponse{
-		ID:          s.ID,
-		Name:        s.Name,
-		Description: s.Description,
-		Address:     s.Address,
-		Phone:       s.Phone,
-		Email:       s.Email,
-		OwnerID:     s.OwnerID,
-		Logo:        s.Logo,
-		IsOpen:      s.IsOpen,
-		IsActive:    s.IsActive,
-		OpeningTime: s.OpeningTime,
-		ClosingTime: s.ClosingTime,
-		QRCodeData:  s.QRCodeData,
-		CreatedAt:   s.CreatedAt,
-		UpdatedAt:   s.UpdatedAt,
+		ID:           s.ID,
+		Name:         s.Name,
+		Description:  s.Description,
+		Address:      s.Address,
+		Phone:        s.Phone,
+		Email:        s.Email,
+		OwnerID:      s.OwnerID,
+		Logo:         s.Logo,
+		IsOpen:       s.IsOpen,
+		IsActive:     s.IsActive,
+		Timezone:     s.Timezone,
+		Currency:     s.Currency,
+		Locale:       s.Locale,
+		OpeningHours: s.OpeningHours,
+		Tax:          s.Tax,
+		QRCodeData:   s.QRCodeData,
+		CreatedAt:    s.CreatedAt,
+		UpdatedAt:    s.UpdatedAt,
 	}
 }
+
+// Location returns the store's time zone, falling back to UTC when it is unset or unknown
+func (s *Store) Location() *time.Location {
+	if s.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// IsWithinOpeningHours reports whether now is inside the store's opening hours.
+// Stores without a schedule are always within hours.
+func (s *Store) IsWithinOpeningHours(now time.Time) bool {
+	if s.OpeningHours == nil {
+		return true
+	}
+	return s.OpeningHours.IsOpenAt(now.In(s.Location()))
+}
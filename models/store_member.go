@@ -0,0 +1,97 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Store roles. The owner is the store's OwnerID; the other roles are granted
+// through store memberships.
+const (
+	StoreRoleOwner   = "owner"
+	StoreRoleManager = "manager"
+	StoreRoleCashier = "cashier"
+	StoreRoleKitchen = "kitchen"
+	StoreRoleWaiter  = "waiter"
+)
+
+// StaffRoles are the roles a store member can be invited with
+var StaffRoles = []string{StoreRoleManager, StoreRoleCashier, StoreRoleKitchen, StoreRoleWaiter}
+
+// Store membership statuses
+const (
+	StoreMemberInvited = "invited"
+	StoreMemberActive  = "active"
+)
+
+// StoreMember gives a user a role in a store. Members are invited by email and
+// become active once the invited user accepts with the invite token.
+type StoreMember struct {
+	ID              primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	StoreID         primitive.ObjectID `json:"store_id" bson:"store_id"`
+	UserID          primitive.ObjectID `json:"user_id,omitzero" bson:"user_id,omitempty"` // set on acceptance
+	Email           string             `json:"email" bson:"email"`
+	Role            string             `json:"role" bson:"role"`
+	Status          string             `json:"status" bson:"status"`
+	InviteTokenHash string             `json:"-" bson:"invite_token_hash,omitempty"`
+	InviteExpiresAt *time.Time         `json:"invite_expires_at,omitempty" bson:"invite_expires_at,omitempty"`
+	InvitedBy       primitive.ObjectID `json:"invited_by" bson:"invited_by"`
+	AcceptedAt      *time.Time         `json:"accepted_at,omitempty" bson:"accepted_at,omitempty"`
+	CreatedAt       time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt       time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// InviteStoreMemberRequest represents data for inviting a user to a store
+type InviteStoreMemberRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required,oneof=manager cashier kitchen waiter"`
+}
+
+// UpdateStoreMemberRequest represents data for changing a member's role
+type UpdateStoreMemberRequest struct {
+	Role string `json:"role" binding:"required,oneof=manager cashier kitchen waiter"`
+}
+
+// AcceptStoreInviteRequest represents an invite token being redeemed
+type AcceptStoreInviteRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// StoreMemberResponse represents the store member data sent in responses
+type StoreMemberResponse struct {
+	ID              primitive.ObjectID `json:"id"`
+	StoreID         primitive.ObjectID `json:"store_id"`
+	UserID          primitive.ObjectID `json:"user_id,omitzero"`
+	Email           string             `json:"email"`
+	Role            string             `json:"role"`
+	Status          string             `json:"status"`
+	InviteExpiresAt *time.Time         `json:"invite_expires_at,omitempty"`
+	InvitedBy       primitive.ObjectID `json:"invited_by"`
+	AcceptedAt      *time.Time         `json:"accepted_at,omitempty"`
+	CreatedAt       time.Time          `json:"created_at"`
+	UpdatedAt       time.Time          `json:"updated_at"`
+}
+
+// MyStoreResponse is a store the caller owns or works at, with the caller's role in it
+type MyStoreResponse struct {
+	StoreResponse
+	Role string `json:"role"`
+}
+
+// ToStoreMemberResponse converts StoreMember to StoreMemberResponse
+func (m *StoreMember) ToStoreMemberResponse() StoreMemberResponse {
+	return StoreMemberResponse{
+		ID:              m.ID,
+		StoreID:         m.StoreID,
+		UserID:          m.UserID,
+		Email:           m.Email,
+		Role:            m.Role,
+		Status:          m.Status,
+		InviteExpiresAt: m.InviteExpiresAt,
+		InvitedBy:       m.InvitedBy,
+		AcceptedAt:      m.AcceptedAt,
+		CreatedAt:       m.CreatedAt,
+		UpdatedAt:       m.UpdatedAt,
+	}
+}
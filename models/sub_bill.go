@@ -0,0 +1,102 @@
+package models
+
+import (
+	"time"
+
+	"ordernew/money"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Bill split modes
+const (
+	SplitByItem = "by_item" // each sub-bill pays the lines given to it; shared lines are divided evenly
+	SplitEvenly = "even"    // every sub-bill pays the same share of everything
+	SplitCustom = "custom"  // each sub-bill pays a set amount
+)
+
+// Sub-bill statuses
+const (
+	SubBillOpen = "open"
+	SubBillPaid = "paid"
+)
+
+// SubBill is the share of a dining session's bill that one guest or group pays.
+// Discounts, the service charge and taxes are allocated to it with its items,
+// so the sub-bills of a split add up exactly to the orders they cover.
+type SubBill struct {
+	ID            primitive.ObjectID   `json:"id" bson:"_id,omitempty"`
+	SessionID     primitive.ObjectID   `json:"session_id" bson:"session_id"`
+	StoreID       primitive.ObjectID   `json:"store_id" bson:"store_id"`
+	Label         string               `json:"label" bson:"label"`
+	Mode          string               `json:"mode" bson:"mode"`
+	OrderIDs      []primitive.ObjectID `json:"order_ids" bson:"order_ids"` // orders the split covers
+	Items         []SubBillItem        `json:"items" bson:"items"`         // by-item splits only
+	Currency      string               `json:"currency" bson:"currency"`
+	Subtotal      money.Amount         `json:"subtotal" bson:"subtotal"` // items after discounts
+	ServiceCharge money.Amount         `json:"service_charge" bson:"service_charge"`
+	TaxTotal      money.Amount         `json:"tax_total" bson:"tax_total"`
+	Tip           money.Amount         `json:"tip" bson:"tip"`
+	Total         money.Amount         `json:"total" bson:"total"`             // tip included
+	AmountPaid    money.Amount         `json:"amount_paid" bson:"amount_paid"` // captured less refunded
+	Status        string               `json:"status" bson:"status"`
+	CreatedAt     time.Time            `json:"created_at" bson:"created_at"`
+	UpdatedAt     time.Time            `json:"updated_at" bson:"updated_at"`
+}
+
+// SubBillItem is the part of an order line a sub-bill pays for
+type SubBillItem struct {
+	OrderID   primitive.ObjectID `json:"order_id" bson:"order_id"`
+	ItemIndex int                `json:"item_index" bson:"item_index"` // position of the line in the order's items
+	Name      string             `json:"name" bson:"name"`
+	Quantity  int                `json:"quantity" bson:"quantity"`
+	SharedBy  int                `json:"shared_by" bson:"shared_by"` // number of sub-bills dividing the line
+	Amount    money.Amount       `json:"amount" bson:"amount"`       // this sub-bill's part, after discounts
+}
+
+// SplitBillRequest represents data for splitting the bill of a dining session
+type SplitBillRequest struct {
+	Mode  string                  `json:"mode" binding:"required,oneof=by_item even custom"`
+	Count int                     `json:"count" binding:"omitempty,gte=2,lte=50"` // even: number of sub-bills
+	Bills []SplitBillShareRequest `json:"bills" binding:"omitempty,max=50,dive"`  // by_item and custom: one per sub-bill
+	Tip   money.Amount            `json:"tip" binding:"gte=0"`                    // spread over the sub-bills in proportion to their totals
+}
+
+// SplitBillShareRequest describes one sub-bill of a by-item or custom split
+type SplitBillShareRequest struct {
+	Label  string                 `json:"label" binding:"max=50"`
+	Items  []SplitBillItemRequest `json:"items" binding:"dive"`   // by_item
+	Amount money.Amount           `json:"amount" binding:"gte=0"` // custom
+}
+
+// SplitBillItemRequest names an order line given to a sub-bill
+type SplitBillItemRequest struct {
+	OrderID   string `json:"order_id" binding:"required"`
+	ItemIndex int    `json:"item_index" binding:"gte=0"`
+}
+
+// SubBillResponse represents the sub-bill data sent in responses
+type SubBillResponse struct {
+	SubBill
+	TotalFormatted     string       `json:"total_formatted,omitempty"`
+	AmountDue          money.Amount `json:"amount_due"`
+	AmountDueFormatted string       `json:"amount_due_formatted,omitempty"`
+}
+
+// ToSubBillResponse converts SubBill to SubBillResponse
+func (b *SubBill) ToSubBillResponse() SubBillResponse {
+	return SubBillResponse{
+		SubBill:   *b,
+		AmountDue: max(b.Total-b.AmountPaid, 0),
+	}
+}
+
+// CoversOrder reports whether the split the sub-bill belongs to covers an order
+func (b *SubBill) CoversOrder(orderID primitive.ObjectID) bool {
+	for _, id := range b.OrderIDs {
+		if id == orderID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"time"
+
+	"ordernew/money"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Dining session statuses
+const (
+	DiningSessionOpen   = "open"
+	DiningSessionClosed = "closed"
+)
+
+// Table represents a table inside a store
+type Table struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	StoreID    primitive.ObjectID `json:"store_id" bson:"store_id"`
+	Name       string             `json:"name" bson:"name"`
+	Capacity   int                `json:"capacity" bson:"capacity"`
+	IsActive   bool               `json:"is_active" bson:"is_active"`
+	TokenNonce string             `json:"-" bson:"token_nonce"`
+	QRCodeData string             `json:"qr_code_data" bson:"qr_code_data"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// DiningSession groups every order placed from a table until the bill is closed
+type DiningSession struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	StoreID   primitive.ObjectID `json:"store_id" bson:"store_id"`
+	TableID   primitive.ObjectID `json:"table_id" bson:"table_id"`
+	TableName string             `json:"table_name" bson:"table_name"`
+	Status    string             `json:"status" bson:"status"`
+	OpenedAt  time.Time          `json:"opened_at" bson:"opened_at"`
+	ClosedAt  *time.Time         `json:"closed_at,omitempty" bson:"closed_at,omitempty"`
+	ClosedBy  primitive.ObjectID `json:"closed_by,omitzero" bson:"closed_by,omitempty"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// CreateTableRequest represents data for creating a table
+type CreateTableRequest struct {
+	StoreID  string `json:"store_id" binding:"required"`
+	Name     string `json:"name" binding:"required"`
+	Capacity int    `json:"capacity" binding:"omitempty,gte=0"`
+}
+
+// UpdateTableRequest represents data for updating a table
+type UpdateTableRequest struct {
+	Name     string `json:"name"`
+	Capacity *int   `json:"capacity" binding:"omitempty,gte=0"`
+	IsActive *bool  `json:"is_active"`
+}
+
+// ScanTableRequest represents a scanned table QR code
+type ScanTableRequest struct {
+	TableToken string `json:"table_token" binding:"required"`
+}
+
+// TableResponse represents the table data sent in responses
+type TableResponse struct {
+	ID         primitive.ObjectID `json:"id"`
+	StoreID    primitive.ObjectID `json:"store_id"`
+	Name       string             `json:"name"`
+	Capacity   int                `json:"capacity"`
+	IsActive   bool               `json:"is_active"`
+	QRCodeData string             `json:"qr_code_data"`
+	CreatedAt  time.Time          `json:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at"`
+}
+
+// DiningSessionResponse represents a dining session with its orders, and its sub-bills when the bill is split
+type DiningSessionResponse struct {
+	DiningSession
+	Orders              []OrderResponse   `json:"orders"`
+	Bills               []SubBillResponse `json:"bills"`
+	Currency            string            `json:"currency"`
+	Total               money.Amount      `json:"total"`
+	TotalFormatted      string            `json:"total_formatted,omitempty"`
+	TipTotal            money.Amount      `json:"tip_total"`
+	AmountPaid          money.Amount      `json:"amount_paid"`
+	AmountPaidFormatted string            `json:"amount_paid_formatted,omitempty"`
+	BalanceDue          money.Amount      `json:"balance_due"` // the session closes only at zero
+	BalanceDueFormatted string            `json:"balance_due_formatted,omitempty"`
+}
+
+// ToTableResponse converts Table to TableResponse
+func (t *Table) ToTableResponse() TableResponse {
+	return TableResponse{
+		ID:         t.ID,
+		StoreID:    t.StoreID,
+		Name:       t.Name,
+		Capacity:   t.Capacity,
+		IsActive:   t.IsActive,
+		QRCodeData: t.QRCodeData,
+		CreatedAt:  t.CreatedAt,
+		UpdatedAt:  t.UpdatedAt,
+	}
+}
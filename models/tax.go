@@ -0,0 +1,92 @@
+package models
+
+import (
+	"ordernew/money"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TaxSettings describes how a store taxes its sales.
+//
+// Each food item and combo has a tax category, e.g. "food" or "alcohol".
+// A line is taxed by every rate that lists its category, so a category
+// can carry several taxes (GST + excise) and a category no rate lists is
+// not taxed at all.
+type TaxSettings struct {
+	// PricesIncludeTax is true when menu prices already contain tax (VAT
+	// style); otherwise tax is added on top of them (US sales tax style)
+	PricesIncludeTax bool           `json:"prices_include_tax" bson:"prices_include_tax"`
+	DefaultCategory  string         `json:"default_category" bson:"default_category"` // category of items without one
+	Rates            []TaxRate      `json:"rates" bson:"rates" binding:"dive"`
+	ServiceCharge    *ServiceCharge `json:"service_charge,omitempty" bson:"service_charge,omitempty"`
+}
+
+// TaxRate is one tax a store charges, e.g. GST at 5%
+type TaxRate struct {
+	Code       string   `json:"code" bson:"code" binding:"required,max=20"` // short unique code, e.g. "GST"
+	Name       string   `json:"name" bson:"name" binding:"required"`
+	Rate       float64  `json:"rate" bson:"rate" binding:"gt=0,lte=100"` // percent
+	Categories []string `json:"categories" bson:"categories" binding:"required,min=1"`
+}
+
+// ServiceCharge is a percentage added to the subtotal of an order, e.g. 10% on dine-in orders
+type ServiceCharge struct {
+	Name        string   `json:"name" bson:"name" binding:"required"`
+	Rate        float64  `json:"rate" bson:"rate" binding:"gt=0,lte=100"` // percent of the subtotal
+	OrderTypes  []string `json:"order_types" bson:"order_types"`          // order types it applies to; empty = all
+	TaxCategory string   `json:"tax_category" bson:"tax_category"`        // category the charge is taxed as; empty = not taxed
+}
+
+// AppliesTo reports whether the service charge is added to orders of the given type
+func (s *ServiceCharge) AppliesTo(orderType string) bool {
+	if len(s.OrderTypes) == 0 {
+		return true
+	}
+	for _, t := range s.OrderTypes {
+		if t == orderType {
+			return true
+		}
+	}
+	return false
+}
+
+// LineTax is the amount of one tax contained in, or added to, a line
+type LineTax struct {
+	Code   string       `json:"code" bson:"code"`
+	Rate   float64      `json:"rate" bson:"rate"`
+	Amount money.Amount `json:"amount" bson:"amount"`
+}
+
+// TaxBreakdown totals one tax rate over a cart or order. Amount is the sum of
+// the rounded line taxes, so it always matches the lines.
+type TaxBreakdown struct {
+	Code          string       `json:"code" bson:"code"`
+	Name          string       `json:"name" bson:"name"`
+	Rate          float64      `json:"rate" bson:"rate"`
+	TaxableAmount money.Amount `json:"taxable_amount" bson:"taxable_amount"` // net of tax
+	Amount        money.Amount `json:"amount" bson:"amount"`
+}
+
+// AppliedServiceCharge is the service charge added to a cart or order
+type AppliedServiceCharge struct {
+	Name        string       `json:"name" bson:"name"`
+	Rate        float64      `json:"rate" bson:"rate"`
+	Amount      money.Amount `json:"amount" bson:"amount"`
+	TaxCategory string       `json:"tax_category,omitempty" bson:"tax_category,omitempty"`
+	Taxes       []LineTax    `json:"taxes,omitempty" bson:"taxes,omitempty"`
+}
+
+// TaxReport totals the sales and taxes of a store over a date range, for accounting
+type TaxReport struct {
+	StoreID        primitive.ObjectID `json:"store_id"`
+	Currency       string             `json:"currency"`
+	From           string             `json:"from"` // first day, YYYY-MM-DD in the store time zone
+	To             string             `json:"to"`   // last day, included
+	OrderCount     int                `json:"order_count"`
+	Subtotal       money.Amount       `json:"subtotal"`
+	Discounts      money.Amount       `json:"discounts"`
+	ServiceCharges money.Amount       `json:"service_charges"`
+	TaxTotal       money.Amount       `json:"tax_total"`
+	Total          money.Amount       `json:"total"`
+	Taxes          []TaxBreakdown     `json:"taxes"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TwoFactorSetupResponse holds a new TOTP secret for the user to add to their authenticator app
+type TwoFactorSetupResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+	QRCode          string `json:"qr_code"` // PNG data URL of the provisioning URI
+}
+
+// TwoFactorCodeRequest represents a TOTP code, or a recovery code where allowed
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// TwoFactorLoginRequest represents the second step of a login with two-factor authentication
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+// TwoFactorChallenge is returned by login instead of tokens when the user has
+// two-factor authentication enabled
+type TwoFactorChallenge struct {
+	ChallengeToken string `json:"challenge_token"`
+	ExpiresIn      int64  `json:"expires_in"` // seconds
+}
+
+// LoginResult is the outcome of a first login step: either tokens, or a
+// challenge to complete with a two-factor code
+type LoginResult struct {
+	Tokens    *TokenPair
+	User      *UserResponse
+	Challenge *TwoFactorChallenge
+}
+
+// SecuritySettings are account security policies set by admins
+type SecuritySettings struct {
+	ID                    string             `json:"-" bson:"_id"`
+	RequireOwnerTwoFactor bool               `json:"require_owner_two_factor" bson:"require_owner_two_factor"`
+	UpdatedBy             primitive.ObjectID `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+	UpdatedAt             time.Time          `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+}
+
+// UpdateSecuritySettingsRequest represents a change to the security settings
+type UpdateSecuritySettingsRequest struct {
+	RequireOwnerTwoFactor *bool `json:"require_owner_two_factor" binding:"required"`
+}
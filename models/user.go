@@ -8,27 +8,40 @@ import (
 
 // User represents a user in the system
 type User struct {
-	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	Name      string             `json:"name" bson:"name"`
-	Email     string             `json:"email" bson:"email" binding:"omitempty,email"`
-	Phone     string             `json:"phone" bson:"phone"`
-	Password  string             `json:"password,omitempty" bson:"password"`
-	Role      string             `json:"role" bson:"role"`
-	IsActive  bool               `json:"is_active" bson:"is_active"`
-	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
-	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+	ID                     primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name                   string             `json:"name" bson:"name"`
+	Email                  string             `json:"email" bson:"email" binding:"omitempty,email"`
+	Phone                  string             `json:"phone" bson:"phone"`
+	Password               string             `json:"password,omitempty" bson:"password"`
+	Role                   string             `json:"role" bson:"role"`
+	IsActive               bool               `json:"is_active" bson:"is_active"`
+	EmailVerified          bool               `json:"email_verified" bson:"email_verified"`
+	EmailVerifiedAt        *time.Time         `json:"email_verified_at,omitempty" bson:"email_verified_at,omitempty"`
+	TwoFactorEnabled       bool               `json:"two_factor_enabled" bson:"two_factor_enabled"`
+	TwoFactorSecret        string             `json:"-" bson:"two_factor_secret,omitempty"`
+	TwoFactorPendingSecret string             `json:"-" bson:"two_factor_pending_secret,omitempty"` // set during enrolment until the first code is verified
+	TwoFactorLastStep      int64              `json:"-" bson:"two_factor_last_step,omitempty"`      // last TOTP time step used, so codes cannot be replayed
+	RecoveryCodeHashes     []string           `json:"-" bson:"recovery_code_hashes,omitempty"`
+	FailedLoginAttempts    int                `json:"-" bson:"failed_login_attempts,omitempty"` // consecutive failures since the last successful login
+	LockedUntil            *time.Time         `json:"locked_until,omitempty" bson:"locked_until,omitempty"`
+	Addresses              []SavedAddress     `json:"-" bson:"addresses,omitempty"`
+	CreatedAt              time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt              time.Time          `json:"updated_at" bson:"updated_at"`
 }
 
 // UserResponse represents the user data sent in responses (without password)
 type UserResponse struct {
-	ID        primitive.ObjectID `json:"id"`
-	Name      string             `json:"name"`
-	Email     string             `json:"email"`
-	Phone     string             `json:"phone"`
-	Role      string             `json:"role"`
-	IsActive  bool               `json:"is_active"`
-	CreatedAt time.Time          `json:"created_at"`
-	UpdatedAt time.Time          `json:"updated_at"`
+	ID               primitive.ObjectID `json:"id"`
+	Name             string             `json:"name"`
+	Email            string             `json:"email"`
+	Phone            string             `json:"phone"`
+	Role             string             `json:"role"`
+	IsActive         bool               `json:"is_active"`
+	EmailVerified    bool               `json:"email_verified"`
+	TwoFactorEnabled bool               `json:"two_factor_enabled"`
+	LockedUntil      *time.Time         `json:"locked_until,omitempty"`
+	CreatedAt        time.Time          `json:"created_at"`
+	UpdatedAt        time.Time          `json:"updated_at"`
 }
 
 // LoginRequest represents login credentials
@@ -44,6 +57,22 @@ type RegisterRequest struct {
 	Password string `json:"password" binding:"required,min=6"`
 }
 
+// ForgotPasswordRequest represents a request for a password reset email
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest represents a new password set with a reset token
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+// VerifyEmailRequest represents an email verification token being redeemed
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
 // UpdateUserRequest represents data for updating a user
 type UpdateUserRequest struct {
 	Name     string `json:"name"`
@@ -52,16 +81,41 @@ type UpdateUserRequest struct {
 	IsActive *bool  `json:"is_active"`
 }
 
+// UpdateProfileRequest represents the fields users can change on their own profile
+type UpdateProfileRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// ChangePasswordRequest represents a password change by the user themselves
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+// ChangeEmailRequest represents an email change by the user themselves
+type ChangeEmailRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password"`
+}
+
+// DeleteAccountRequest confirms that users want to delete their own account
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
 // ToUserResponse converts User to UserResponse (removes password)
 func (u *User) ToUserResponse() UserResponse {
 	return UserResponse{
-		ID:        u.ID,
-		Name:      u.Name,
-		Email:     u.Email,
-		Phone:     u.Phone,
-		Role:      u.Role,
-		IsActive:  u.IsActive,
-		CreatedAt: u.CreatedAt,
-		UpdatedAt: u.UpdatedAt,
+		ID:               u.ID,
+		Name:             u.Name,
+		Email:            u.Email,
+		Phone:            u.Phone,
+		Role:             u.Role,
+		IsActive:         u.IsActive,
+		EmailVerified:    u.EmailVerified,
+		TwoFactorEnabled: u.TwoFactorEnabled,
+		LockedUntil:      u.LockedUntil,
+		CreatedAt:        u.CreatedAt,
+		UpdatedAt:        u.UpdatedAt,
 	}
 }
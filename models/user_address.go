@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxSavedAddresses is how many delivery addresses a user can save
+const MaxSavedAddresses = 10
+
+// SavedAddress is a delivery address saved on a user's account
+type SavedAddress struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id"`
+	Label        string             `json:"label" bson:"label"` // e.g. "Home" or "Work"
+	Address      Address            `json:"address" bson:"address"`
+	Instructions string             `json:"instructions,omitempty" bson:"instructions,omitempty"` // for the courier, e.g. "Ring twice"
+	IsDefault    bool               `json:"is_default" bson:"is_default"`
+	CreatedAt    time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// SavedAddressRequest represents data for saving or updating a delivery address
+type SavedAddressRequest struct {
+	Label        string  `json:"label" binding:"required,max=50"`
+	Address      Address `json:"address"`
+	Instructions string  `json:"instructions" binding:"max=500"`
+	IsDefault    bool    `json:"is_default"`
+}
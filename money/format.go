@@ -0,0 +1,52 @@
+package money
+
+import (
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// symbolAfter lists the languages that write the currency symbol after the
+// amount, e.g. "12,99 €" in German
+var symbolAfter = map[string]bool{
+	"cs": true, "da": true, "de": true, "es": true, "fi": true, "fr": true,
+	"hu": true, "it": true, "nb": true, "no": true, "pl": true, "ro": true,
+	"ru": true, "sk": true, "sv": true, "uk": true,
+}
+
+// ValidLocale reports whether locale is a well-formed BCP 47 tag such as "en-US"
+func ValidLocale(locale string) bool {
+	_, err := language.Parse(locale)
+	return err == nil
+}
+
+// Format renders an amount for display in a locale, e.g. "$1,234.50" for
+// en-US or "1.234,50 €" for de-DE. Unknown locales fall back to English.
+func Format(a Amount, code, locale string) string {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		tag = language.English
+	}
+	unit, err := currency.ParseISO(code)
+	if err != nil {
+		unit = currency.XXX
+	}
+
+	printer := message.NewPrinter(tag)
+	scale, _ := currency.Standard.Rounding(unit)
+	value := a
+	sign := ""
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+	amount := printer.Sprint(number.Decimal(value.Major(unit.String()), number.Scale(scale)))
+	symbol := printer.Sprint(currency.Symbol(unit))
+
+	base, _ := tag.Base()
+	if symbolAfter[base.String()] {
+		return sign + amount + " " + symbol
+	}
+	return sign + symbol + amount
+}
@@ -0,0 +1,30 @@
+package money
+
+import "testing"
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount Amount
+		code   string
+		locale string
+		want   string
+	}{
+		{"US dollars", 123450, "USD", "en-US", "$1,234.50"},
+		{"negative amount", -1299, "USD", "en-US", "-$12.99"},
+		{"yen have no decimals", 1500, "JPY", "ja-JP", "￥1,500"},
+		{"yen in English", 1500, "JPY", "en-US", "¥1,500"},
+		{"German puts the symbol after", 123450, "EUR", "de-DE", "1.234,50\u00a0€"},
+		{"German with dollars", 1299, "USD", "de-DE", "12,99\u00a0$"},
+		{"French", 123450, "EUR", "fr-FR", "1\u00a0234,50\u00a0€"},
+		{"unknown locale falls back to English", 999, "USD", "not a locale", "$9.99"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Format(tt.amount, tt.code, tt.locale); got != tt.want {
+				t.Errorf("Format(%d, %q, %q) = %q, want %q", tt.amount, tt.code, tt.locale, got, tt.want)
+			}
+		})
+	}
+}
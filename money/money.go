@@ -0,0 +1,49 @@
+package money
+
+import (
+	"fmt"
+	"math"
+
+	"golang.org/x/text/currency"
+)
+
+// Amount is an amount of money in the minor unit of its currency, e.g. cents
+// for USD or yen for JPY. Integer amounts add up exactly, unlike floats.
+// The currency is kept next to the amount, usually on the store.
+type Amount int64
+
+// Times returns the amount multiplied by a quantity
+func (a Amount) Times(quantity int) Amount {
+	return a * Amount(quantity)
+}
+
+// NormalizeCurrency validates an ISO 4217 currency code and returns it in upper case
+func NormalizeCurrency(code string) (string, error) {
+	unit, err := currency.ParseISO(code)
+	if err != nil {
+		return "", fmt.Errorf("unknown currency %q", code)
+	}
+	return unit.String(), nil
+}
+
+// Exponent returns the number of decimal places of a currency's minor unit,
+// e.g. 2 for USD and 0 for JPY. Unknown currencies are given 2.
+func Exponent(code string) int {
+	unit, err := currency.ParseISO(code)
+	if err != nil {
+		return 2
+	}
+	scale, _ := currency.Standard.Rounding(unit)
+	return scale
+}
+
+// FromMajor converts an amount in major units, e.g. 12.99 dollars, to minor
+// units, rounding half away from zero. It is meant for legacy float prices.
+func FromMajor(value float64, code string) Amount {
+	return Amount(math.Round(value * math.Pow10(Exponent(code))))
+}
+
+// Major returns the amount in major units, for display only
+func (a Amount) Major(code string) float64 {
+	return float64(a) / math.Pow10(Exponent(code))
+}
@@ -0,0 +1,47 @@
+package money
+
+import "testing"
+
+func TestFromMajor(t *testing.T) {
+	tests := []struct {
+		name  string
+		value float64
+		code  string
+		want  Amount
+	}{
+		{"dollars to cents", 12.99, "USD", 1299},
+		{"float error is rounded away", 0.29, "USD", 29},
+		{"half a cent rounds away from zero", 0.125, "USD", 13},
+		{"negative rounds away from zero", -2.5, "JPY", -3},
+		{"yen have no minor unit", 1500, "JPY", 1500},
+		{"yen fractions are rounded", 1499.5, "JPY", 1500},
+		{"three decimal currency", 1.234, "BHD", 1234},
+		{"unknown currency uses two decimals", 3.5, "???", 350},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FromMajor(tt.value, tt.code); got != tt.want {
+				t.Errorf("FromMajor(%v, %q) = %d, want %d", tt.value, tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMajor(t *testing.T) {
+	tests := []struct {
+		amount Amount
+		code   string
+		want   float64
+	}{
+		{1299, "USD", 12.99},
+		{1500, "JPY", 1500},
+		{-50, "EUR", -0.5},
+	}
+
+	for _, tt := range tests {
+		if got := tt.amount.Major(tt.code); got != tt.want {
+			t.Errorf("Amount(%d).Major(%q) = %v, want %v", tt.amount, tt.code, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package payments
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"ordernew/money"
+	"ordernew/utils"
+)
+
+const mockIntentPrefix = "pi_mock_"
+
+// MockProvider is a sandbox gateway for local development. It accepts every
+// call without moving money; payments are confirmed with Confirm, which
+// produces the signed webhook a real gateway would send.
+type MockProvider struct {
+	WebhookSecret string
+}
+
+// mockEvent is the webhook payload of the mock gateway
+type mockEvent struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Data struct {
+		IntentID      string       `json:"intent_id"`
+		Reference     string       `json:"reference"`
+		Amount        money.Amount `json:"amount"`
+		FailureReason string       `json:"failure_reason,omitempty"`
+	} `json:"data"`
+}
+
+// Name returns the provider name stored on payments
+func (p *MockProvider) Name() string {
+	return "mock"
+}
+
+// CreateIntent starts a sandbox payment
+func (p *MockProvider) CreateIntent(req IntentRequest) (*Intent, error) {
+	id, err := utils.GenerateRandomToken(12)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	return &Intent{ID: mockIntentPrefix + id, ClientSecret: mockIntentPrefix + id + "_secret_" + secret}, nil
+}
+
+// Capture takes an authorized sandbox payment
+func (p *MockProvider) Capture(intentID string, amount money.Amount) error {
+	return checkMockIntent(intentID)
+}
+
+// Void releases an authorized sandbox payment
+func (p *MockProvider) Void(intentID string) error {
+	return checkMockIntent(intentID)
+}
+
+// Refund returns part or all of a captured sandbox payment
+func (p *MockProvider) Refund(intentID string, amount money.Amount) (string, error) {
+	if err := checkMockIntent(intentID); err != nil {
+		return "", err
+	}
+	id, err := utils.GenerateRandomToken(12)
+	if err != nil {
+		return "", err
+	}
+	return "re_mock_" + id, nil
+}
+
+// Confirm simulates the customer confirming a sandbox payment. It returns the
+// webhook payload and signature the gateway sends: payment.authorized, or
+// payment.failed when succeed is false.
+func (p *MockProvider) Confirm(intentID, reference string, amount money.Amount, succeed bool) ([]byte, string, error) {
+	if err := checkMockIntent(intentID); err != nil {
+		return nil, "", err
+	}
+	id, err := utils.GenerateRandomToken(12)
+	if err != nil {
+		return nil, "", err
+	}
+
+	event := mockEvent{ID: "evt_mock_" + id, Type: EventAuthorized}
+	event.Data.IntentID = intentID
+	event.Data.Reference = reference
+	event.Data.Amount = amount
+	if !succeed {
+		event.Type = EventFailed
+		event.Data.FailureReason = "card_declined"
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, "", err
+	}
+	return payload, p.Sign(payload), nil
+}
+
+// Sign returns the signature of a webhook payload: its hex HMAC-SHA256 with the webhook secret
+func (p *MockProvider) Sign(payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(p.WebhookSecret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ParseWebhook verifies the signature of a webhook and decodes its event
+func (p *MockProvider) ParseWebhook(payload []byte, signature string) (*Event, error) {
+	if !hmac.Equal([]byte(p.Sign(payload)), []byte(strings.ToLower(signature))) {
+		return nil, ErrInvalidSignature
+	}
+
+	var event mockEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("invalid webhook payload: %w", err)
+	}
+	if event.ID == "" || event.Type == "" || event.Data.IntentID == "" {
+		return nil, fmt.Errorf("invalid webhook payload: missing id, type or intent_id")
+	}
+
+	return &Event{
+		ID:            event.ID,
+		Type:          event.Type,
+		IntentID:      event.Data.IntentID,
+		Reference:     event.Data.Reference,
+		Amount:        event.Data.Amount,
+		FailureReason: event.Data.FailureReason,
+	}, nil
+}
+
+// checkMockIntent rejects intent IDs the mock gateway did not issue
+func checkMockIntent(intentID string) error {
+	if !strings.HasPrefix(intentID, mockIntentPrefix) {
+		return fmt.Errorf("unknown payment intent %q", intentID)
+	}
+	return nil
+}
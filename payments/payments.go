@@ -0,0 +1,68 @@
+package payments
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"ordernew/config"
+	"ordernew/money"
+)
+
+// Webhook event types a provider reports
+const (
+	EventAuthorized = "payment.authorized" // the customer confirmed; funds are held until captured
+	EventCaptured   = "payment.captured"   // the funds were taken
+	EventFailed     = "payment.failed"     // the customer's confirmation was declined
+)
+
+// ErrInvalidSignature is returned for a webhook whose signature does not match its payload
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// IntentRequest describes a payment to start with a provider
+type IntentRequest struct {
+	Amount      money.Amount
+	Currency    string
+	Reference   string // our payment ID, echoed back in webhooks
+	Description string
+}
+
+// Intent is a payment started with a provider. The customer confirms it on
+// the frontend with the client secret, and the provider reports the outcome
+// through a webhook.
+type Intent struct {
+	ID           string
+	ClientSecret string
+}
+
+// Event is a verified webhook notification from a provider
+type Event struct {
+	ID            string // unique per event, for idempotency
+	Type          string
+	IntentID      string
+	Reference     string
+	Amount        money.Amount
+	FailureReason string
+}
+
+// Provider takes card payments through a payment gateway. Payments are
+// authorized first and captured later, so they can still be voided.
+type Provider interface {
+	Name() string
+	CreateIntent(req IntentRequest) (*Intent, error)
+	Capture(intentID string, amount money.Amount) error
+	Void(intentID string) error
+	Refund(intentID string, amount money.Amount) (string, error)
+	ParseWebhook(payload []byte, signature string) (*Event, error)
+}
+
+// New builds the provider selected by PAYMENT_PROVIDER. Only "mock" (the
+// default), a sandbox gateway for local development, is built in.
+func New() (Provider, error) {
+	switch strings.ToLower(config.AppConfig.PaymentProvider) {
+	case "", "mock":
+		return &MockProvider{WebhookSecret: config.AppConfig.PaymentWebhookSecret}, nil
+	default:
+		return nil, fmt.Errorf("unknown PAYMENT_PROVIDER %q", config.AppConfig.PaymentProvider)
+	}
+}
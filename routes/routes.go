@@ -1,8 +1,10 @@
 package routes
 
 import (
+	"ordernew/config"
 	"ordernew/controllers"
 	"ordernew/middleware"
+	"ordernew/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,6 +18,9 @@ func SetupRoutes(router *gin.Engine) {
 	// Apply CORS middleware
 	router.Use(middleware.CORSMiddleware())
 
+	// Public keys for services that verify our access tokens
+	router.GET("/.well-known/jwks.json", userController.GetJWKS)
+
 	// API v1 group
 	v1 := router.Group("/api/v1")
 	{
@@ -27,31 +32,75 @@ func SetupRoutes(router *gin.Engine) {
 		{
 			auth.POST("/register", userController.Register)
 			auth.POST("/login", userController.Login)
+			auth.POST("/refresh", userController.RefreshToken)
+			auth.POST("/logout", middleware.AuthMiddleware(), userController.Logout)
+			auth.POST("/logout-all", middleware.AuthMiddleware(), userController.LogoutAll)
+			auth.POST("/forgot-password", userController.ForgotPassword)
+			auth.POST("/reset-password", userController.ResetPassword)
+			auth.POST("/verify-email", userController.VerifyEmail)
+			auth.POST("/resend-verification", middleware.AuthMiddleware(), userController.ResendVerificationEmail)
+			auth.POST("/otp/request", userController.RequestOTP)
+			auth.POST("/otp/verify", middleware.OptionalAuthMiddleware(), userController.VerifyOTP)
+
+			// Two-factor authentication
+			auth.POST("/2fa/verify", userController.VerifyTwoFactorLogin)
+			auth.POST("/2fa/setup", middleware.AuthMiddleware(), userController.SetupTwoFactor)
+			auth.POST("/2fa/enable", middleware.AuthMiddleware(), userController.EnableTwoFactor)
+			auth.POST("/2fa/disable", middleware.AuthMiddleware(), userController.DisableTwoFactor)
+			auth.POST("/2fa/recovery-codes", middleware.AuthMiddleware(), userController.RegenerateRecoveryCodes)
 		}
 
 		// Protected routes (require authentication)
 		users := v1.Group("/users")
 		users.Use(middleware.AuthMiddleware())
 		{
-			users.GET("", userController.GetAllUsers)
-			users.GET("/:id", userController.GetUserByID)
-			users.PUT("/:id", userController.UpdateUser)
-			users.DELETE("/:id", userController.DeleteUser)
+			users.GET("", middleware.RequirePermission(models.PermissionUsersRead), userController.GetAllUsers)
+
+			// The authenticated user's own profile, addresses, sessions and login history
+			users.GET("/me", userController.GetMe)
+			users.PATCH("/me", userController.UpdateMe)
+			users.DELETE("/me", userController.DeleteMe)
+			users.PUT("/me/password", userController.ChangeMyPassword)
+			users.PUT("/me/email", userController.ChangeMyEmail)
+			users.GET("/me/addresses", userController.GetMyAddresses)
+			users.POST("/me/addresses", userController.AddMyAddress)
+			users.PUT("/me/addresses/:addressId", userController.UpdateMyAddress)
+			users.DELETE("/me/addresses/:addressId", userController.DeleteMyAddress)
+			users.GET("/me/sessions", userController.GetMySessions)
+			users.DELETE("/me/sessions/:sessionId", userController.RevokeMySession)
+			users.GET("/me/login-events", userController.GetMyLoginEvents)
+
+			users.GET("/:id", middleware.RequireSelfOrPermission("id", models.PermissionUsersRead), userController.GetUserByID)
+			users.PUT("/:id", middleware.RequireSelfOrPermission("id", models.PermissionUsersUpdate), userController.UpdateUser)
+			users.DELETE("/:id", middleware.RequirePermission(models.PermissionUsersDelete), userController.DeleteUser)
+			users.DELETE("/:id/2fa", middleware.RequirePermission(models.PermissionUsersUpdate), userController.ResetUserTwoFactor)
+			users.POST("/:id/unlock", middleware.RequirePermission(models.PermissionUsersUpdate), userController.UnlockUser)
+		}
+
+		// Admin routes (require admin role)
+		admin := v1.Group("/admin")
+		admin.Use(middleware.AuthMiddleware(), middleware.AdminMiddleware())
+		{
+			admin.GET("/security-settings", userController.GetSecuritySettings)
+			admin.PUT("/security-settings", userController.UpdateSecuritySettings)
 		}
 
 		// Product routes (require authentication)
 		products := v1.Group("/products")
 		products.Use(middleware.AuthMiddleware())
 		{
-			products.POST("", productController.CreateProduct)                   // Create product
-			products.GET("", productController.GetAllProducts)                   // Get all products
-			products.GET("/search", productController.SearchProducts)            // Search products
-			products.GET("/category/:category", productController.GetProductsByCategory) // Get by category
-			products.GET("/:id", productController.GetProductByID)               // Get product by ID
-			products.PUT("/:id", productController.UpdateProduct)                // Update product (full)
-			products.PATCH("/:id", productController.PatchProduct)               // Patch product (partial)
-			products.DELETE("/:id", productController.DeleteProduct)             // Delete product
-			products.PUT("/:id/quantity", productController.UpdateProductQuantity) // Update quantity only
+			productsRead := middleware.RequirePermission(models.PermissionProductsRead)
+			productsWrite := middleware.RequirePermission(models.PermissionProductsWrite)
+
+			products.POST("", productsWrite, productController.CreateProduct)                   // Create product
+			products.GET("", productsRead, productController.GetAllProducts)                   // Get all products
+			products.GET("/search", productsRead, productController.SearchProducts)            // Search products
+			products.GET("/category/:category", productsRead, productController.GetProductsByCategory) // Get by category
+			products.GET("/:id", productsRead, productController.GetProductByID)               // Get product by ID
+			products.PUT("/:id", productsWrite, productController.UpdateProduct)                // Update product (full)
+			products.PATCH("/:id", productsWrite, productController.PatchProduct)               // Patch product (partial)
+			products.DELETE("/:id", productsWrite, productController.DeleteProduct)             // Delete product
+			products.PUT("/:id/quantity", productsWrite, productController.UpdateProductQuantity) // Update quantity only
 		}
 
 		// Store routes
@@ -60,19 +109,41 @@ func SetupRoutes(router *gin.Engine) {
 			// Public endpoints (for QR code scanning and customer viewing)
 			stores.GET("/:id", controllers.GetStore)
 			stores.GET("", controllers.GetAllStores)
+			stores.GET("/:id/qr.png", controllers.GetStoreQRCodePNG)
+			stores.GET("/:id/qr.svg", controllers.GetStoreQRCodeSVG)
+			stores.GET("/:id/menu", controllers.GetStoreMenu)
 
 			// Protected endpoints (require authentication - for store owners)
 			storesProtected := stores.Group("")
 			storesProtected.Use(middleware.AuthMiddleware())
 			{
-				storesProtected.POST("", controllers.CreateStore)
+				storesProtected.POST("", middleware.RequireVerifiedEmail(), middleware.RequirePermission(models.PermissionStoresCreate), controllers.CreateStore)
 				storesProtected.GET("/my-stores", controllers.GetMyStores)
-				storesProtected.PUT("/:id", controllers.UpdateStore)
-				storesProtected.DELETE("/:id", controllers.DeleteStore)
-				storesProtected.PATCH("/:id/toggle-status", controllers.ToggleStoreStatus)
+
+				// Store endpoints check the caller's permissions in the store
+				storeAccess := middleware.RequireStorePermission(models.PermissionStoreUpdate, middleware.StoreFromParam("id"))
+				storesProtected.PUT("/:id", storeAccess, controllers.UpdateStore)
+				storesProtected.DELETE("/:id", middleware.RequireStorePermission(models.PermissionStoreDelete, middleware.StoreFromParam("id")), controllers.DeleteStore)
+				storesProtected.PATCH("/:id/toggle-status", storeAccess, controllers.ToggleStoreStatus)
+				storesProtected.GET("/:id/tables/qr.pdf", middleware.RequireStorePermission(models.PermissionTablesRead, middleware.StoreFromParam("id")), controllers.GetStoreTableQRSheet)
+
+				// Staff membership
+				staffAccess := middleware.RequireStorePermission(models.PermissionStaffManage, middleware.StoreFromParam("id"))
+				storesProtected.GET("/:id/members", staffAccess, controllers.GetStoreMembers)
+				storesProtected.POST("/:id/members", middleware.RequireVerifiedEmail(), staffAccess, controllers.InviteStoreMember)
+				storesProtected.PUT("/:id/members/:memberId", staffAccess, controllers.UpdateStoreMember)
+				storesProtected.DELETE("/:id/members/:memberId", staffAccess, controllers.RemoveStoreMember)
 			}
 		}
 
+		// Store invite routes (for the invited user)
+		storeInvites := v1.Group("/store-invites")
+		storeInvites.Use(middleware.AuthMiddleware())
+		{
+			storeInvites.GET("", controllers.GetMyStoreInvites)
+			storeInvites.POST("/accept", middleware.RequireVerifiedEmail(), controllers.AcceptStoreInvite)
+		}
+
 		// Category routes
 		categories := v1.Group("/categories")
 		{
@@ -85,9 +156,11 @@ func SetupRoutes(router *gin.Engine) {
 			categoriesProtected := categories.Group("")
 			categoriesProtected.Use(middleware.AuthMiddleware())
 			{
-				categoriesProtected.POST("", controllers.CreateCategory)
-				categoriesProtected.PUT("/:id", controllers.UpdateCategory)
-				categoriesProtected.DELETE("/:id", controllers.DeleteCategory)
+				categoryAccess := middleware.RequireStorePermission(models.PermissionMenuWrite, middleware.StoreOfCategory("id"))
+				categoriesProtected.POST("", middleware.RequireStorePermission(models.PermissionMenuWrite, middleware.StoreFromBody()), controllers.CreateCategory)
+				categoriesProtected.PUT("/:id", categoryAccess, controllers.UpdateCategory)
+				categoriesProtected.DELETE("/:id", categoryAccess, controllers.DeleteCategory)
+				categoriesProtected.PUT("/:id/modifier-groups", categoryAccess, controllers.SetCategoryModifierGroups)
 			}
 		}
 
@@ -105,10 +178,157 @@ func SetupRoutes(router *gin.Engine) {
 			foodItemsProtected := foodItems.Group("")
 			foodItemsProtected.Use(middleware.AuthMiddleware())
 			{
-				foodItemsProtected.POST("", controllers.CreateFoodItem)
-				foodItemsProtected.PUT("/:id", controllers.UpdateFoodItem)
-				foodItemsProtected.DELETE("/:id", controllers.DeleteFoodItem)
-				foodItemsProtected.PATCH("/:id/toggle-availability", controllers.ToggleFoodItemAvailability)
+				foodItemAccess := middleware.RequireStorePermission(models.PermissionMenuWrite, middleware.StoreOfFoodItem("id"))
+				foodItemsProtected.POST("", middleware.RequireStorePermission(models.PermissionMenuWrite, middleware.StoreFromBody()), controllers.CreateFoodItem)
+				foodItemsProtected.PUT("/:id", foodItemAccess, controllers.UpdateFoodItem)
+				foodItemsProtected.DELETE("/:id", foodItemAccess, controllers.DeleteFoodItem)
+				foodItemsProtected.PATCH("/:id/toggle-availability", foodItemAccess, controllers.ToggleFoodItemAvailability)
+				foodItemsProtected.PUT("/:id/modifier-groups", foodItemAccess, controllers.SetFoodItemModifierGroups)
+			}
+		}
+
+		// Combo routes (bundles of food items at a fixed price)
+		combos := v1.Group("/combos")
+		{
+			// Public endpoints (for customers to view menu)
+			combos.GET("/store/:storeId", controllers.GetCombosByStore)
+			combos.GET("/store/:storeId/available", controllers.GetAvailableCombosByStore)
+			combos.GET("/:id", controllers.GetCombo)
+
+			// Protected endpoints (require authentication - for store owners)
+			combosProtected := combos.Group("")
+			combosProtected.Use(middleware.AuthMiddleware())
+			{
+				comboAccess := middleware.RequireStorePermission(models.PermissionMenuWrite, middleware.StoreOfCombo("id"))
+				combosProtected.POST("", middleware.RequireStorePermission(models.PermissionMenuWrite, middleware.StoreFromBody()), controllers.CreateCombo)
+				combosProtected.PUT("/:id", comboAccess, controllers.UpdateCombo)
+				combosProtected.DELETE("/:id", comboAccess, controllers.DeleteCombo)
+				combosProtected.PATCH("/:id/toggle-availability", comboAccess, controllers.ToggleComboAvailability)
+			}
+		}
+
+		// Modifier Group routes (sizes, add-ons, etc.)
+		modifierGroups := v1.Group("/modifier-groups")
+		{
+			// Public endpoints (for customers to view menu)
+			modifierGroups.GET("/store/:storeId", controllers.GetModifierGroupsByStore)
+			modifierGroups.GET("/food-item/:foodItemId", controllers.GetFoodItemModifierGroups)
+			modifierGroups.GET("/:id", controllers.GetModifierGroup)
+
+			// Protected endpoints (require authentication - for store owners)
+			modifierGroupsProtected := modifierGroups.Group("")
+			modifierGroupsProtected.Use(middleware.AuthMiddleware())
+			{
+				modifierGroupAccess := middleware.RequireStorePermission(models.PermissionMenuWrite, middleware.StoreOfModifierGroup("id"))
+				modifierGroupsProtected.POST("", middleware.RequireStorePermission(models.PermissionMenuWrite, middleware.StoreFromBody()), controllers.CreateModifierGroup)
+				modifierGroupsProtected.PUT("/:id", modifierGroupAccess, controllers.UpdateModifierGroup)
+				modifierGroupsProtected.DELETE("/:id", modifierGroupAccess, controllers.DeleteModifierGroup)
+			}
+		}
+
+		// Table routes
+		tables := v1.Group("/tables")
+		{
+			// Public endpoint (customers scan the table QR code)
+			tables.POST("/scan", controllers.ScanTable)
+
+			// Protected endpoints (require authentication - for store owners)
+			tablesProtected := tables.Group("")
+			tablesProtected.Use(middleware.AuthMiddleware())
+			{
+				// Managers set tables up; floor staff (cashiers, waiters) may view them
+				tableAccess := middleware.RequireStorePermission(models.PermissionTablesWrite, middleware.StoreOfTable("id"))
+				tableFloorAccess := middleware.RequireStorePermission(models.PermissionTablesRead, middleware.StoreOfTable("id"))
+				tablesProtected.POST("", middleware.RequireStorePermission(models.PermissionTablesWrite, middleware.StoreFromBody()), controllers.CreateTable)
+				tablesProtected.GET("/store/:storeId", middleware.RequireStorePermission(models.PermissionTablesRead, middleware.StoreFromParam("storeId")), controllers.GetTablesByStore)
+				tablesProtected.GET("/:id", tableFloorAccess, controllers.GetTable)
+				tablesProtected.PUT("/:id", tableAccess, controllers.UpdateTable)
+				tablesProtected.DELETE("/:id", tableAccess, controllers.DeleteTable)
+				tablesProtected.POST("/:id/regenerate-token", tableAccess, controllers.RegenerateTableToken)
+				tablesProtected.GET("/:id/session", tableFloorAccess, controllers.GetTableSession)
+				tablesProtected.GET("/:id/qr.png", tableFloorAccess, controllers.GetTableQRCodePNG)
+				tablesProtected.GET("/:id/qr.svg", tableFloorAccess, controllers.GetTableQRCodeSVG)
+			}
+		}
+
+		// Dining session routes (require authentication - for store floor staff)
+		diningSessions := v1.Group("/dining-sessions")
+		diningSessions.Use(middleware.AuthMiddleware(), middleware.RequireStorePermission(models.PermissionSessionsManage, middleware.StoreOfDiningSession("id")))
+		{
+			diningSessions.GET("/:id", controllers.GetDiningSession)
+			diningSessions.POST("/:id/close", controllers.CloseDiningSession)
+			diningSessions.POST("/:id/split", controllers.SplitDiningSessionBill)
+			diningSessions.DELETE("/:id/split", controllers.RemoveDiningSessionSplit)
+		}
+
+		// Promotion routes (require authentication - for store managers)
+		promotions := v1.Group("/promotions")
+		promotions.Use(middleware.AuthMiddleware())
+		{
+			promotionAccess := middleware.RequireStorePermission(models.PermissionPromotionsManage, middleware.StoreOfPromotion("id"))
+			promotions.POST("", middleware.RequireStorePermission(models.PermissionPromotionsManage, middleware.StoreFromBody()), controllers.CreatePromotion)
+			promotions.GET("/store/:storeId", middleware.RequireStorePermission(models.PermissionPromotionsManage, middleware.StoreFromParam("storeId")), controllers.GetPromotionsByStore)
+			promotions.GET("/:id", promotionAccess, controllers.GetPromotion)
+			promotions.PUT("/:id", promotionAccess, controllers.UpdatePromotion)
+			promotions.DELETE("/:id", promotionAccess, controllers.DeletePromotion)
+		}
+
+		// Cart routes (anonymous carts are accessed with the X-Cart-Token header)
+		carts := v1.Group("/carts")
+		carts.Use(middleware.OptionalAuthMiddleware())
+		{
+			carts.POST("", controllers.CreateCart)
+			carts.GET("/:id", controllers.GetCart)
+			carts.POST("/:id/items", controllers.AddCartItem)
+			carts.PATCH("/:id/items/:itemId", controllers.UpdateCartItem)
+			carts.DELETE("/:id/items/:itemId", controllers.RemoveCartItem)
+			carts.PUT("/:id/promo-code", controllers.ApplyCartPromoCode)
+			carts.DELETE("/:id/promo-code", controllers.RemoveCartPromoCode)
+			carts.POST("/:id/checkout", controllers.CheckoutCart)
+		}
+
+		// Order routes
+		orders := v1.Group("/orders")
+		{
+			// Customers may order anonymously (e.g. after scanning a store QR code)
+			orders.POST("", middleware.OptionalAuthMiddleware(), controllers.CreateOrder)
+
+			// Protected endpoints (require authentication)
+			ordersProtected := orders.Group("")
+			ordersProtected.Use(middleware.AuthMiddleware())
+			{
+				ordersProtected.GET("/my-orders", controllers.GetMyOrders)
+				ordersProtected.GET("/store/:storeId", controllers.GetOrdersByStore)
+				ordersProtected.GET("/store/:storeId/tax-report", controllers.GetStoreTaxReport)
+				ordersProtected.GET("/:id", controllers.GetOrder)
+				ordersProtected.PATCH("/:id/status", controllers.UpdateOrderStatus)
+			}
+		}
+
+		// Payment routes
+		payments := v1.Group("/payments")
+		{
+			// Provider callbacks are authenticated by their signature
+			payments.POST("/webhook", controllers.HandlePaymentWebhook)
+
+			// Protected endpoints (require authentication)
+			paymentsProtected := payments.Group("")
+			paymentsProtected.Use(middleware.AuthMiddleware())
+			{
+				// Floor staff take and void payments; refunds are for managers
+				paymentAccess := middleware.RequireStorePermission(models.PermissionPaymentsManage, middleware.StoreOfPayment("id"))
+				paymentsProtected.POST("/order/:orderId", controllers.CreatePayment)
+				paymentsProtected.GET("/order/:orderId", controllers.GetOrderPayments)
+				paymentsProtected.POST("/sub-bill/:billId", controllers.CreateSubBillPayment)
+				paymentsProtected.GET("/sub-bill/:billId", controllers.GetSubBillPayments)
+				paymentsProtected.GET("/:id", controllers.GetPayment)
+				paymentsProtected.POST("/:id/capture", paymentAccess, controllers.CapturePayment)
+				paymentsProtected.POST("/:id/void", paymentAccess, controllers.VoidPayment)
+				paymentsProtected.POST("/:id/refund", middleware.RequireStorePermission(models.PermissionPaymentsRefund, middleware.StoreOfPayment("id")), controllers.RefundPayment)
+				// Simulating the customer's confirmation is for local development with the mock gateway
+				if config.AppConfig.GinMode != "release" {
+					paymentsProtected.POST("/:id/sandbox/confirm", paymentAccess, controllers.ConfirmSandboxPayment)
+				}
 			}
 		}
 	}
@@ -122,11 +342,25 @@ func SetupRoutes(router *gin.Engine) {
 				"health":      "/api/v1/hello",
 				"register":    "POST /api/v1/auth/register",
 				"login":       "POST /api/v1/auth/login",
+				"refresh":     "POST /api/v1/auth/refresh",
+				"logout":      "POST /api/v1/auth/logout (requires auth)",
+				"otp_login":   "POST /api/v1/auth/otp/request, POST /api/v1/auth/otp/verify",
+				"jwks":        "/.well-known/jwks.json",
 				"users":       "/api/v1/users (requires auth)",
+				"admin":       "/api/v1/admin (requires admin)",
 				"products":    "/api/v1/products (requires auth)",
 				"stores":      "/api/v1/stores",
+				"store_invites": "/api/v1/store-invites (requires auth)",
+				"menu":        "/api/v1/stores/:id/menu",
 				"categories":  "/api/v1/categories",
 				"food_items":  "/api/v1/food-items",
+				"modifier_groups": "/api/v1/modifier-groups",
+				"combos":      "/api/v1/combos",
+				"tables":      "/api/v1/tables",
+				"promotions":  "/api/v1/promotions (requires auth)",
+				"carts":       "/api/v1/carts",
+				"orders":      "/api/v1/orders",
+				"payments":    "/api/v1/payments (requires auth)",
 			},
 		})
 	})
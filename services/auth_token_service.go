@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"ordernew/config"
+	"ordernew/mailer"
+	"ordernew/models"
+	"ordernew/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var authTokenCollection *mongo.Collection
+
+// mailSender delivers account emails; it is set by InitMailer
+var mailSender mailer.Mailer
+
+// ErrInvalidAuthToken is returned for unknown, expired or already used reset and verification tokens
+var ErrInvalidAuthToken = errors.New("invalid or expired token")
+
+// ErrEmailAlreadyVerified is returned when asking to verify an address that already is
+var ErrEmailAlreadyVerified = errors.New("email is already verified")
+
+const (
+	passwordResetTTL     = time.Hour
+	emailVerificationTTL = 48 * time.Hour
+)
+
+// InitAuthTokenCollection initializes the auth token collection
+func InitAuthTokenCollection() {
+	authTokenCollection = config.GetCollection("auth_tokens")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Expired tokens are removed by MongoDB
+	_, err := authTokenCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "purpose", Value: 1}}},
+	})
+	if err != nil {
+		log.Println("Warning: failed to create auth token indexes:", err)
+	}
+}
+
+// InitMailer sets up the mailer configured by MAIL_DRIVER
+func InitMailer() error {
+	m, err := mailer.New()
+	if err != nil {
+		return err
+	}
+	mailSender = m
+	return nil
+}
+
+// MigrateUserEmailVerification marks accounts created before email
+// verification existed as verified, so they keep their access
+func MigrateUserEmailVerification() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	result, err := userCollection.UpdateMany(ctx,
+		bson.M{"email_verified": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"email_verified": true}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.ModifiedCount > 0 {
+		log.Printf("Marked %d existing users as email verified", result.ModifiedCount)
+	}
+	return nil
+}
+
+// SendVerificationEmail emails a user a link to verify their address
+func SendVerificationEmail(user *models.User) error {
+	token, err := issueAuthToken(user, models.AuthTokenEmailVerification, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	return mailSender.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease verify your email address by opening this link:\n\n%s\n\nThe link expires in 48 hours.\n",
+			user.Name, appLink("/verify-email", token)),
+	})
+}
+
+// ResendVerificationEmail sends a new verification link to a user who is not verified yet
+func ResendVerificationEmail(userID primitive.ObjectID) error {
+	user, err := getActiveUser(userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerified {
+		return ErrEmailAlreadyVerified
+	}
+	return SendVerificationEmail(user)
+}
+
+// VerifyEmail redeems a verification token and marks the user's email as verified
+func VerifyEmail(token string) (*models.UserResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	authToken, err := consumeAuthToken(token, models.AuthTokenEmailVerification)
+	if err != nil {
+		return nil, err
+	}
+
+	// The token only verifies the address it was sent to
+	now := time.Now()
+	var user models.User
+	err = userCollection.FindOneAndUpdate(ctx,
+		bson.M{"_id": authToken.UserID, "email": authToken.Email},
+		bson.M{"$set": bson.M{"email_verified": true, "email_verified_at": now, "updated_at": now}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrInvalidAuthToken
+		}
+		return nil, err
+	}
+
+	response := user.ToUserResponse()
+	return &response, nil
+}
+
+// RequestPasswordReset emails a reset link if an active account uses the
+// address. Unknown addresses are ignored so callers cannot probe for accounts.
+func RequestPasswordReset(email string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var user models.User
+	err := userCollection.FindOne(ctx, bson.M{"email": email}).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		return err
+	}
+	if !user.IsActive {
+		return nil
+	}
+
+	token, err := issueAuthToken(&user, models.AuthTokenPasswordReset, passwordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	return mailSender.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your account. If it was you, open this link:\n\n%s\n\nThe link expires in 1 hour. If you did not ask for a reset, you can ignore this email.\n",
+			user.Name, appLink("/reset-password", token)),
+	})
+}
+
+// ResetPassword sets a new password with a reset token and signs the user out everywhere
+func ResetPassword(token, password string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	authToken, err := consumeAuthToken(token, models.AuthTokenPasswordReset)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return errors.New("failed to hash password")
+	}
+
+	// Receiving the reset email also proves the user owns the address
+	now := time.Now()
+	result, err := userCollection.UpdateOne(ctx,
+		bson.M{"_id": authToken.UserID, "email": authToken.Email, "is_active": true},
+		bson.M{
+			"$set": bson.M{
+				"password":          hashedPassword,
+				"email_verified":    true,
+				"email_verified_at": now,
+				"updated_at":        now,
+			},
+			// A new password also lifts a lock from failed logins
+			"$unset": bson.M{"failed_login_attempts": "", "locked_until": ""},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrInvalidAuthToken
+	}
+
+	return RevokeAllSessions(authToken.UserID)
+}
+
+// issueAuthToken creates a token for a user, replacing earlier ones with the same purpose
+func issueAuthToken(user *models.User, purpose string, ttl time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", errors.New("failed to generate token")
+	}
+
+	if _, err := authTokenCollection.DeleteMany(ctx, bson.M{"user_id": user.ID, "purpose": purpose}); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	_, err = authTokenCollection.InsertOne(ctx, models.AuthToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenHash: utils.HashToken(token),
+		Email:     user.Email,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// consumeAuthToken redeems a token, deleting it so it cannot be used twice
+func consumeAuthToken(token, purpose string) (*models.AuthToken, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var authToken models.AuthToken
+	err := authTokenCollection.FindOneAndDelete(ctx, bson.M{
+		"token_hash": utils.HashToken(token),
+		"purpose":    purpose,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}).Decode(&authToken)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrInvalidAuthToken
+		}
+		return nil, err
+	}
+
+	return &authToken, nil
+}
+
+// appLink builds a link to a page of the frontend carrying a token
+func appLink(path, token string) string {
+	return config.AppConfig.AppBaseURL + path + "?token=" + url.QueryEscape(token)
+}
@@ -0,0 +1,733 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"ordernew/config"
+	"ordernew/models"
+	"ordernew/money"
+	"ordernew/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var cartCollection *mongo.Collection
+
+var (
+	// ErrCartAccessDenied is returned when the caller neither owns the cart nor holds its session token
+	ErrCartAccessDenied = errors.New("you do not have access to this cart")
+	// ErrCartNotActive is returned when a cart that was already checked out is modified
+	ErrCartNotActive = errors.New("cart is no longer active")
+)
+
+// CartAccess identifies the caller of a cart operation: an authenticated
+// customer, the holder of the cart's session token, or both
+type CartAccess struct {
+	CustomerID primitive.ObjectID
+	Token      string
+}
+
+// InitCartCollection initializes the cart collection and its expiry index
+func InitCartCollection() {
+	cartCollection = config.GetCollection("carts")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Let MongoDB remove carts once they expire
+	_, err := cartCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		log.Println("Warning: failed to create cart expiry index:", err)
+	}
+}
+
+// cartTTL returns how long a cart stays alive without activity
+func cartTTL() time.Duration {
+	ttl, err := time.ParseDuration(config.AppConfig.CartTTL)
+	if err != nil {
+		ttl = 2 * time.Hour // Default to 2 hours
+	}
+	return ttl
+}
+
+// CreateCart opens a new cart for a store and returns it with its session token.
+// The token is only returned here; the server keeps its hash.
+func CreateCart(req models.CreateCartRequest, customerID primitive.ObjectID) (*models.Cart, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	storeID, err := primitive.ObjectIDFromHex(req.StoreID)
+	if err != nil {
+		return nil, "", errors.New("invalid store ID")
+	}
+
+	store, err := GetStoreByID(req.StoreID)
+	if err != nil {
+		return nil, "", errors.New("store not found")
+	}
+	if !store.IsActive {
+		return nil, "", errors.New("store is not active")
+	}
+
+	// A cart opened from a table QR code orders for that table
+	var tableID primitive.ObjectID
+	if req.TableToken != "" {
+		table, err := ResolveTableToken(req.TableToken)
+		if err != nil {
+			return nil, "", err
+		}
+		if table.StoreID != storeID {
+			return nil, "", errors.New("table does not belong to this store")
+		}
+		tableID = table.ID
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, "", errors.New("failed to generate cart token")
+	}
+
+	now := time.Now()
+	cart := &models.Cart{
+		StoreID:          storeID,
+		CustomerID:       customerID,
+		SessionTokenHash: utils.HashToken(token),
+		TableToken:       req.TableToken,
+		TableID:          tableID,
+		Items:            []models.CartItem{},
+		Status:           models.CartStatusActive,
+		ExpiresAt:        now.Add(cartTTL()),
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+
+	result, err := cartCollection.InsertOne(ctx, cart)
+	if err != nil {
+		return nil, "", err
+	}
+
+	cart.ID = result.InsertedID.(primitive.ObjectID)
+	return cart, token, nil
+}
+
+// GetCart retrieves a cart the caller has access to
+func GetCart(cartID string, access CartAccess) (*models.Cart, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(cartID)
+	if err != nil {
+		return nil, errors.New("invalid cart ID")
+	}
+
+	var cart models.Cart
+	err = cartCollection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&cart)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("cart not found")
+		}
+		return nil, err
+	}
+
+	// The TTL monitor only runs periodically, so check expiry ourselves too
+	if cart.Status == models.CartStatusActive && time.Now().After(cart.ExpiresAt) {
+		return nil, errors.New("cart has expired")
+	}
+
+	if !canAccessCart(&cart, access) {
+		return nil, ErrCartAccessDenied
+	}
+
+	return &cart, nil
+}
+
+// AddCartItem adds a food item to a cart, merging it with an identical line if present
+func AddCartItem(cartID string, access CartAccess, req models.AddCartItemRequest) (*models.Cart, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cart, err := getActiveCart(cartID, access)
+	if err != nil {
+		return nil, err
+	}
+
+	line := models.CartItem{
+		ID:       primitive.NewObjectID(),
+		Quantity: req.Quantity,
+		Notes:    req.Notes,
+	}
+	if req.ComboID != "" {
+		combo, err := validateCartCombo(cart, req.ComboID)
+		if err != nil {
+			return nil, err
+		}
+		selections, err := ParseComboSelections(req.ComboSelections)
+		if err != nil {
+			return nil, err
+		}
+		if _, _, err := PriceComboLine(combo, selections); err != nil {
+			return nil, err
+		}
+		line.ComboID = combo.ID
+		line.ComboSelections = selections
+	} else {
+		foodItem, err := validateCartFoodItem(cart, req.FoodItemID)
+		if err != nil {
+			return nil, err
+		}
+		optionIDs, err := ParseModifierOptionIDs(req.ModifierOptionIDs)
+		if err != nil {
+			return nil, err
+		}
+		if _, _, err := PriceFoodItemLine(foodItem, optionIDs); err != nil {
+			return nil, err
+		}
+		line.FoodItemID = foodItem.ID
+		line.ModifierOptionIDs = optionIDs
+	}
+
+	now := time.Now()
+	touch := bson.M{
+		"expires_at": now.Add(cartTTL()),
+		"updated_at": now,
+	}
+
+	// Merge with an existing line for the same item, choices and notes
+	for _, item := range cart.Items {
+		if sameCartLine(item, line) {
+			filter := bson.M{"_id": cart.ID, "status": models.CartStatusActive, "items._id": item.ID}
+			update := bson.M{
+				"$inc": bson.M{"items.$.quantity": req.Quantity},
+				"$set": touch,
+			}
+			if err := updateActiveCart(ctx, filter, update); err != nil {
+				return nil, err
+			}
+			return GetCart(cartID, access)
+		}
+	}
+
+	line.AddedAt = now
+	filter := bson.M{"_id": cart.ID, "status": models.CartStatusActive}
+	update := bson.M{
+		"$push": bson.M{"items": line},
+		"$set":  touch,
+	}
+	if err := updateActiveCart(ctx, filter, update); err != nil {
+		return nil, err
+	}
+
+	return GetCart(cartID, access)
+}
+
+// UpdateCartItem changes the quantity, notes or modifier options of a cart line
+func UpdateCartItem(cartID, itemID string, access CartAccess, req models.UpdateCartItemRequest) (*models.Cart, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cart, err := getActiveCart(cartID, access)
+	if err != nil {
+		return nil, err
+	}
+
+	lineID, err := primitive.ObjectIDFromHex(itemID)
+	if err != nil {
+		return nil, errors.New("invalid cart item ID")
+	}
+
+	var line *models.CartItem
+	for i := range cart.Items {
+		if cart.Items[i].ID == lineID {
+			line = &cart.Items[i]
+			break
+		}
+	}
+	if line == nil {
+		return nil, errors.New("cart item not found")
+	}
+
+	now := time.Now()
+	set := bson.M{
+		"expires_at": now.Add(cartTTL()),
+		"updated_at": now,
+	}
+	if req.Quantity != nil {
+		set["items.$.quantity"] = *req.Quantity
+	}
+	if req.Notes != nil {
+		set["items.$.notes"] = *req.Notes
+	}
+	if req.ModifierOptionIDs != nil {
+		if !line.ComboID.IsZero() {
+			return nil, errors.New("modifier options cannot be set on a combo")
+		}
+		foodItem, err := validateCartFoodItem(cart, line.FoodItemID.Hex())
+		if err != nil {
+			return nil, err
+		}
+		optionIDs, err := ParseModifierOptionIDs(req.ModifierOptionIDs)
+		if err != nil {
+			return nil, err
+		}
+		if _, _, err := PriceFoodItemLine(foodItem, optionIDs); err != nil {
+			return nil, err
+		}
+		set["items.$.modifier_option_ids"] = optionIDs
+	}
+	if req.ComboSelections != nil {
+		if line.ComboID.IsZero() {
+			return nil, errors.New("combo selections can only be set on a combo")
+		}
+		combo, err := validateCartCombo(cart, line.ComboID.Hex())
+		if err != nil {
+			return nil, err
+		}
+		selections, err := ParseComboSelections(req.ComboSelections)
+		if err != nil {
+			return nil, err
+		}
+		if _, _, err := PriceComboLine(combo, selections); err != nil {
+			return nil, err
+		}
+		set["items.$.combo_selections"] = selections
+	}
+
+	filter := bson.M{"_id": cart.ID, "status": models.CartStatusActive, "items._id": lineID}
+	if err := updateActiveCart(ctx, filter, bson.M{"$set": set}); err != nil {
+		return nil, errors.New("cart item not found")
+	}
+
+	return GetCart(cartID, access)
+}
+
+// RemoveCartItem removes a line from a cart
+func RemoveCartItem(cartID, itemID string, access CartAccess) (*models.Cart, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cart, err := getActiveCart(cartID, access)
+	if err != nil {
+		return nil, err
+	}
+
+	lineID, err := primitive.ObjectIDFromHex(itemID)
+	if err != nil {
+		return nil, errors.New("invalid cart item ID")
+	}
+
+	now := time.Now()
+	filter := bson.M{"_id": cart.ID, "status": models.CartStatusActive, "items._id": lineID}
+	update := bson.M{
+		"$pull": bson.M{"items": bson.M{"_id": lineID}},
+		"$set": bson.M{
+			"expires_at": now.Add(cartTTL()),
+			"updated_at": now,
+		},
+	}
+	if err := updateActiveCart(ctx, filter, update); err != nil {
+		return nil, errors.New("cart item not found")
+	}
+
+	return GetCart(cartID, access)
+}
+
+// ApplyCartPromoCode enters a promo code on a cart. The code must exist and be
+// running now; whether it applies to the items is shown when the cart is priced.
+func ApplyCartPromoCode(cartID string, access CartAccess, req models.ApplyPromoCodeRequest) (*models.Cart, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cart, err := getActiveCart(cartID, access)
+	if err != nil {
+		return nil, err
+	}
+	store, err := GetStoreByID(cart.StoreID.Hex())
+	if err != nil {
+		return nil, errors.New("store not found")
+	}
+
+	code, err := normalizePromoCode(req.Code)
+	if err != nil || code == "" {
+		return nil, ErrInvalidPromoCode
+	}
+	if _, err := findApplicablePromotions(store, code, cart.CustomerID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"promo_code": code,
+			"expires_at": now.Add(cartTTL()),
+			"updated_at": now,
+		},
+	}
+	if err := updateActiveCart(ctx, bson.M{"_id": cart.ID, "status": models.CartStatusActive}, update); err != nil {
+		return nil, err
+	}
+
+	return GetCart(cartID, access)
+}
+
+// RemoveCartPromoCode removes the promo code of a cart
+func RemoveCartPromoCode(cartID string, access CartAccess) (*models.Cart, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cart, err := getActiveCart(cartID, access)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	update := bson.M{
+		"$unset": bson.M{"promo_code": ""},
+		"$set": bson.M{
+			"expires_at": now.Add(cartTTL()),
+			"updated_at": now,
+		},
+	}
+	if err := updateActiveCart(ctx, bson.M{"_id": cart.ID, "status": models.CartStatusActive}, update); err != nil {
+		return nil, err
+	}
+
+	return GetCart(cartID, access)
+}
+
+// CheckoutCart turns a cart into an order. The cart is claimed first so the
+// same cart cannot be checked out twice concurrently.
+func CheckoutCart(cartID string, access CartAccess, req models.CheckoutCartRequest) (*models.Order, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cart, err := getActiveCart(cartID, access)
+	if err != nil {
+		return nil, err
+	}
+	if len(cart.Items) == 0 {
+		return nil, errors.New("cart is empty")
+	}
+
+	// Claim the cart
+	claim := bson.M{"$set": bson.M{"status": models.CartStatusCheckingOut, "updated_at": time.Now()}}
+	if err := updateActiveCart(ctx, bson.M{"_id": cart.ID, "status": models.CartStatusActive}, claim); err != nil {
+		return nil, err
+	}
+
+	orderReq := models.CreateOrderRequest{
+		StoreID:       cart.StoreID.Hex(),
+		OrderType:     req.OrderType,
+		TableToken:    cart.TableToken,
+		Notes:         req.Notes,
+		CustomerName:  req.CustomerName,
+		CustomerPhone: req.CustomerPhone,
+		PromoCode:     cart.PromoCode,
+	}
+	for _, item := range cart.Items {
+		orderItem := models.CreateOrderItemRequest{
+			Quantity: item.Quantity,
+			Notes:    item.Notes,
+		}
+		if !item.ComboID.IsZero() {
+			orderItem.ComboID = item.ComboID.Hex()
+			for _, selection := range item.ComboSelections {
+				orderItem.ComboSelections = append(orderItem.ComboSelections, models.ComboSelectionRequest{
+					SlotID:     selection.SlotID.Hex(),
+					FoodItemID: selection.FoodItemID.Hex(),
+				})
+			}
+		} else {
+			orderItem.FoodItemID = item.FoodItemID.Hex()
+			for _, optionID := range item.ModifierOptionIDs {
+				orderItem.ModifierOptionIDs = append(orderItem.ModifierOptionIDs, optionID.Hex())
+			}
+		}
+		orderReq.Items = append(orderReq.Items, orderItem)
+	}
+
+	customerID := cart.CustomerID
+	if customerID.IsZero() {
+		customerID = access.CustomerID
+	}
+
+	order, err := CreateOrder(orderReq, customerID)
+	if err != nil {
+		// Release the cart so the customer can fix it and try again
+		release := bson.M{"$set": bson.M{"status": models.CartStatusActive, "updated_at": time.Now()}}
+		if _, releaseErr := cartCollection.UpdateOne(ctx, bson.M{"_id": cart.ID}, release); releaseErr != nil {
+			log.Println("Warning: failed to release cart after checkout error:", releaseErr)
+		}
+		return nil, err
+	}
+
+	done := bson.M{
+		"$set": bson.M{
+			"status":     models.CartStatusCheckedOut,
+			"order_id":   order.ID,
+			"updated_at": time.Now(),
+		},
+	}
+	if _, err := cartCollection.UpdateOne(ctx, bson.M{"_id": cart.ID}, done); err != nil {
+		log.Println("Warning: failed to mark cart as checked out:", err)
+	}
+
+	return order, nil
+}
+
+// PriceCart builds the cart response using current menu names, prices, promotions and taxes.
+// Lines whose item or chosen options are no longer valid are flagged and left out of the subtotal.
+// The cart is taxed as a dine-in order, the default order type at checkout.
+func PriceCart(cart *models.Cart) models.CartResponse {
+	response := models.CartResponse{
+		ID:         cart.ID,
+		StoreID:    cart.StoreID,
+		CustomerID: cart.CustomerID,
+		TableID:    cart.TableID,
+		Items:      []models.CartItemResponse{},
+		PromoCode:  cart.PromoCode,
+		Discounts:  []models.AppliedPromotion{},
+		Status:     cart.Status,
+		OrderID:    cart.OrderID,
+		ExpiresAt:  cart.ExpiresAt,
+		CreatedAt:  cart.CreatedAt,
+		UpdatedAt:  cart.UpdatedAt,
+	}
+
+	store, err := GetStoreByID(cart.StoreID.Hex())
+	var taxSettings *models.TaxSettings
+	if err == nil {
+		response.Currency = store.Currency
+		taxSettings = store.Tax
+	}
+
+	var subtotal money.Amount
+	var discountLines []discountLine
+	var discountIndexes []int
+	for i, item := range cart.Items {
+		var line models.CartItemResponse
+		var discountable discountLine
+		if !item.ComboID.IsZero() {
+			line, discountable = priceComboCartLine(item)
+		} else {
+			line, discountable = priceFoodCartLine(item)
+		}
+
+		if line.IsAvailable {
+			line.LineTotal = line.UnitPrice.Times(line.Quantity)
+			subtotal += line.LineTotal
+			response.ItemCount += line.Quantity
+			discountable.Total = line.LineTotal
+			discountLines = append(discountLines, discountable)
+			discountIndexes = append(discountIndexes, i)
+		}
+		response.Items = append(response.Items, line)
+	}
+	response.Subtotal = subtotal
+
+	if store != nil {
+		applyCartPromotions(&response, store, cart, discountLines, discountIndexes)
+	}
+	applyCartTaxes(&response, taxSettings, models.OrderTypeDineIn)
+
+	return response
+}
+
+// applyCartPromotions discounts the available lines of a priced cart, as checkout would.
+// A promo code that does not apply is reported on the cart rather than failing it,
+// since adding items may make it apply.
+func applyCartPromotions(response *models.CartResponse, store *models.Store, cart *models.Cart, lines []discountLine, indexes []int) {
+	promotions, err := findApplicablePromotions(store, cart.PromoCode, cart.CustomerID)
+	if err != nil && cart.PromoCode != "" {
+		response.PromoCodeError = err.Error()
+		promotions, err = findApplicablePromotions(store, "", cart.CustomerID)
+	}
+	if err != nil {
+		log.Println("Warning: failed to load promotions for cart:", err)
+		return
+	}
+
+	discounts := applyPromotions(promotions, lines)
+	if cart.PromoCode != "" && response.PromoCodeError == "" && !promoCodeApplied(discounts.Promotions) {
+		response.PromoCodeError = ErrPromoCodeNotApplicable.Error()
+	}
+	for j, i := range indexes {
+		response.Items[i].Discounts = discounts.LineDiscounts[j]
+		for _, discount := range discounts.LineDiscounts[j] {
+			response.Items[i].Discount += discount.Amount
+		}
+	}
+	response.Discounts = discounts.Promotions
+	response.DiscountTotal = discounts.Total
+}
+
+// priceFoodCartLine prices a food item cart line with the current menu.
+// Items deleted from the menu stay in the cart as unavailable lines.
+// The returned discount line carries what promotions match the line on.
+func priceFoodCartLine(item models.CartItem) (models.CartItemResponse, discountLine) {
+	line := models.CartItemResponse{
+		ID:         item.ID,
+		FoodItemID: item.FoodItemID,
+		Modifiers:  []models.OrderItemModifier{},
+		Quantity:   item.Quantity,
+		Notes:      item.Notes,
+	}
+
+	foodItem, err := GetFoodItemByID(item.FoodItemID.Hex())
+	if err != nil {
+		line.UnavailableReason = "item was removed from the menu"
+		return line, discountLine{}
+	}
+	discountable := discountLine{CategoryID: foodItem.CategoryID, Tags: foodItem.Tags, Quantity: item.Quantity}
+	line.Name = foodItem.Name
+	line.Image = foodItem.Image
+	line.Price = foodItem.Price
+	line.TaxCategory = foodItem.TaxCategory
+	if !foodItem.IsActive || !foodItem.IsAvailable {
+		line.UnavailableReason = "item is not available"
+		return line, discountable
+	}
+	if err := checkFoodItemSchedule(foodItem, time.Now()); err != nil {
+		line.UnavailableReason = "item is not available at this time"
+		return line, discountable
+	}
+
+	// Options can change after the line was added; re-validate them
+	unitPrice, modifiers, err := PriceFoodItemLine(foodItem, item.ModifierOptionIDs)
+	if err != nil {
+		line.UnavailableReason = err.Error()
+		return line, discountable
+	}
+	line.UnitPrice = unitPrice
+	line.Modifiers = modifiers
+	line.IsAvailable = true
+
+	return line, discountable
+}
+
+// priceComboCartLine prices a combo cart line with the current menu
+func priceComboCartLine(item models.CartItem) (models.CartItemResponse, discountLine) {
+	line := models.CartItemResponse{
+		ID:        item.ID,
+		ComboID:   item.ComboID,
+		Modifiers: []models.OrderItemModifier{},
+		Quantity:  item.Quantity,
+		Notes:     item.Notes,
+	}
+
+	combo, err := GetComboByID(item.ComboID.Hex())
+	if err != nil {
+		line.UnavailableReason = "combo was removed from the menu"
+		return line, discountLine{}
+	}
+	discountable := discountLine{CategoryID: combo.CategoryID, Tags: combo.Tags, Quantity: item.Quantity}
+	line.Name = combo.Name
+	line.Image = combo.Image
+	line.Price = combo.Price
+	line.TaxCategory = combo.TaxCategory
+
+	unitPrice, comboItems, err := PriceComboLine(combo, item.ComboSelections)
+	if err != nil {
+		line.UnavailableReason = err.Error()
+		return line, discountable
+	}
+	line.UnitPrice = unitPrice
+	line.ComboItems = comboItems
+	line.IsAvailable = true
+
+	return line, discountable
+}
+
+// getActiveCart retrieves a cart that can still be modified
+func getActiveCart(cartID string, access CartAccess) (*models.Cart, error) {
+	cart, err := GetCart(cartID, access)
+	if err != nil {
+		return nil, err
+	}
+	if cart.Status != models.CartStatusActive {
+		return nil, ErrCartNotActive
+	}
+	return cart, nil
+}
+
+// updateActiveCart applies an update to a cart, failing if the filter no longer matches
+func updateActiveCart(ctx context.Context, filter, update bson.M) error {
+	result, err := cartCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrCartNotActive
+	}
+	return nil
+}
+
+// validateCartFoodItem checks that a food item can be added to the cart
+func validateCartFoodItem(cart *models.Cart, foodItemID string) (*models.FoodItem, error) {
+	foodItem, err := GetFoodItemByID(foodItemID)
+	if err != nil {
+		return nil, err
+	}
+	if foodItem.StoreID != cart.StoreID {
+		return nil, fmt.Errorf("food item %s does not belong to this store", foodItemID)
+	}
+	if !foodItem.IsActive || !foodItem.IsAvailable {
+		return nil, fmt.Errorf("food item %s is not available", foodItem.Name)
+	}
+	if err := checkFoodItemSchedule(foodItem, time.Now()); err != nil {
+		return nil, err
+	}
+	return foodItem, nil
+}
+
+// validateCartCombo checks that a combo can be added to the cart
+func validateCartCombo(cart *models.Cart, comboID string) (*models.Combo, error) {
+	combo, err := GetComboByID(comboID)
+	if err != nil {
+		return nil, err
+	}
+	if combo.StoreID != cart.StoreID {
+		return nil, fmt.Errorf("combo %s does not belong to this store", comboID)
+	}
+	return combo, nil
+}
+
+// sameCartLine reports whether two cart lines are for the same item with the same choices and notes
+func sameCartLine(a, b models.CartItem) bool {
+	if a.FoodItemID != b.FoodItemID || a.ComboID != b.ComboID || a.Notes != b.Notes {
+		return false
+	}
+	if !sameModifierOptions(a.ModifierOptionIDs, b.ModifierOptionIDs) {
+		return false
+	}
+	if len(a.ComboSelections) != len(b.ComboSelections) {
+		return false
+	}
+	chosen := make(map[primitive.ObjectID]primitive.ObjectID, len(a.ComboSelections))
+	for _, selection := range a.ComboSelections {
+		chosen[selection.SlotID] = selection.FoodItemID
+	}
+	for _, selection := range b.ComboSelections {
+		if foodItemID, ok := chosen[selection.SlotID]; !ok || foodItemID != selection.FoodItemID {
+			return false
+		}
+	}
+	return true
+}
+
+// canAccessCart checks whether the caller owns the cart or holds its session token
+func canAccessCart(cart *models.Cart, access CartAccess) bool {
+	if !cart.CustomerID.IsZero() && cart.CustomerID == access.CustomerID {
+		return true
+	}
+	return access.Token != "" && utils.CompareTokenHash(access.Token, cart.SessionTokenHash)
+}
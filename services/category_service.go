@@ -16,6 +16,9 @@ import (
 
 var categoryCollection *mongo.Collection
 
+// ErrCategoryNotFound is returned when a category does not exist
+var ErrCategoryNotFound = errors.New("category not found")
+
 // InitCategoryCollection initializes the category collection
 func InitCategoryCollection() {
 	categoryCollection = config.GetCollection("categories")
@@ -37,6 +40,10 @@ func CreateCategory(req models.CreateCategoryRequest) (*models.Category, error)
 		return nil, errors.New("store not found")
 	}
 
+	if err := validateAvailability(req.Availability); err != nil {
+		return nil, err
+	}
+
 	category := &models.Category{
 		StoreID:      storeID,
 		Name:         req.Name,
@@ -44,6 +51,7 @@ func CreateCategory(req models.CreateCategoryRequest) (*models.Category, error)
 		Image:        req.Image,
 		DisplayOrder: req.DisplayOrder,
 		IsActive:     true,
+		Availability: req.Availability,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 	}
@@ -71,7 +79,7 @@ func GetCategoryByID(categoryID string) (*models.Category, error) {
 	err = categoryCollection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&category)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, errors.New("category not found")
+			return nil, ErrCategoryNotFound
 		}
 		return nil, err
 	}
@@ -131,7 +139,20 @@ func GetActiveCategoriesByStore(storeID string) ([]models.Category, error) {
 		return nil, err
 	}
 
-	return categories, nil
+	// Leave out categories outside their day-part schedule
+	schedule := newMenuSchedule(time.Now())
+	activeCategories := []models.Category{}
+	for i := range categories {
+		open, err := schedule.categoryOpen(&categories[i])
+		if err != nil {
+			return nil, err
+		}
+		if open {
+			activeCategories = append(activeCategories, categories[i])
+		}
+	}
+
+	return activeCategories, nil
 }
 
 // UpdateCategory updates an existing category
@@ -166,6 +187,12 @@ func UpdateCategory(categoryID string, req models.UpdateCategoryRequest) (*model
 	if req.IsActive != nil {
 		update["$set"].(bson.M)["is_active"] = *req.IsActive
 	}
+	if req.Availability != nil {
+		if err := validateAvailability(req.Availability); err != nil {
+			return nil, err
+		}
+		update["$set"].(bson.M)["availability"] = req.Availability
+	}
 
 	_, err = categoryCollection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
 	if err != nil {
@@ -191,8 +218,39 @@ func DeleteCategory(categoryID string) error {
 	}
 
 	if result.DeletedCount == 0 {
-		return errors.New("category not found")
+		return ErrCategoryNotFound
 	}
 
 	return nil
 }
+
+// SetCategoryModifierGroups replaces the modifier groups attached to a category.
+// These groups apply to every food item in the category.
+func SetCategoryModifierGroups(categoryID string, req models.AttachModifierGroupsRequest) (*models.Category, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	category, err := GetCategoryByID(categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	groupIDs, err := ResolveModifierGroupIDs(category.StoreID, req.ModifierGroupIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"modifier_group_ids": groupIDs,
+			"updated_at":         time.Now(),
+		},
+	}
+
+	_, err = categoryCollection.UpdateOne(ctx, bson.M{"_id": category.ID}, update)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetCategoryByID(categoryID)
+}
@@ -0,0 +1,508 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"ordernew/config"
+	"ordernew/models"
+	"ordernew/money"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var comboCollection *mongo.Collection
+
+// InitComboCollection initializes the combo collection
+func InitComboCollection() {
+	comboCollection = config.GetCollection("combos")
+}
+
+// CreateCombo creates a new combo
+func CreateCombo(req models.CreateComboRequest) (*models.Combo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	storeID, err := primitive.ObjectIDFromHex(req.StoreID)
+	if err != nil {
+		return nil, errors.New("invalid store ID")
+	}
+
+	// Verify store exists
+	_, err = GetStoreByID(req.StoreID)
+	if err != nil {
+		return nil, errors.New("store not found")
+	}
+
+	categoryID, err := resolveComboCategory(storeID, req.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	slots, err := buildComboSlots(storeID, req.Slots)
+	if err != nil {
+		return nil, err
+	}
+
+	combo := &models.Combo{
+		StoreID:      storeID,
+		CategoryID:   categoryID,
+		Name:         req.Name,
+		Description:  req.Description,
+		Price:        req.Price,
+		Image:        req.Image,
+		Slots:        slots,
+		IsAvailable:  true,
+		IsActive:     true,
+		DisplayOrder: req.DisplayOrder,
+		Tags:         req.Tags,
+		TaxCategory:  req.TaxCategory,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+
+	result, err := comboCollection.InsertOne(ctx, combo)
+	if err != nil {
+		return nil, err
+	}
+
+	combo.ID = result.InsertedID.(primitive.ObjectID)
+	return combo, nil
+}
+
+// GetComboByID retrieves a combo by ID
+func GetComboByID(comboID string) (*models.Combo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(comboID)
+	if err != nil {
+		return nil, errors.New("invalid combo ID")
+	}
+
+	var combo models.Combo
+	err = comboCollection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&combo)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("combo not found")
+		}
+		return nil, err
+	}
+
+	return &combo, nil
+}
+
+// GetCombosByStore retrieves all combos for a specific store
+func GetCombosByStore(storeID string) ([]models.Combo, error) {
+	objectID, err := primitive.ObjectIDFromHex(storeID)
+	if err != nil {
+		return nil, errors.New("invalid store ID")
+	}
+
+	return findCombos(bson.M{"store_id": objectID})
+}
+
+// GetCombosByCategory retrieves all combos listed in a specific category
+func GetCombosByCategory(categoryID string) ([]models.Combo, error) {
+	objectID, err := primitive.ObjectIDFromHex(categoryID)
+	if err != nil {
+		return nil, errors.New("invalid category ID")
+	}
+
+	return findCombos(bson.M{"category_id": objectID})
+}
+
+// UpdateCombo updates an existing combo
+func UpdateCombo(comboID string, req models.UpdateComboRequest) (*models.Combo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	combo, err := GetComboByID(comboID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build update document
+	update := bson.M{
+		"$set": bson.M{
+			"updated_at": time.Now(),
+		},
+	}
+
+	if req.CategoryID != "" {
+		categoryID, err := resolveComboCategory(combo.StoreID, req.CategoryID)
+		if err != nil {
+			return nil, err
+		}
+		update["$set"].(bson.M)["category_id"] = categoryID
+	}
+	if req.Name != "" {
+		update["$set"].(bson.M)["name"] = req.Name
+	}
+	if req.Description != "" {
+		update["$set"].(bson.M)["description"] = req.Description
+	}
+	if req.Price != nil {
+		update["$set"].(bson.M)["price"] = *req.Price
+	}
+	if req.Image != "" {
+		update["$set"].(bson.M)["image"] = req.Image
+	}
+	if req.Slots != nil {
+		slots, err := buildComboSlots(combo.StoreID, req.Slots)
+		if err != nil {
+			return nil, err
+		}
+		update["$set"].(bson.M)["slots"] = slots
+	}
+	if req.IsAvailable != nil {
+		update["$set"].(bson.M)["is_available"] = *req.IsAvailable
+	}
+	if req.IsActive != nil {
+		update["$set"].(bson.M)["is_active"] = *req.IsActive
+	}
+	if req.DisplayOrder != nil {
+		update["$set"].(bson.M)["display_order"] = *req.DisplayOrder
+	}
+	if req.Tags != nil {
+		update["$set"].(bson.M)["tags"] = req.Tags
+	}
+	if req.TaxCategory != nil {
+		update["$set"].(bson.M)["tax_category"] = *req.TaxCategory
+	}
+
+	_, err = comboCollection.UpdateOne(ctx, bson.M{"_id": combo.ID}, update)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetComboByID(comboID)
+}
+
+// DeleteCombo deletes a combo
+func DeleteCombo(comboID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(comboID)
+	if err != nil {
+		return errors.New("invalid combo ID")
+	}
+
+	result, err := comboCollection.DeleteOne(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return errors.New("combo not found")
+	}
+
+	return nil
+}
+
+// ToggleComboAvailability toggles the is_available status of a combo
+func ToggleComboAvailability(comboID string) (*models.Combo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	combo, err := GetComboByID(comboID)
+	if err != nil {
+		return nil, err
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"is_available": !combo.IsAvailable,
+			"updated_at":   time.Now(),
+		},
+	}
+
+	_, err = comboCollection.UpdateOne(ctx, bson.M{"_id": combo.ID}, update)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetComboByID(comboID)
+}
+
+// BuildComboResponses resolves the components of combos against the current menu.
+// With availableOnly set, combos that cannot be ordered right now are left out.
+func BuildComboResponses(combos []models.Combo, availableOnly bool) ([]models.ComboResponse, error) {
+	foodItems, err := getComboFoodItems(combos)
+	if err != nil {
+		return nil, err
+	}
+
+	// Customer-facing lists only offer what fits the day-part schedules now
+	schedule := newMenuSchedule(time.Now())
+	if availableOnly {
+		for id, foodItem := range foodItems {
+			open, err := schedule.foodItemOpen(&foodItem)
+			if err != nil {
+				return nil, err
+			}
+			if !open {
+				foodItem.IsAvailable = false
+				foodItems[id] = foodItem
+			}
+		}
+	}
+
+	responses := []models.ComboResponse{}
+	for _, combo := range combos {
+		response := combo.ToComboResponse(foodItems)
+		if availableOnly && !response.IsAvailable {
+			continue
+		}
+		if availableOnly {
+			open, err := schedule.categoryOpenByID(combo.CategoryID)
+			if err != nil {
+				return nil, err
+			}
+			if !open {
+				continue
+			}
+		}
+		responses = append(responses, response)
+	}
+
+	return responses, nil
+}
+
+// GetComboResponsesByStore retrieves the combos of a store with their components resolved
+func GetComboResponsesByStore(storeID string, availableOnly bool) ([]models.ComboResponse, error) {
+	combos, err := GetCombosByStore(storeID)
+	if err != nil {
+		return nil, err
+	}
+	return BuildComboResponses(combos, availableOnly)
+}
+
+// GetComboResponsesByCategory retrieves the combos of a category with their components resolved
+func GetComboResponsesByCategory(categoryID string, availableOnly bool) ([]models.ComboResponse, error) {
+	combos, err := GetCombosByCategory(categoryID)
+	if err != nil {
+		return nil, err
+	}
+	return BuildComboResponses(combos, availableOnly)
+}
+
+// PriceComboLine validates the slot choices for a combo and returns the unit price
+// (bundle price plus option deltas) with a snapshot of the chosen food items.
+// Slots with a single option are filled automatically when no choice is sent.
+func PriceComboLine(combo *models.Combo, selections []models.ComboSelection) (money.Amount, []models.OrderComboItem, error) {
+	if !combo.IsActive || !combo.IsAvailable {
+		return 0, nil, fmt.Errorf("combo %s is not available", combo.Name)
+	}
+
+	chosen := make(map[primitive.ObjectID]primitive.ObjectID, len(selections))
+	for _, selection := range selections {
+		if _, ok := chosen[selection.SlotID]; ok {
+			return 0, nil, errors.New("combo slot selected more than once")
+		}
+		chosen[selection.SlotID] = selection.FoodItemID
+	}
+
+	schedule := newMenuSchedule(time.Now())
+	if open, err := schedule.categoryOpenByID(combo.CategoryID); err != nil {
+		return 0, nil, err
+	} else if !open {
+		return 0, nil, fmt.Errorf("combo %s is not available at this time", combo.Name)
+	}
+
+	foodItems, err := getComboFoodItems([]models.Combo{*combo})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	unitPrice := combo.Price
+	comboItems := []models.OrderComboItem{}
+	for _, slot := range combo.Slots {
+		foodItemID, ok := chosen[slot.ID]
+		delete(chosen, slot.ID)
+		if !ok {
+			if len(slot.Options) == 1 && slot.IsRequired {
+				foodItemID = slot.Options[0].FoodItemID
+			} else if slot.IsRequired {
+				return 0, nil, fmt.Errorf("%s: choose an option", slot.Name)
+			} else {
+				continue
+			}
+		}
+
+		var option *models.ComboSlotOption
+		for i := range slot.Options {
+			if slot.Options[i].FoodItemID == foodItemID {
+				option = &slot.Options[i]
+				break
+			}
+		}
+		if option == nil {
+			return 0, nil, fmt.Errorf("%s: invalid option", slot.Name)
+		}
+
+		foodItem, ok := foodItems[foodItemID]
+		if !ok || !foodItem.IsActive || !foodItem.IsAvailable {
+			return 0, nil, fmt.Errorf("%s: option is not available", slot.Name)
+		}
+		if open, err := schedule.foodItemOpen(&foodItem); err != nil {
+			return 0, nil, err
+		} else if !open {
+			return 0, nil, fmt.Errorf("%s: option is not available at this time", slot.Name)
+		}
+
+		unitPrice += option.PriceDelta
+		comboItems = append(comboItems, models.OrderComboItem{
+			SlotID:     slot.ID,
+			SlotName:   slot.Name,
+			FoodItemID: foodItem.ID,
+			Name:       foodItem.Name,
+			PriceDelta: option.PriceDelta,
+		})
+	}
+
+	// Anything left over does not belong to this combo
+	if len(chosen) > 0 {
+		return 0, nil, fmt.Errorf("invalid combo slot for %s", combo.Name)
+	}
+	if unitPrice < 0 {
+		unitPrice = 0
+	}
+
+	return unitPrice, comboItems, nil
+}
+
+// ParseComboSelections converts combo slot choices from a request to ObjectIDs
+func ParseComboSelections(reqs []models.ComboSelectionRequest) ([]models.ComboSelection, error) {
+	selections := make([]models.ComboSelection, 0, len(reqs))
+	for _, req := range reqs {
+		slotID, err := primitive.ObjectIDFromHex(req.SlotID)
+		if err != nil {
+			return nil, errors.New("invalid combo slot ID")
+		}
+		foodItemID, err := primitive.ObjectIDFromHex(req.FoodItemID)
+		if err != nil {
+			return nil, errors.New("invalid food item ID")
+		}
+		selections = append(selections, models.ComboSelection{SlotID: slotID, FoodItemID: foodItemID})
+	}
+	return selections, nil
+}
+
+// findCombos retrieves the combos matching filter, ordered by display order
+func findCombos(filter bson.M) ([]models.Combo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "display_order", Value: 1}})
+	cursor, err := comboCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var combos []models.Combo
+	if err = cursor.All(ctx, &combos); err != nil {
+		return nil, err
+	}
+
+	return combos, nil
+}
+
+// getComboFoodItems loads every food item referenced by the slots of combos
+func getComboFoodItems(combos []models.Combo) (map[primitive.ObjectID]models.FoodItem, error) {
+	var ids []primitive.ObjectID
+	for _, combo := range combos {
+		for _, slot := range combo.Slots {
+			for _, option := range slot.Options {
+				ids = append(ids, option.FoodItemID)
+			}
+		}
+	}
+
+	foodItems := make(map[primitive.ObjectID]models.FoodItem)
+	if len(ids) == 0 {
+		return foodItems, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := foodItemCollection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var found []models.FoodItem
+	if err = cursor.All(ctx, &found); err != nil {
+		return nil, err
+	}
+	for _, foodItem := range found {
+		foodItems[foodItem.ID] = foodItem
+	}
+
+	return foodItems, nil
+}
+
+// resolveComboCategory parses an optional category ID and checks it belongs to the store
+func resolveComboCategory(storeID primitive.ObjectID, categoryID string) (primitive.ObjectID, error) {
+	if categoryID == "" {
+		return primitive.NilObjectID, nil
+	}
+
+	category, err := GetCategoryByID(categoryID)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	if category.StoreID != storeID {
+		return primitive.NilObjectID, errors.New("category does not belong to this store")
+	}
+
+	return category.ID, nil
+}
+
+// buildComboSlots converts slot requests to slots, checking every food item belongs to the store
+func buildComboSlots(storeID primitive.ObjectID, reqs []models.ComboSlotRequest) ([]models.ComboSlot, error) {
+	slots := make([]models.ComboSlot, 0, len(reqs))
+	for _, req := range reqs {
+		slot := models.ComboSlot{
+			ID:         primitive.NewObjectID(),
+			Name:       req.Name,
+			IsRequired: true,
+			Options:    make([]models.ComboSlotOption, 0, len(req.Options)),
+		}
+		if req.ID != "" {
+			slotID, err := primitive.ObjectIDFromHex(req.ID)
+			if err != nil {
+				return nil, errors.New("invalid combo slot ID")
+			}
+			slot.ID = slotID
+		}
+		if req.IsRequired != nil {
+			slot.IsRequired = *req.IsRequired
+		}
+
+		for _, optionReq := range req.Options {
+			foodItem, err := GetFoodItemByID(optionReq.FoodItemID)
+			if err != nil {
+				return nil, err
+			}
+			if foodItem.StoreID != storeID {
+				return nil, fmt.Errorf("food item %s does not belong to this store", optionReq.FoodItemID)
+			}
+			slot.Options = append(slot.Options, models.ComboSlotOption{
+				FoodItemID: foodItem.ID,
+				PriceDelta: optionReq.PriceDelta,
+			})
+		}
+		slots = append(slots, slot)
+	}
+	return slots, nil
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"math"
+	"sort"
+
+	"ordernew/models"
+	"ordernew/money"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// discountLine is a priced cart or order line that promotions can discount
+type discountLine struct {
+	CategoryID primitive.ObjectID
+	Tags       []string
+	Quantity   int
+	Total      money.Amount // line total before discounts
+}
+
+// discountResult is the outcome of applying promotions to the lines of a cart or order
+type discountResult struct {
+	LineDiscounts [][]models.LineDiscount // discounts of each line
+	Promotions    []models.AppliedPromotion
+	Total         money.Amount
+}
+
+// applyPromotions discounts lines with promotions, in the order given.
+// Promotions stack: each works on what the earlier ones left of a line, and no
+// line goes below zero. Promotions that take nothing off are left out.
+func applyPromotions(promotions []models.Promotion, lines []discountLine) discountResult {
+	result := discountResult{
+		LineDiscounts: make([][]models.LineDiscount, len(lines)),
+		Promotions:    []models.AppliedPromotion{},
+	}
+
+	remaining := make([]money.Amount, len(lines))
+	for i, line := range lines {
+		remaining[i] = line.Total
+	}
+
+	for _, promotion := range promotions {
+		var qualifying []int
+		var spend money.Amount
+		for i, line := range lines {
+			if lineQualifies(&promotion.Conditions, line) {
+				qualifying = append(qualifying, i)
+				spend += line.Total
+			}
+		}
+		if len(qualifying) == 0 || spend < promotion.Conditions.MinSubtotal {
+			continue
+		}
+
+		discounts := promotionDiscounts(&promotion.Effect, lines, remaining, qualifying)
+
+		var total money.Amount
+		for j, i := range qualifying {
+			amount := min(discounts[j], remaining[i])
+			if amount <= 0 {
+				continue
+			}
+			remaining[i] -= amount
+			total += amount
+			result.LineDiscounts[i] = append(result.LineDiscounts[i], models.LineDiscount{
+				PromotionID: promotion.ID,
+				Name:        promotion.Name,
+				Amount:      amount,
+			})
+		}
+		if total == 0 {
+			continue
+		}
+
+		result.Promotions = append(result.Promotions, models.AppliedPromotion{
+			PromotionID: promotion.ID,
+			Name:        promotion.Name,
+			Code:        promotion.Code,
+			Amount:      total,
+		})
+		result.Total += total
+	}
+
+	return result
+}
+
+// lineQualifies reports whether a line matches the category and tag conditions of a promotion
+func lineQualifies(conditions *models.PromotionConditions, line discountLine) bool {
+	if len(conditions.CategoryIDs) > 0 {
+		found := false
+		for _, id := range conditions.CategoryIDs {
+			if id == line.CategoryID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(conditions.Tags) > 0 {
+		found := false
+		for _, tag := range conditions.Tags {
+			if containsString(line.Tags, tag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// promotionDiscounts works out the discount of an effect on each qualifying
+// line, given what is left of the lines after earlier promotions
+func promotionDiscounts(effect *models.PromotionEffect, lines []discountLine, remaining []money.Amount, qualifying []int) []money.Amount {
+	discounts := make([]money.Amount, len(qualifying))
+
+	switch effect.Type {
+	case models.PromotionPercentOff:
+		for j, i := range qualifying {
+			discounts[j] = money.Amount(math.Round(float64(remaining[i]) * effect.Percent / 100))
+		}
+
+	case models.PromotionAmountOff:
+		// Spread the amount over the lines in proportion to what is left of them
+		var left money.Amount
+		for _, i := range qualifying {
+			left += remaining[i]
+		}
+		amount := min(effect.Amount, left)
+		if left == 0 {
+			return discounts
+		}
+		var spread money.Amount
+		for j, i := range qualifying {
+			if j == len(qualifying)-1 {
+				discounts[j] = amount - spread
+				break
+			}
+			discounts[j] = money.Amount(int64(amount) * int64(remaining[i]) / int64(left))
+			spread += discounts[j]
+		}
+
+	case models.PromotionPriceOverride:
+		for j, i := range qualifying {
+			discounts[j] = max(remaining[i]-effect.Amount.Times(lines[i].Quantity), 0)
+		}
+
+	case models.PromotionFreeItem:
+		// Sort the units from dearest to cheapest and group them: in every
+		// full group of buy + free units, the cheapest are free
+		type unit struct {
+			index int
+			price money.Amount
+		}
+		var units []unit
+		for j, i := range qualifying {
+			if lines[i].Quantity <= 0 {
+				continue
+			}
+			price := remaining[i] / money.Amount(lines[i].Quantity)
+			for q := 0; q < lines[i].Quantity; q++ {
+				units = append(units, unit{index: j, price: price})
+			}
+		}
+		sort.SliceStable(units, func(a, b int) bool { return units[a].price > units[b].price })
+
+		free := effect.FreeQuantity
+		if free <= 0 {
+			free = 1
+		}
+		group := effect.BuyQuantity + free
+		for start := 0; start+group <= len(units); start += group {
+			for _, u := range units[start+effect.BuyQuantity : start+group] {
+				discounts[u.index] += u.price
+			}
+		}
+	}
+
+	return discounts
+}
+
+// promoCodeApplied reports whether a promotion entered by code took something off
+func promoCodeApplied(applied []models.AppliedPromotion) bool {
+	for _, promotion := range applied {
+		if promotion.Code != "" {
+			return true
+		}
+	}
+	return false
+}
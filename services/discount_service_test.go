@@ -0,0 +1,188 @@
+package services
+
+import (
+	"testing"
+
+	"ordernew/models"
+	"ordernew/money"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPromotionDiscounts(t *testing.T) {
+	tests := []struct {
+		name   string
+		effect models.PromotionEffect
+		lines  []discountLine
+		want   []money.Amount
+	}{
+		{
+			name:   "percent off rounds each line",
+			effect: models.PromotionEffect{Type: models.PromotionPercentOff, Percent: 10},
+			lines:  []discountLine{{Quantity: 1, Total: 1005}, {Quantity: 2, Total: 2000}},
+			want:   []money.Amount{101, 200},
+		},
+		{
+			name:   "amount off is spread in proportion",
+			effect: models.PromotionEffect{Type: models.PromotionAmountOff, Amount: 500},
+			lines:  []discountLine{{Quantity: 1, Total: 1000}, {Quantity: 1, Total: 3000}},
+			want:   []money.Amount{125, 375},
+		},
+		{
+			name:   "amount off gives the rounding to the last line",
+			effect: models.PromotionEffect{Type: models.PromotionAmountOff, Amount: 100},
+			lines:  []discountLine{{Quantity: 1, Total: 333}, {Quantity: 1, Total: 333}, {Quantity: 1, Total: 334}},
+			want:   []money.Amount{33, 33, 34},
+		},
+		{
+			name:   "amount off is capped at the lines",
+			effect: models.PromotionEffect{Type: models.PromotionAmountOff, Amount: 5000},
+			lines:  []discountLine{{Quantity: 1, Total: 1000}, {Quantity: 1, Total: 500}},
+			want:   []money.Amount{1000, 500},
+		},
+		{
+			name:   "price override never raises a price",
+			effect: models.PromotionEffect{Type: models.PromotionPriceOverride, Amount: 500},
+			lines:  []discountLine{{Quantity: 2, Total: 1400}, {Quantity: 1, Total: 300}},
+			want:   []money.Amount{400, 0},
+		},
+		{
+			name:   "buy one get one frees the cheaper unit of each pair",
+			effect: models.PromotionEffect{Type: models.PromotionFreeItem, BuyQuantity: 1},
+			lines:  []discountLine{{Quantity: 3, Total: 3000}, {Quantity: 1, Total: 600}},
+			want:   []money.Amount{1000, 600},
+		},
+		{
+			name:   "buy two get one ignores an incomplete group",
+			effect: models.PromotionEffect{Type: models.PromotionFreeItem, BuyQuantity: 2, FreeQuantity: 1},
+			lines:  []discountLine{{Quantity: 5, Total: 1500}},
+			want:   []money.Amount{300},
+		},
+		{
+			name:   "buy one get two groups across lines",
+			effect: models.PromotionEffect{Type: models.PromotionFreeItem, BuyQuantity: 1, FreeQuantity: 2},
+			lines:  []discountLine{{Quantity: 1, Total: 900}, {Quantity: 2, Total: 800}, {Quantity: 0, Total: 0}},
+			want:   []money.Amount{0, 800, 0},
+		},
+		{
+			name:   "free item needs a full group",
+			effect: models.PromotionEffect{Type: models.PromotionFreeItem, BuyQuantity: 1},
+			lines:  []discountLine{{Quantity: 1, Total: 1000}},
+			want:   []money.Amount{0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remaining := make([]money.Amount, len(tt.lines))
+			qualifying := make([]int, len(tt.lines))
+			for i, line := range tt.lines {
+				remaining[i] = line.Total
+				qualifying[i] = i
+			}
+
+			got := promotionDiscounts(&tt.effect, tt.lines, remaining, qualifying)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyPromotions(t *testing.T) {
+	drinks := primitive.NewObjectID()
+	halfOff := models.Promotion{ID: primitive.NewObjectID(), Name: "Half off",
+		Effect: models.PromotionEffect{Type: models.PromotionPercentOff, Percent: 50}}
+	tenOff := models.Promotion{ID: primitive.NewObjectID(), Name: "10 off", Code: "TEN",
+		Effect: models.PromotionEffect{Type: models.PromotionAmountOff, Amount: 1000}}
+	tenPercent := models.Promotion{ID: primitive.NewObjectID(), Name: "10%",
+		Effect: models.PromotionEffect{Type: models.PromotionPercentOff, Percent: 10}}
+	bigSpend := models.Promotion{ID: primitive.NewObjectID(), Name: "Big spend",
+		Conditions: models.PromotionConditions{MinSubtotal: 2000},
+		Effect:     models.PromotionEffect{Type: models.PromotionAmountOff, Amount: 200}}
+	drinksOff := models.Promotion{ID: primitive.NewObjectID(), Name: "Drinks",
+		Conditions: models.PromotionConditions{CategoryIDs: []primitive.ObjectID{drinks}},
+		Effect:     models.PromotionEffect{Type: models.PromotionPercentOff, Percent: 10}}
+	happyHour := models.Promotion{ID: primitive.NewObjectID(), Name: "Happy hour",
+		Conditions: models.PromotionConditions{Tags: []string{"beer"}},
+		Effect:     models.PromotionEffect{Type: models.PromotionFreeItem, BuyQuantity: 1}}
+
+	tests := []struct {
+		name        string
+		promotions  []models.Promotion
+		lines       []discountLine
+		wantLines   []money.Amount // discount of each line
+		wantApplied []string
+		wantTotal   money.Amount
+	}{
+		{
+			name:        "stacked promotions floor the line at zero",
+			promotions:  []models.Promotion{halfOff, tenOff, tenPercent},
+			lines:       []discountLine{{Quantity: 1, Total: 1500}},
+			wantLines:   []money.Amount{1500},
+			wantApplied: []string{"Half off", "10 off"},
+			wantTotal:   1500,
+		},
+		{
+			name:        "later promotions work on what is left",
+			promotions:  []models.Promotion{halfOff, tenPercent},
+			lines:       []discountLine{{Quantity: 1, Total: 1000}, {Quantity: 1, Total: 555}},
+			wantLines:   []money.Amount{550, 306},
+			wantApplied: []string{"Half off", "10%"},
+			wantTotal:   856,
+		},
+		{
+			name:        "minimum spend not reached",
+			promotions:  []models.Promotion{bigSpend},
+			lines:       []discountLine{{Quantity: 1, Total: 1500}},
+			wantLines:   []money.Amount{0},
+			wantApplied: []string{},
+		},
+		{
+			name:        "only qualifying lines are discounted",
+			promotions:  []models.Promotion{drinksOff, happyHour},
+			lines:       []discountLine{{CategoryID: drinks, Quantity: 1, Total: 400}, {Tags: []string{"beer"}, Quantity: 2, Total: 1200}, {Quantity: 1, Total: 900}},
+			wantLines:   []money.Amount{40, 600, 0},
+			wantApplied: []string{"Drinks", "Happy hour"},
+			wantTotal:   640,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := applyPromotions(tt.promotions, tt.lines)
+
+			for i, discounts := range result.LineDiscounts {
+				var discount money.Amount
+				for _, d := range discounts {
+					discount += d.Amount
+				}
+				if discount != tt.wantLines[i] {
+					t.Errorf("line %d discount = %d, want %d", i, discount, tt.wantLines[i])
+				}
+				if discount > tt.lines[i].Total {
+					t.Errorf("line %d is discounted below zero", i)
+				}
+			}
+
+			if len(result.Promotions) != len(tt.wantApplied) {
+				t.Fatalf("applied %d promotions, want %v", len(result.Promotions), tt.wantApplied)
+			}
+			var total money.Amount
+			for i, applied := range result.Promotions {
+				if applied.Name != tt.wantApplied[i] {
+					t.Errorf("promotion %d = %q, want %q", i, applied.Name, tt.wantApplied[i])
+				}
+				total += applied.Amount
+			}
+			if result.Total != tt.wantTotal || total != tt.wantTotal {
+				t.Errorf("total = %d, promotions add up to %d, want %d", result.Total, total, tt.wantTotal)
+			}
+		})
+	}
+}
@@ -43,10 +43,17 @@ func CreateFoodItem(req models.CreateFoodItemRequest) (*models.FoodItem, error)
 	}
 
 	// Verify category exists
-	_, err = GetCategoryByID(req.CategoryID)
+	category, err := GetCategoryByID(req.CategoryID)
 	if err != nil {
 		return nil, errors.New("category not found")
 	}
+	if category.StoreID != storeID {
+		return nil, errors.New("category does not belong to this store")
+	}
+
+	if err := validateAvailability(req.Availability); err != nil {
+		return nil, err
+	}
 
 	foodItem := &models.FoodItem{
 		StoreID:      storeID,
@@ -61,6 +68,8 @@ func CreateFoodItem(req models.CreateFoodItemRequest) (*models.FoodItem, error)
 		PrepTime:     req.PrepTime,
 		DisplayOrder: req.DisplayOrder,
 		Tags:         req.Tags,
+		TaxCategory:  req.TaxCategory,
+		Availability: req.Availability,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 	}
@@ -172,7 +181,14 @@ func GetAvailableFoodItemsByStore(storeID string) ([]models.FoodItem, error) {
 		return nil, err
 	}
 
-	return foodItems, nil
+	categories, err := GetCategoriesByStore(storeID)
+	if err != nil {
+		return nil, err
+	}
+	schedule := newMenuSchedule(time.Now())
+	schedule.addCategories(categories)
+
+	return filterScheduledFoodItems(schedule, foodItems)
 }
 
 // GetAvailableFoodItemsByCategory retrieves all available food items for a specific category
@@ -201,7 +217,7 @@ func GetAvailableFoodItemsByCategory(categoryID string) ([]models.FoodItem, erro
 		return nil, err
 	}
 
-	return foodItems, nil
+	return filterScheduledFoodItems(newMenuSchedule(time.Now()), foodItems)
 }
 
 // UpdateFoodItem updates an existing food item
@@ -222,11 +238,18 @@ func UpdateFoodItem(foodItemID string, req models.UpdateFoodItemRequest) (*model
 	}
 
 	if req.CategoryID != "" {
-		categoryID, err := primitive.ObjectIDFromHex(req.CategoryID)
+		foodItem, err := GetFoodItemByID(foodItemID)
+		if err != nil {
+			return nil, err
+		}
+		category, err := GetCategoryByID(req.CategoryID)
 		if err != nil {
-			return nil, errors.New("invalid category ID")
+			return nil, err
 		}
-		update["$set"].(bson.M)["category_id"] = categoryID
+		if category.StoreID != foodItem.StoreID {
+			return nil, errors.New("category does not belong to this store")
+		}
+		update["$set"].(bson.M)["category_id"] = category.ID
 	}
 	if req.Name != "" {
 		update["$set"].(bson.M)["name"] = req.Name
@@ -258,6 +281,15 @@ func UpdateFoodItem(foodItemID string, req models.UpdateFoodItemRequest) (*model
 	if req.Tags != nil {
 		update["$set"].(bson.M)["tags"] = req.Tags
 	}
+	if req.TaxCategory != nil {
+		update["$set"].(bson.M)["tax_category"] = *req.TaxCategory
+	}
+	if req.Availability != nil {
+		if err := validateAvailability(req.Availability); err != nil {
+			return nil, err
+		}
+		update["$set"].(bson.M)["availability"] = req.Availability
+	}
 
 	_, err = foodItemCollection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
 	if err != nil {
@@ -320,3 +352,49 @@ func ToggleFoodItemAvailability(foodItemID string) (*models.FoodItem, error) {
 
 	return GetFoodItemByID(foodItemID)
 }
+
+// SetFoodItemModifierGroups replaces the modifier groups attached to a food item
+func SetFoodItemModifierGroups(foodItemID string, req models.AttachModifierGroupsRequest) (*models.FoodItem, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	foodItem, err := GetFoodItemByID(foodItemID)
+	if err != nil {
+		return nil, err
+	}
+
+	groupIDs, err := ResolveModifierGroupIDs(foodItem.StoreID, req.ModifierGroupIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"modifier_group_ids": groupIDs,
+			"updated_at":         time.Now(),
+		},
+	}
+
+	_, err = foodItemCollection.UpdateOne(ctx, bson.M{"_id": foodItem.ID}, update)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetFoodItemByID(foodItemID)
+}
+
+// filterScheduledFoodItems leaves out food items outside their own or their
+// category's day-part schedule
+func filterScheduledFoodItems(schedule *menuSchedule, foodItems []models.FoodItem) ([]models.FoodItem, error) {
+	available := []models.FoodItem{}
+	for i := range foodItems {
+		open, err := schedule.foodItemOpen(&foodItems[i])
+		if err != nil {
+			return nil, err
+		}
+		if open {
+			available = append(available, foodItems[i])
+		}
+	}
+	return available, nil
+}
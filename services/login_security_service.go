@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"ordernew/config"
+	"ordernew/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var loginEventCollection *mongo.Collection
+
+var (
+	// ErrAccountLocked is returned while an account is locked after failed logins
+	ErrAccountLocked = errors.New("account is temporarily locked after too many failed logins")
+	// ErrTooManyLoginAttempts is returned while an IP address is throttled after failed logins
+	ErrTooManyLoginAttempts = errors.New("too many failed logins from this address, please try again later")
+)
+
+// LoginThrottleError wraps ErrAccountLocked or ErrTooManyLoginAttempts with
+// how long the caller has to wait
+type LoginThrottleError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottleError) Error() string { return e.Err.Error() }
+
+func (e *LoginThrottleError) Unwrap() error { return e.Err }
+
+const (
+	loginLockThreshold  = 5           // failures before an account is locked
+	loginLockBase       = time.Minute // first lock; it doubles with every further failure
+	loginLockMax        = 24 * time.Hour
+	loginIPWindow       = 15 * time.Minute
+	loginIPMaxFailures  = 30 // failures per IP address per window
+	loginEventRetention = 90 * 24 * time.Hour
+	loginEventListLimit = 50
+)
+
+// InitLoginEventCollection initializes the login event collection
+func InitLoginEventCollection() {
+	loginEventCollection = config.GetCollection("login_events")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Events are kept for 90 days
+	_, err := loginEventCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "created_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(int32(loginEventRetention.Seconds()))},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "ip_address", Value: 1}, {Key: "success", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	if err != nil {
+		log.Println("Warning: failed to create login event indexes:", err)
+	}
+}
+
+// recordLoginEvent stores a login attempt. Failures to record are logged, not returned.
+func recordLoginEvent(event models.LoginEvent) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	event.CreatedAt = time.Now()
+	if _, err := loginEventCollection.InsertOne(ctx, event); err != nil {
+		log.Println("Warning: failed to record login event:", err)
+	}
+}
+
+// checkLoginIPThrottle rejects an IP address with too many recent failed logins
+func checkLoginIPThrottle(ipAddress string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"ip_address": ipAddress,
+		"success":    false,
+		"created_at": bson.M{"$gt": time.Now().Add(-loginIPWindow)},
+	}
+	count, err := loginEventCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if count < loginIPMaxFailures {
+		return nil
+	}
+
+	// Wait until the oldest failure in the window drops out of it
+	var oldest models.LoginEvent
+	err = loginEventCollection.FindOne(ctx, filter,
+		options.FindOne().SetSort(bson.D{{Key: "created_at", Value: 1}}),
+	).Decode(&oldest)
+	if err != nil {
+		return err
+	}
+	return &LoginThrottleError{Err: ErrTooManyLoginAttempts, RetryAfter: time.Until(oldest.CreatedAt.Add(loginIPWindow))}
+}
+
+// checkAccountLock rejects a user whose account is locked
+func checkAccountLock(user *models.User) error {
+	if user.LockedUntil != nil && user.LockedUntil.After(time.Now()) {
+		return &LoginThrottleError{Err: ErrAccountLocked, RetryAfter: time.Until(*user.LockedUntil)}
+	}
+	return nil
+}
+
+// registerLoginFailure counts a failed login and locks the account once the
+// failures reach the threshold, for twice as long with every further failure
+func registerLoginFailure(userID primitive.ObjectID) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var user models.User
+	err := userCollection.FindOneAndUpdate(ctx,
+		bson.M{"_id": userID},
+		bson.M{"$inc": bson.M{"failed_login_attempts": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&user)
+	if err != nil {
+		log.Println("Warning: failed to count failed login:", err)
+		return
+	}
+	if user.FailedLoginAttempts < loginLockThreshold {
+		return
+	}
+
+	lock := loginLockMax
+	if shift := user.FailedLoginAttempts - loginLockThreshold; shift < 16 {
+		lock = min(loginLockBase<<shift, loginLockMax)
+	}
+	_, err = userCollection.UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{"$set": bson.M{"locked_until": time.Now().Add(lock)}},
+	)
+	if err != nil {
+		log.Println("Warning: failed to lock account:", err)
+	}
+}
+
+// resetLoginFailures clears the failed login count after a successful login
+func resetLoginFailures(user *models.User) {
+	if user.FailedLoginAttempts == 0 && user.LockedUntil == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := userCollection.UpdateOne(ctx,
+		bson.M{"_id": user.ID},
+		bson.M{"$unset": bson.M{"failed_login_attempts": "", "locked_until": ""}},
+	)
+	if err != nil {
+		log.Println("Warning: failed to reset failed logins:", err)
+	}
+}
+
+// UnlockUser clears the lock and failed login count of a user
+func UnlockUser(userID string) (*models.UserResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	var user models.User
+	err = userCollection.FindOneAndUpdate(ctx,
+		bson.M{"_id": objectID},
+		bson.M{
+			"$unset": bson.M{"failed_login_attempts": "", "locked_until": ""},
+			"$set":   bson.M{"updated_at": time.Now()},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&user)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	response := user.ToUserResponse()
+	return &response, nil
+}
+
+// GetLoginEvents returns the most recent login attempts on a user's account
+func GetLoginEvents(userID primitive.ObjectID) ([]models.LoginEvent, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := loginEventCollection.Find(ctx,
+		bson.M{"user_id": userID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(loginEventListLimit),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	events := []models.LoginEvent{}
+	if err = cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"ordernew/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrInvalidAvailability is returned when a category or food item availability schedule cannot be used
+var ErrInvalidAvailability = errors.New("invalid availability schedule")
+
+// menuSchedule evaluates category and food item availability schedules at a
+// fixed moment, in the time zone of their store. Stores and categories are
+// loaded once and cached, so a whole menu can be filtered with a few queries.
+type menuSchedule struct {
+	now        time.Time
+	locations  map[primitive.ObjectID]*time.Location
+	categories map[primitive.ObjectID]*models.Category
+}
+
+// newMenuSchedule creates a schedule evaluator for the given moment
+func newMenuSchedule(now time.Time) *menuSchedule {
+	return &menuSchedule{
+		now:        now,
+		locations:  make(map[primitive.ObjectID]*time.Location),
+		categories: make(map[primitive.ObjectID]*models.Category),
+	}
+}
+
+// addCategories caches categories that were already loaded
+func (s *menuSchedule) addCategories(categories []models.Category) {
+	for i := range categories {
+		s.categories[categories[i].ID] = &categories[i]
+	}
+}
+
+// localTime returns the evaluated moment in the store's time zone
+func (s *menuSchedule) localTime(storeID primitive.ObjectID) (time.Time, error) {
+	location, ok := s.locations[storeID]
+	if !ok {
+		store, err := GetStoreByID(storeID.Hex())
+		if err != nil {
+			return time.Time{}, err
+		}
+		location = store.Location()
+		s.locations[storeID] = location
+	}
+	return s.now.In(location), nil
+}
+
+// categoryOpen reports whether a category's schedule allows ordering now
+func (s *menuSchedule) categoryOpen(category *models.Category) (bool, error) {
+	if len(category.Availability) == 0 {
+		return true, nil
+	}
+	local, err := s.localTime(category.StoreID)
+	if err != nil {
+		return false, err
+	}
+	return category.Availability.IsAvailableAt(local), nil
+}
+
+// categoryOpenByID is categoryOpen for a category referenced by ID.
+// A missing category places no restriction.
+func (s *menuSchedule) categoryOpenByID(categoryID primitive.ObjectID) (bool, error) {
+	if categoryID.IsZero() {
+		return true, nil
+	}
+	category, ok := s.categories[categoryID]
+	if !ok {
+		found, err := GetCategoryByID(categoryID.Hex())
+		if err != nil && !errors.Is(err, ErrCategoryNotFound) {
+			return false, err
+		}
+		category = found
+		s.categories[categoryID] = category
+	}
+	if category == nil {
+		return true, nil
+	}
+	return s.categoryOpen(category)
+}
+
+// foodItemOpen reports whether both a food item's schedule and its category's
+// schedule allow ordering now
+func (s *menuSchedule) foodItemOpen(foodItem *models.FoodItem) (bool, error) {
+	if len(foodItem.Availability) > 0 {
+		local, err := s.localTime(foodItem.StoreID)
+		if err != nil {
+			return false, err
+		}
+		if !foodItem.Availability.IsAvailableAt(local) {
+			return false, nil
+		}
+	}
+	return s.categoryOpenByID(foodItem.CategoryID)
+}
+
+// checkFoodItemSchedule returns an error when a food item cannot be ordered at this time
+func checkFoodItemSchedule(foodItem *models.FoodItem, now time.Time) error {
+	open, err := newMenuSchedule(now).foodItemOpen(foodItem)
+	if err != nil {
+		return err
+	}
+	if !open {
+		return fmt.Errorf("food item %s is not available at this time", foodItem.Name)
+	}
+	return nil
+}
+
+// validateAvailability checks the days and times of an availability schedule
+func validateAvailability(schedule models.AvailabilitySchedule) error {
+	for _, window := range schedule {
+		for _, day := range window.Days {
+			if !containsString(models.Weekdays, strings.ToLower(day)) {
+				return fmt.Errorf("%w: invalid day %q, expected one of %s", ErrInvalidAvailability, day, strings.Join(models.Weekdays, ", "))
+			}
+		}
+		if window.Start != "" {
+			start, err := models.ParseClock(window.Start)
+			if err != nil {
+				return fmt.Errorf("%w: start: %v", ErrInvalidAvailability, err)
+			}
+			if start == 24*60 {
+				return fmt.Errorf("%w: start cannot be 24:00", ErrInvalidAvailability)
+			}
+		}
+		if window.End != "" {
+			if _, err := models.ParseClock(window.End); err != nil {
+				return fmt.Errorf("%w: end: %v", ErrInvalidAvailability, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"ordernew/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetStoreMenu builds the nested customer menu of a store: active categories in
+// display order, each with its available food items and combos in display order
+func GetStoreMenu(store *models.Store) (*models.StoreMenu, error) {
+	storeID := store.ID.Hex()
+
+	categories, err := GetActiveCategoriesByStore(storeID)
+	if err != nil {
+		return nil, err
+	}
+
+	foodItems, err := GetAvailableFoodItemsByStore(storeID)
+	if err != nil {
+		return nil, err
+	}
+
+	combos, err := GetComboResponsesByStore(storeID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	menu := &models.StoreMenu{
+		Store:      store.ToStoreResponse(),
+		Categories: []models.MenuCategory{},
+		Combos:     []models.ComboResponse{},
+	}
+
+	// Categories are already sorted, so index them to keep that order
+	index := make(map[primitive.ObjectID]int, len(categories))
+	for i, category := range categories {
+		index[category.ID] = i
+		menu.Categories = append(menu.Categories, models.MenuCategory{
+			CategoryResponse: category.ToCategoryResponse(),
+			Items:            []models.FoodItemResponse{},
+			Combos:           []models.ComboResponse{},
+		})
+	}
+
+	// Items of inactive or off-schedule categories are left out
+	for _, foodItem := range foodItems {
+		if i, ok := index[foodItem.CategoryID]; ok {
+			menu.Categories[i].Items = append(menu.Categories[i].Items, foodItem.ToFoodItemResponse())
+		}
+	}
+
+	for _, combo := range combos {
+		if combo.CategoryID.IsZero() {
+			menu.Combos = append(menu.Combos, combo)
+			continue
+		}
+		if i, ok := index[combo.CategoryID]; ok {
+			menu.Categories[i].Combos = append(menu.Categories[i].Combos, combo)
+		}
+	}
+
+	return menu, nil
+}
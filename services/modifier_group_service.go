@@ -0,0 +1,432 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"ordernew/config"
+	"ordernew/models"
+	"ordernew/money"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var modifierGroupCollection *mongo.Collection
+
+// InitModifierGroupCollection initializes the modifier group collection
+func InitModifierGroupCollection() {
+	modifierGroupCollection = config.GetCollection("modifier_groups")
+}
+
+// CreateModifierGroup creates a new modifier group for a store
+func CreateModifierGroup(req models.CreateModifierGroupRequest) (*models.ModifierGroup, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	storeID, err := primitive.ObjectIDFromHex(req.StoreID)
+	if err != nil {
+		return nil, errors.New("invalid store ID")
+	}
+
+	// Verify store exists
+	_, err = GetStoreByID(req.StoreID)
+	if err != nil {
+		return nil, errors.New("store not found")
+	}
+
+	modifierOptions, err := buildModifierOptions(req.Options)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateSelectionRules(req.MinSelect, req.MaxSelect, len(modifierOptions)); err != nil {
+		return nil, err
+	}
+
+	group := &models.ModifierGroup{
+		StoreID:      storeID,
+		Name:         req.Name,
+		Description:  req.Description,
+		MinSelect:    req.MinSelect,
+		MaxSelect:    req.MaxSelect,
+		Options:      modifierOptions,
+		DisplayOrder: req.DisplayOrder,
+		IsActive:     true,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+
+	result, err := modifierGroupCollection.InsertOne(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+
+	group.ID = result.InsertedID.(primitive.ObjectID)
+	return group, nil
+}
+
+// GetModifierGroupByID retrieves a modifier group by ID
+func GetModifierGroupByID(groupID string) (*models.ModifierGroup, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(groupID)
+	if err != nil {
+		return nil, errors.New("invalid modifier group ID")
+	}
+
+	var group models.ModifierGroup
+	err = modifierGroupCollection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&group)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("modifier group not found")
+		}
+		return nil, err
+	}
+
+	return &group, nil
+}
+
+// GetModifierGroupsByStore retrieves all modifier groups for a specific store
+func GetModifierGroupsByStore(storeID string) ([]models.ModifierGroup, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(storeID)
+	if err != nil {
+		return nil, errors.New("invalid store ID")
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "display_order", Value: 1}})
+	cursor, err := modifierGroupCollection.Find(ctx, bson.M{"store_id": objectID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var groups []models.ModifierGroup
+	if err = cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+// GetModifierGroupsByIDs retrieves the given modifier groups, keeping the order of ids
+func GetModifierGroupsByIDs(ids []primitive.ObjectID) ([]models.ModifierGroup, error) {
+	if len(ids) == 0 {
+		return []models.ModifierGroup{}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := modifierGroupCollection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var found []models.ModifierGroup
+	if err = cursor.All(ctx, &found); err != nil {
+		return nil, err
+	}
+
+	byID := make(map[primitive.ObjectID]models.ModifierGroup, len(found))
+	for _, group := range found {
+		byID[group.ID] = group
+	}
+
+	groups := make([]models.ModifierGroup, 0, len(found))
+	for _, id := range ids {
+		if group, ok := byID[id]; ok {
+			groups = append(groups, group)
+		}
+	}
+
+	return groups, nil
+}
+
+// GetEffectiveModifierGroups returns the active modifier groups that apply to a food item:
+// the groups attached to its category followed by its own, without duplicates
+func GetEffectiveModifierGroups(foodItem *models.FoodItem) ([]models.ModifierGroup, error) {
+	var ids []primitive.ObjectID
+	seen := make(map[primitive.ObjectID]bool)
+
+	if category, err := GetCategoryByID(foodItem.CategoryID.Hex()); err == nil {
+		for _, id := range category.ModifierGroupIDs {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	for _, id := range foodItem.ModifierGroupIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	groups, err := GetModifierGroupsByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	active := make([]models.ModifierGroup, 0, len(groups))
+	for _, group := range groups {
+		if group.IsActive {
+			active = append(active, group)
+		}
+	}
+
+	return active, nil
+}
+
+// UpdateModifierGroup updates an existing modifier group
+func UpdateModifierGroup(groupID string, req models.UpdateModifierGroupRequest) (*models.ModifierGroup, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	group, err := GetModifierGroupByID(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build update document
+	update := bson.M{
+		"$set": bson.M{
+			"updated_at": time.Now(),
+		},
+	}
+
+	if req.Name != "" {
+		update["$set"].(bson.M)["name"] = req.Name
+	}
+	if req.Description != "" {
+		update["$set"].(bson.M)["description"] = req.Description
+	}
+	if req.MinSelect != nil {
+		group.MinSelect = *req.MinSelect
+		update["$set"].(bson.M)["min_select"] = *req.MinSelect
+	}
+	if req.MaxSelect != nil {
+		group.MaxSelect = *req.MaxSelect
+		update["$set"].(bson.M)["max_select"] = *req.MaxSelect
+	}
+	if req.Options != nil {
+		modifierOptions, err := buildModifierOptions(req.Options)
+		if err != nil {
+			return nil, err
+		}
+		group.Options = modifierOptions
+		update["$set"].(bson.M)["options"] = modifierOptions
+	}
+	if req.DisplayOrder != nil {
+		update["$set"].(bson.M)["display_order"] = *req.DisplayOrder
+	}
+	if req.IsActive != nil {
+		update["$set"].(bson.M)["is_active"] = *req.IsActive
+	}
+
+	if err := validateSelectionRules(group.MinSelect, group.MaxSelect, len(group.Options)); err != nil {
+		return nil, err
+	}
+
+	_, err = modifierGroupCollection.UpdateOne(ctx, bson.M{"_id": group.ID}, update)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetModifierGroupByID(groupID)
+}
+
+// DeleteModifierGroup deletes a modifier group and detaches it from food items and categories
+func DeleteModifierGroup(groupID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(groupID)
+	if err != nil {
+		return errors.New("invalid modifier group ID")
+	}
+
+	result, err := modifierGroupCollection.DeleteOne(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return errors.New("modifier group not found")
+	}
+
+	detach := bson.M{"$pull": bson.M{"modifier_group_ids": objectID}}
+	filter := bson.M{"modifier_group_ids": objectID}
+	if _, err := foodItemCollection.UpdateMany(ctx, filter, detach); err != nil {
+		return err
+	}
+	if _, err := categoryCollection.UpdateMany(ctx, filter, detach); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ResolveModifierGroupIDs parses modifier group IDs and checks they all belong to the store
+func ResolveModifierGroupIDs(storeID primitive.ObjectID, groupIDs []string) ([]primitive.ObjectID, error) {
+	ids := make([]primitive.ObjectID, 0, len(groupIDs))
+	seen := make(map[primitive.ObjectID]bool)
+	for _, groupID := range groupIDs {
+		objectID, err := primitive.ObjectIDFromHex(groupID)
+		if err != nil {
+			return nil, errors.New("invalid modifier group ID")
+		}
+		if !seen[objectID] {
+			seen[objectID] = true
+			ids = append(ids, objectID)
+		}
+	}
+
+	groups, err := GetModifierGroupsByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) != len(ids) {
+		return nil, errors.New("modifier group not found")
+	}
+	for _, group := range groups {
+		if group.StoreID != storeID {
+			return nil, fmt.Errorf("modifier group %s does not belong to this store", group.Name)
+		}
+	}
+
+	return ids, nil
+}
+
+// PriceFoodItemLine validates the modifier options chosen for a food item and returns the
+// unit price (base price plus option deltas) with a snapshot of the chosen options
+func PriceFoodItemLine(foodItem *models.FoodItem, optionIDs []primitive.ObjectID) (money.Amount, []models.OrderItemModifier, error) {
+	groups, err := GetEffectiveModifierGroups(foodItem)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	chosen := make(map[primitive.ObjectID]bool, len(optionIDs))
+	for _, id := range optionIDs {
+		if chosen[id] {
+			return 0, nil, errors.New("modifier option selected more than once")
+		}
+		chosen[id] = true
+	}
+
+	unitPrice := foodItem.Price
+	modifiers := []models.OrderItemModifier{}
+	for _, group := range groups {
+		count := 0
+		for _, option := range group.Options {
+			if !chosen[option.ID] {
+				continue
+			}
+			if !option.IsAvailable {
+				return 0, nil, fmt.Errorf("%s: %s is not available", group.Name, option.Name)
+			}
+			delete(chosen, option.ID)
+			count++
+			unitPrice += option.PriceDelta
+			modifiers = append(modifiers, models.OrderItemModifier{
+				GroupID:    group.ID,
+				GroupName:  group.Name,
+				OptionID:   option.ID,
+				OptionName: option.Name,
+				PriceDelta: option.PriceDelta,
+			})
+		}
+
+		if count < group.MinSelect {
+			return 0, nil, fmt.Errorf("%s: select at least %d option(s)", group.Name, group.MinSelect)
+		}
+		if group.MaxSelect > 0 && count > group.MaxSelect {
+			return 0, nil, fmt.Errorf("%s: select at most %d option(s)", group.Name, group.MaxSelect)
+		}
+	}
+
+	// Anything left over does not belong to this item's modifier groups
+	if len(chosen) > 0 {
+		return 0, nil, fmt.Errorf("invalid modifier option for %s", foodItem.Name)
+	}
+	if unitPrice < 0 {
+		unitPrice = 0
+	}
+
+	return unitPrice, modifiers, nil
+}
+
+// ParseModifierOptionIDs converts modifier option IDs from a request to ObjectIDs
+func ParseModifierOptionIDs(optionIDs []string) ([]primitive.ObjectID, error) {
+	ids := make([]primitive.ObjectID, 0, len(optionIDs))
+	for _, optionID := range optionIDs {
+		objectID, err := primitive.ObjectIDFromHex(optionID)
+		if err != nil {
+			return nil, errors.New("invalid modifier option ID")
+		}
+		ids = append(ids, objectID)
+	}
+	return ids, nil
+}
+
+// buildModifierOptions converts option requests to options, keeping IDs that were sent
+func buildModifierOptions(reqs []models.ModifierOptionRequest) ([]models.ModifierOption, error) {
+	modifierOptions := make([]models.ModifierOption, 0, len(reqs))
+	for _, req := range reqs {
+		option := models.ModifierOption{
+			ID:          primitive.NewObjectID(),
+			Name:        req.Name,
+			PriceDelta:  req.PriceDelta,
+			IsAvailable: true,
+		}
+		if req.ID != "" {
+			objectID, err := primitive.ObjectIDFromHex(req.ID)
+			if err != nil {
+				return nil, errors.New("invalid modifier option ID")
+			}
+			option.ID = objectID
+		}
+		if req.IsAvailable != nil {
+			option.IsAvailable = *req.IsAvailable
+		}
+		modifierOptions = append(modifierOptions, option)
+	}
+	return modifierOptions, nil
+}
+
+// validateSelectionRules checks that a group's min/max selection can be satisfied
+func validateSelectionRules(minSelect, maxSelect, optionCount int) error {
+	if maxSelect > 0 && minSelect > maxSelect {
+		return errors.New("min_select cannot be greater than max_select")
+	}
+	if minSelect > optionCount {
+		return errors.New("min_select cannot be greater than the number of options")
+	}
+	return nil
+}
+
+// sameModifierOptions reports whether two option selections contain the same options
+func sameModifierOptions(a, b []primitive.ObjectID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	set := make(map[primitive.ObjectID]bool, len(a))
+	for _, id := range a {
+		set[id] = true
+	}
+	for _, id := range b {
+		if !set[id] {
+			return false
+		}
+	}
+	return true
+}
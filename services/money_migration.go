@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"ordernew/config"
+	"ordernew/money"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// legacyAmountFields are the keys that held float amounts in major units
+// before prices were stored as integers in minor units
+var legacyAmountFields = map[string]bool{
+	"price":       true,
+	"price_delta": true,
+	"unit_price":  true,
+	"line_total":  true,
+	"subtotal":    true,
+	"total":       true,
+}
+
+// MigrateMoneyAmounts converts float prices in major units (12.99) to integer
+// minor units (1299) in the currency of their store. Stores without a currency
+// or locale get the defaults first, and orders get their store's currency. New
+// code never writes floats, so the migration only touches legacy documents and
+// is safe to run on every start.
+func MigrateMoneyAmounts() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	for field, value := range map[string]string{
+		"currency": config.AppConfig.DefaultCurrency,
+		"locale":   config.AppConfig.DefaultLocale,
+	} {
+		result, err := storeCollection.UpdateMany(ctx,
+			bson.M{field: bson.M{"$in": bson.A{nil, ""}}},
+			bson.M{"$set": bson.M{field: value}},
+		)
+		if err != nil {
+			return err
+		}
+		if result.ModifiedCount > 0 {
+			log.Printf("Set the default %s on %d stores", field, result.ModifiedCount)
+		}
+	}
+
+	storeCurrencies, err := loadStoreCurrencies(ctx)
+	if err != nil {
+		return err
+	}
+	storeCurrency := func(doc bson.M) string {
+		if storeID, ok := doc["store_id"].(primitive.ObjectID); ok {
+			if code, ok := storeCurrencies[storeID]; ok {
+				return code
+			}
+		}
+		return config.AppConfig.DefaultCurrency
+	}
+
+	migrations := []struct {
+		collection *mongo.Collection
+		paths      []string
+		currency   func(doc bson.M) string
+		setMissing bool // also store the currency on the document
+	}{
+		{foodItemCollection, []string{"price"}, storeCurrency, false},
+		{comboCollection, []string{"price", "slots.options.price_delta"}, storeCurrency, false},
+		{modifierGroupCollection, []string{"options.price_delta"}, storeCurrency, false},
+		{orderCollection, []string{"subtotal", "total", "items.price", "items.unit_price", "items.line_total", "items.modifiers.price_delta", "items.combo_items.price_delta"}, storeCurrency, true},
+		{productCollection, []string{"price"}, func(doc bson.M) string {
+			if code, ok := doc["currency"].(string); ok && code != "" {
+				return code
+			}
+			return config.AppConfig.DefaultCurrency
+		}, true},
+	}
+
+	for _, migration := range migrations {
+		filter := bson.A{}
+		for _, path := range migration.paths {
+			filter = append(filter, bson.M{path: bson.M{"$type": "double"}})
+		}
+		if migration.setMissing {
+			filter = append(filter, bson.M{"currency": bson.M{"$in": bson.A{nil, ""}}})
+		}
+
+		migrated, err := migrateLegacyAmounts(ctx, migration.collection, bson.M{"$or": filter}, migration.currency, migration.setMissing)
+		if err != nil {
+			return err
+		}
+		if migrated > 0 {
+			log.Printf("Converted prices to minor units in %d %s", migrated, migration.collection.Name())
+		}
+	}
+
+	return nil
+}
+
+// migrateLegacyAmounts converts the float amounts of the documents matching filter
+func migrateLegacyAmounts(ctx context.Context, collection *mongo.Collection, filter bson.M, currencyOf func(doc bson.M) string, setMissing bool) (int, error) {
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	migrated := 0
+	for cursor.Next(ctx) {
+		var doc bson.M
+		if err := cursor.Decode(&doc); err != nil {
+			return migrated, err
+		}
+
+		code := currencyOf(doc)
+		set := bson.M{}
+		for key, value := range doc {
+			if converted, changed := convertLegacyAmounts(key, value, code); changed {
+				set[key] = converted
+			}
+		}
+		if setMissing {
+			if current, _ := doc["currency"].(string); current == "" {
+				set["currency"] = code
+			}
+		}
+		if len(set) == 0 {
+			continue
+		}
+
+		if _, err := collection.UpdateOne(ctx, bson.M{"_id": doc["_id"]}, bson.M{"$set": set}); err != nil {
+			return migrated, err
+		}
+		migrated++
+	}
+
+	return migrated, cursor.Err()
+}
+
+// convertLegacyAmounts converts float amounts under key, including those
+// nested in sub-documents and arrays, and reports whether anything changed
+func convertLegacyAmounts(key string, value interface{}, code string) (interface{}, bool) {
+	switch v := value.(type) {
+	case float64:
+		if legacyAmountFields[key] {
+			return int64(money.FromMajor(v, code)), true
+		}
+	case bson.M:
+		changed := false
+		for childKey, child := range v {
+			if converted, ok := convertLegacyAmounts(childKey, child, code); ok {
+				v[childKey] = converted
+				changed = true
+			}
+		}
+		return v, changed
+	case bson.A:
+		changed := false
+		for i, elem := range v {
+			if converted, ok := convertLegacyAmounts(key, elem, code); ok {
+				v[i] = converted
+				changed = true
+			}
+		}
+		return v, changed
+	}
+	return value, false
+}
+
+// loadStoreCurrencies maps every store to its currency
+func loadStoreCurrencies(ctx context.Context) (map[primitive.ObjectID]string, error) {
+	cursor, err := storeCollection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	currencies := make(map[primitive.ObjectID]string)
+	for cursor.Next(ctx) {
+		var store struct {
+			ID       primitive.ObjectID `bson:"_id"`
+			Currency string             `bson:"currency"`
+		}
+		if err := cursor.Decode(&store); err != nil {
+			return nil, err
+		}
+		currencies[store.ID] = store.Currency
+	}
+
+	return currencies, cursor.Err()
+}
@@ -0,0 +1,538 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"ordernew/config"
+	"ordernew/models"
+	"ordernew/money"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var orderCollection *mongo.Collection
+
+var (
+	// ErrInvalidOrderTransition is returned when the requested status cannot follow the current one
+	ErrInvalidOrderTransition = errors.New("invalid order status transition")
+	// ErrOrderTransitionForbidden is returned when the caller may not perform the transition
+	ErrOrderTransitionForbidden = errors.New("you are not allowed to perform this status change")
+	// ErrOrderStatusConflict is returned when the order changed status concurrently
+	ErrOrderStatusConflict = errors.New("order status was changed by someone else, please retry")
+)
+
+// orderTransitions lists, for every status, the statuses it may move to and
+// which actors are allowed to make that move
+var orderTransitions = map[string]map[string][]string{
+	models.OrderStatusPlaced: {
+		models.OrderStatusAccepted:  {models.OrderActorStore},
+		models.OrderStatusRejected:  {models.OrderActorStore},
+		models.OrderStatusCancelled: {models.OrderActorStore, models.OrderActorCustomer},
+	},
+	models.OrderStatusAccepted: {
+		models.OrderStatusPreparing: {models.OrderActorStore},
+		models.OrderStatusCancelled: {models.OrderActorStore},
+	},
+	models.OrderStatusPreparing: {
+		models.OrderStatusReady:     {models.OrderActorStore},
+		models.OrderStatusCancelled: {models.OrderActorStore},
+	},
+	models.OrderStatusReady: {
+		models.OrderStatusServed:   {models.OrderActorStore},
+		models.OrderStatusPickedUp: {models.OrderActorStore},
+	},
+	models.OrderStatusServed: {
+		models.OrderStatusCompleted: {models.OrderActorStore, models.OrderActorPayment},
+	},
+	models.OrderStatusPickedUp: {
+		models.OrderStatusCompleted: {models.OrderActorStore, models.OrderActorPayment},
+	},
+}
+
+// paidOrderTransitions lists where an order moves on to once it is fully paid:
+// a paid order that was handed over is completed. Accepting or rejecting a paid
+// order stays with the store.
+var paidOrderTransitions = map[string]string{
+	models.OrderStatusServed:   models.OrderStatusCompleted,
+	models.OrderStatusPickedUp: models.OrderStatusCompleted,
+}
+
+// InitOrderCollection initializes the order collection
+func InitOrderCollection() {
+	orderCollection = config.GetCollection("orders")
+}
+
+// CreateOrder places a new order for a store.
+// customerID may be a zero ObjectID for anonymous orders.
+func CreateOrder(req models.CreateOrderRequest, customerID primitive.ObjectID) (*models.Order, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	storeID, err := primitive.ObjectIDFromHex(req.StoreID)
+	if err != nil {
+		return nil, errors.New("invalid store ID")
+	}
+
+	// Verify store can accept orders
+	store, err := GetStoreByID(req.StoreID)
+	if err != nil {
+		return nil, errors.New("store not found")
+	}
+	if !store.IsActive {
+		return nil, errors.New("store is not active")
+	}
+	if !store.IsOpen {
+		return nil, errors.New("store is currently closed")
+	}
+	if !store.IsWithinOpeningHours(time.Now()) {
+		return nil, errors.New("store is outside its opening hours")
+	}
+
+	orderType := req.OrderType
+	if orderType == "" {
+		orderType = models.OrderTypeDineIn
+	}
+
+	// Orders placed from a table QR code join the table's dining session
+	var table *models.Table
+	if req.TableToken != "" {
+		table, err = ResolveTableToken(req.TableToken)
+		if err != nil {
+			return nil, err
+		}
+		if table.StoreID != storeID {
+			return nil, errors.New("table does not belong to this store")
+		}
+		orderType = models.OrderTypeDineIn
+	}
+
+	// Build line items from the current menu
+	items := make([]models.OrderItem, 0, len(req.Items))
+	lines := make([]discountLine, 0, len(req.Items))
+	var subtotal money.Amount
+	for _, reqItem := range req.Items {
+		if reqItem.Quantity <= 0 {
+			return nil, errors.New("quantity must be greater than zero")
+		}
+
+		var item *models.OrderItem
+		var line discountLine
+		if reqItem.ComboID != "" {
+			item, line, err = buildComboOrderItem(storeID, reqItem)
+		} else {
+			item, line, err = buildFoodOrderItem(storeID, reqItem)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, *item)
+		lines = append(lines, line)
+		subtotal += item.LineTotal
+	}
+
+	// Automatic promotions apply first, then the promo code
+	promotions, err := findApplicablePromotions(store, req.PromoCode, customerID)
+	if err != nil {
+		return nil, err
+	}
+	discounts := applyPromotions(promotions, lines)
+	if req.PromoCode != "" && !promoCodeApplied(discounts.Promotions) {
+		return nil, ErrPromoCodeNotApplicable
+	}
+	for i := range items {
+		items[i].Discounts = discounts.LineDiscounts[i]
+		for _, discount := range discounts.LineDiscounts[i] {
+			items[i].Discount += discount.Amount
+		}
+	}
+
+	now := time.Now()
+	order := &models.Order{
+		StoreID:       storeID,
+		CustomerID:    customerID,
+		CustomerName:  req.CustomerName,
+		CustomerPhone: req.CustomerPhone,
+		OrderType:     orderType,
+		Items:         items,
+		Notes:         req.Notes,
+		Currency:      store.Currency,
+		Subtotal:      subtotal,
+		Discounts:     discounts.Promotions,
+		DiscountTotal: discounts.Total,
+		PaymentStatus: models.OrderPaymentUnpaid,
+		Status:        models.OrderStatusPlaced,
+		StatusHistory: []models.OrderStatusChange{
+			{
+				To:        models.OrderStatusPlaced,
+				ChangedBy: customerID,
+				Actor:     models.OrderActorCustomer,
+				ChangedAt: now,
+			},
+		},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if req.PromoCode != "" {
+		order.PromoCode, _ = normalizePromoCode(req.PromoCode)
+	}
+
+	applyOrderTaxes(order, store.Tax)
+
+	// Count the promotions against their usage limits before the order exists
+	if err := redeemPromotions(order.Discounts, promotions, customerID); err != nil {
+		return nil, err
+	}
+
+	// The table's session is only opened once the order is known to be valid
+	if table != nil {
+		session, err := OpenDiningSession(table)
+		if err != nil {
+			releasePromotions(order.Discounts, customerID)
+			return nil, err
+		}
+		order.TableID = table.ID
+		order.TableName = table.Name
+		order.SessionID = session.ID
+	}
+
+	result, err := orderCollection.InsertOne(ctx, order)
+	if err != nil {
+		releasePromotions(order.Discounts, customerID)
+		return nil, err
+	}
+
+	order.ID = result.InsertedID.(primitive.ObjectID)
+	return order, nil
+}
+
+// GetOrderByID retrieves an order by ID
+func GetOrderByID(orderID string) (*models.Order, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(orderID)
+	if err != nil {
+		return nil, errors.New("invalid order ID")
+	}
+
+	var order models.Order
+	err = orderCollection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&order)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("order not found")
+		}
+		return nil, err
+	}
+
+	return &order, nil
+}
+
+// GetOrdersByStore retrieves all orders for a specific store, newest first
+func GetOrdersByStore(storeID string) ([]models.Order, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(storeID)
+	if err != nil {
+		return nil, errors.New("invalid store ID")
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := orderCollection.Find(ctx, bson.M{"store_id": objectID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var orders []models.Order
+	if err = cursor.All(ctx, &orders); err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+// GetOrdersByCustomer retrieves all orders placed by a customer, newest first
+func GetOrdersByCustomer(customerID primitive.ObjectID) ([]models.Order, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := orderCollection.Find(ctx, bson.M{"customer_id": customerID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var orders []models.Order
+	if err = cursor.All(ctx, &orders); err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+// GetOrdersBySession retrieves all orders of a dining session, oldest first
+func GetOrdersBySession(sessionID primitive.ObjectID) ([]models.Order, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := orderCollection.Find(ctx, bson.M{"session_id": sessionID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var orders []models.Order
+	if err = cursor.All(ctx, &orders); err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+// UpdateOrderStatus moves an order to a new status, enforcing the order workflow
+// and recording the transition in the order's status history
+func UpdateOrderStatus(orderID string, req models.UpdateOrderStatusRequest, userID primitive.ObjectID, role string) (*models.Order, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	order, err := GetOrderByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	actor, err := resolveOrderActor(order, userID, role)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkOrderTransition(order, req.Status, actor); err != nil {
+		return nil, err
+	}
+
+	// An order on a split bill is taken off it, which only works while nothing was paid on the split
+	finishSplit := func(bool) {}
+	if req.Status == models.OrderStatusCancelled || req.Status == models.OrderStatusRejected {
+		finishSplit, err = prepareSplitForCancel(ctx, order)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	change := models.OrderStatusChange{
+		From:      order.Status,
+		To:        req.Status,
+		ChangedBy: userID,
+		Actor:     actor,
+		Reason:    req.Reason,
+		ChangedAt: now,
+	}
+
+	// Only apply the change if nobody moved the order in the meantime
+	filter := bson.M{"_id": order.ID, "status": order.Status}
+	update := bson.M{
+		"$set": bson.M{
+			"status":     req.Status,
+			"updated_at": now,
+		},
+		"$push": bson.M{
+			"status_history": change,
+		},
+	}
+
+	result, err := orderCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		finishSplit(false)
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		finishSplit(false)
+		return nil, ErrOrderStatusConflict
+	}
+	finishSplit(true)
+
+	// Orders that will not be fulfilled give their promotions back and release held payments
+	if req.Status == models.OrderStatusCancelled || req.Status == models.OrderStatusRejected {
+		releasePromotions(order.Discounts, order.CustomerID)
+		order.Status = req.Status
+		voidOrderPayments(order, userID)
+	}
+
+	// An order paid up front is completed as soon as it is handed over
+	if order.PaymentStatus == models.OrderPaymentPaid {
+		order.Status = req.Status
+		if err := advancePaidOrder(ctx, order); err != nil {
+			log.Println("Warning: failed to complete paid order:", err)
+		}
+	}
+
+	return GetOrderByID(orderID)
+}
+
+// advancePaidOrder moves a fully paid order to its next status, if the workflow
+// lets payment make that move. Orders the store has not handed over yet keep
+// their status.
+func advancePaidOrder(ctx context.Context, order *models.Order) error {
+	next, ok := paidOrderTransitions[order.Status]
+	if !ok || !containsString(orderTransitions[order.Status][next], models.OrderActorPayment) {
+		return nil
+	}
+
+	now := time.Now()
+	change := models.OrderStatusChange{
+		From:      order.Status,
+		To:        next,
+		Actor:     models.OrderActorPayment,
+		Reason:    "paid",
+		ChangedAt: now,
+	}
+
+	// Staff moving the order at the same time win; the payment does not retry
+	_, err := orderCollection.UpdateOne(ctx, bson.M{"_id": order.ID, "status": order.Status}, bson.M{
+		"$set":  bson.M{"status": next, "updated_at": now},
+		"$push": bson.M{"status_history": change},
+	})
+	return err
+}
+
+// checkOrderTransition checks that an order may move to a status and that the actor may make that move
+func checkOrderTransition(order *models.Order, status, actor string) error {
+	allowedActors, ok := orderTransitions[order.Status][status]
+	if !ok {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidOrderTransition, order.Status, status)
+	}
+	if status == models.OrderStatusServed && order.OrderType != models.OrderTypeDineIn {
+		return fmt.Errorf("%w: only dine-in orders can be served", ErrInvalidOrderTransition)
+	}
+	if status == models.OrderStatusPickedUp && order.OrderType != models.OrderTypeTakeaway {
+		return fmt.Errorf("%w: only takeaway orders can be picked up", ErrInvalidOrderTransition)
+	}
+	if !containsString(allowedActors, actor) {
+		return ErrOrderTransitionForbidden
+	}
+	return nil
+}
+
+// resolveOrderActor works out in which capacity a user acts on an order.
+// Store staff and admins act for the store; the customer who placed the order acts as customer.
+func resolveOrderActor(order *models.Order, userID primitive.ObjectID, role string) (string, error) {
+	store, err := GetStoreByID(order.StoreID.Hex())
+	if err != nil {
+		return "", err
+	}
+	if HasStorePermission(store, userID, role, models.PermissionOrdersUpdateStatus) {
+		return models.OrderActorStore, nil
+	}
+
+	if !order.CustomerID.IsZero() && order.CustomerID == userID {
+		return models.OrderActorCustomer, nil
+	}
+
+	return "", ErrOrderTransitionForbidden
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// buildFoodOrderItem prices a food item line of an order request
+func buildFoodOrderItem(storeID primitive.ObjectID, reqItem models.CreateOrderItemRequest) (*models.OrderItem, discountLine, error) {
+	foodItem, err := GetFoodItemByID(reqItem.FoodItemID)
+	if err != nil {
+		return nil, discountLine{}, err
+	}
+	if foodItem.StoreID != storeID {
+		return nil, discountLine{}, fmt.Errorf("food item %s does not belong to this store", reqItem.FoodItemID)
+	}
+	if !foodItem.IsActive || !foodItem.IsAvailable {
+		return nil, discountLine{}, fmt.Errorf("food item %s is not available", foodItem.Name)
+	}
+	if err := checkFoodItemSchedule(foodItem, time.Now()); err != nil {
+		return nil, discountLine{}, err
+	}
+
+	optionIDs, err := ParseModifierOptionIDs(reqItem.ModifierOptionIDs)
+	if err != nil {
+		return nil, discountLine{}, err
+	}
+	unitPrice, modifiers, err := PriceFoodItemLine(foodItem, optionIDs)
+	if err != nil {
+		return nil, discountLine{}, err
+	}
+
+	item := &models.OrderItem{
+		FoodItemID:  foodItem.ID,
+		Name:        foodItem.Name,
+		Price:       foodItem.Price,
+		Modifiers:   modifiers,
+		UnitPrice:   unitPrice,
+		Quantity:    reqItem.Quantity,
+		LineTotal:   unitPrice.Times(reqItem.Quantity),
+		TaxCategory: foodItem.TaxCategory,
+		Notes:       reqItem.Notes,
+	}
+	line := discountLine{
+		CategoryID: foodItem.CategoryID,
+		Tags:       foodItem.Tags,
+		Quantity:   item.Quantity,
+		Total:      item.LineTotal,
+	}
+	return item, line, nil
+}
+
+// buildComboOrderItem prices a combo line of an order request
+func buildComboOrderItem(storeID primitive.ObjectID, reqItem models.CreateOrderItemRequest) (*models.OrderItem, discountLine, error) {
+	combo, err := GetComboByID(reqItem.ComboID)
+	if err != nil {
+		return nil, discountLine{}, err
+	}
+	if combo.StoreID != storeID {
+		return nil, discountLine{}, fmt.Errorf("combo %s does not belong to this store", reqItem.ComboID)
+	}
+
+	selections, err := ParseComboSelections(reqItem.ComboSelections)
+	if err != nil {
+		return nil, discountLine{}, err
+	}
+	unitPrice, comboItems, err := PriceComboLine(combo, selections)
+	if err != nil {
+		return nil, discountLine{}, err
+	}
+
+	item := &models.OrderItem{
+		ComboID:     combo.ID,
+		Name:        combo.Name,
+		Price:       combo.Price,
+		ComboItems:  comboItems,
+		UnitPrice:   unitPrice,
+		Quantity:    reqItem.Quantity,
+		LineTotal:   unitPrice.Times(reqItem.Quantity),
+		TaxCategory: combo.TaxCategory,
+		Notes:       reqItem.Notes,
+	}
+	line := discountLine{
+		CategoryID: combo.CategoryID,
+		Tags:       combo.Tags,
+		Quantity:   item.Quantity,
+		Total:      item.LineTotal,
+	}
+	return item, line, nil
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"ordernew/models"
+)
+
+func TestCheckOrderTransition(t *testing.T) {
+	const (
+		store    = models.OrderActorStore
+		customer = models.OrderActorCustomer
+		payment  = models.OrderActorPayment
+	)
+
+	tests := []struct {
+		name      string
+		from      string
+		orderType string
+		to        string
+		actor     string
+		wantErr   error
+	}{
+		{"store accepts", models.OrderStatusPlaced, models.OrderTypeDineIn, models.OrderStatusAccepted, store, nil},
+		{"payment cannot accept", models.OrderStatusPlaced, models.OrderTypeDineIn, models.OrderStatusAccepted, payment, ErrOrderTransitionForbidden},
+		{"customer cannot accept", models.OrderStatusPlaced, models.OrderTypeDineIn, models.OrderStatusAccepted, customer, ErrOrderTransitionForbidden},
+		{"store rejects", models.OrderStatusPlaced, models.OrderTypeTakeaway, models.OrderStatusRejected, store, nil},
+		{"customer cannot reject", models.OrderStatusPlaced, models.OrderTypeTakeaway, models.OrderStatusRejected, customer, ErrOrderTransitionForbidden},
+		{"customer cancels before acceptance", models.OrderStatusPlaced, models.OrderTypeDineIn, models.OrderStatusCancelled, customer, nil},
+		{"customer cannot cancel once accepted", models.OrderStatusAccepted, models.OrderTypeDineIn, models.OrderStatusCancelled, customer, ErrOrderTransitionForbidden},
+		{"store cancels while preparing", models.OrderStatusPreparing, models.OrderTypeDineIn, models.OrderStatusCancelled, store, nil},
+		{"ready cannot be cancelled", models.OrderStatusReady, models.OrderTypeDineIn, models.OrderStatusCancelled, store, ErrInvalidOrderTransition},
+		{"cannot skip preparing", models.OrderStatusAccepted, models.OrderTypeDineIn, models.OrderStatusReady, store, ErrInvalidOrderTransition},
+		{"cannot go back", models.OrderStatusPreparing, models.OrderTypeDineIn, models.OrderStatusAccepted, store, ErrInvalidOrderTransition},
+		{"dine-in order is served", models.OrderStatusReady, models.OrderTypeDineIn, models.OrderStatusServed, store, nil},
+		{"takeaway order is not served", models.OrderStatusReady, models.OrderTypeTakeaway, models.OrderStatusServed, store, ErrInvalidOrderTransition},
+		{"takeaway order is picked up", models.OrderStatusReady, models.OrderTypeTakeaway, models.OrderStatusPickedUp, store, nil},
+		{"dine-in order is not picked up", models.OrderStatusReady, models.OrderTypeDineIn, models.OrderStatusPickedUp, store, ErrInvalidOrderTransition},
+		{"payment completes a served order", models.OrderStatusServed, models.OrderTypeDineIn, models.OrderStatusCompleted, payment, nil},
+		{"payment completes a picked up order", models.OrderStatusPickedUp, models.OrderTypeTakeaway, models.OrderStatusCompleted, payment, nil},
+		{"payment cannot start preparing", models.OrderStatusAccepted, models.OrderTypeDineIn, models.OrderStatusPreparing, payment, ErrOrderTransitionForbidden},
+		{"completed is final", models.OrderStatusCompleted, models.OrderTypeDineIn, models.OrderStatusCancelled, store, ErrInvalidOrderTransition},
+		{"cancelled is final", models.OrderStatusCancelled, models.OrderTypeDineIn, models.OrderStatusPlaced, store, ErrInvalidOrderTransition},
+		{"rejected is final", models.OrderStatusRejected, models.OrderTypeDineIn, models.OrderStatusAccepted, store, ErrInvalidOrderTransition},
+		{"unknown status", models.OrderStatusPlaced, models.OrderTypeDineIn, "shipped", store, ErrInvalidOrderTransition},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := &models.Order{Status: tt.from, OrderType: tt.orderType}
+			err := checkOrderTransition(order, tt.to, tt.actor)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("checkOrderTransition(%s -> %s by %s) = %v, want nil", tt.from, tt.to, tt.actor, err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("checkOrderTransition(%s -> %s by %s) = %v, want %v", tt.from, tt.to, tt.actor, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPaidOrderTransitionsAreAllowed(t *testing.T) {
+	for from, to := range paidOrderTransitions {
+		if !containsString(orderTransitions[from][to], models.OrderActorPayment) {
+			t.Errorf("a paid order moves %s -> %s, but payments may not make that move", from, to)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"ordernew/config"
+	"ordernew/models"
+	"ordernew/sms"
+	"ordernew/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var otpCollection *mongo.Collection
+
+// smsSender delivers login codes; it is set by InitSMSSender
+var smsSender sms.Sender
+
+var (
+	// ErrOTPRateLimited is returned when too many codes were requested
+	ErrOTPRateLimited = errors.New("too many code requests, please try again later")
+	// ErrInvalidOTP is returned for wrong, expired or already used codes
+	ErrInvalidOTP = errors.New("invalid or expired code")
+	// ErrOTPTooManyAttempts is returned once a code has been guessed too often
+	ErrOTPTooManyAttempts = errors.New("too many wrong attempts, please request a new code")
+	// ErrPhoneInUse is returned when linking a phone number that belongs to another account
+	ErrPhoneInUse = errors.New("phone number is already used by another account")
+)
+
+const (
+	otpCodeLength     = 6
+	otpTTL            = 5 * time.Minute
+	otpMaxAttempts    = 5
+	otpResendCooldown = time.Minute
+	otpRateWindow     = time.Hour
+	otpMaxPerPhone    = 5  // codes per phone number per window
+	otpMaxPerIP       = 20 // codes per IP address per window
+)
+
+// InitOTPCollection initializes the OTP code collection
+func InitOTPCollection() {
+	otpCollection = config.GetCollection("otp_codes")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Codes are kept for the rate limit window, then removed by MongoDB
+	_, err := otpCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "created_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(int32(otpRateWindow.Seconds()))},
+		{Keys: bson.D{{Key: "phone", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "ip_address", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	if err != nil {
+		log.Println("Warning: failed to create OTP code indexes:", err)
+	}
+}
+
+// InitSMSSender sets up the SMS sender configured by SMS_DRIVER
+func InitSMSSender() error {
+	sender, err := sms.New()
+	if err != nil {
+		return err
+	}
+	smsSender = sender
+	return nil
+}
+
+// RequestOTP texts a login code to a phone number. It returns how long the
+// caller has to wait before requesting another code, which is also the wait
+// when the request is rate limited.
+func RequestOTP(phone, ipAddress string) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	if retryAfter, err := otpRetryAfter(ctx, phone, ipAddress, now); err != nil || retryAfter > 0 {
+		if err != nil {
+			return 0, err
+		}
+		return retryAfter, ErrOTPRateLimited
+	}
+
+	code, err := utils.GenerateNumericCode(otpCodeLength)
+	if err != nil {
+		return 0, errors.New("failed to generate code")
+	}
+
+	result, err := otpCollection.InsertOne(ctx, models.OTPCode{
+		Phone:     phone,
+		CodeHash:  utils.HashOTP(phone, code),
+		IPAddress: ipAddress,
+		ExpiresAt: now.Add(otpTTL),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := smsSender.Send(phone, "Your login code is "+code+". It expires in 5 minutes."); err != nil {
+		// Let the caller retry straight away when the message never went out
+		otpCollection.DeleteOne(ctx, bson.M{"_id": result.InsertedID})
+		return 0, errors.New("failed to send code")
+	}
+
+	return otpResendCooldown, nil
+}
+
+// otpRetryAfter returns how long a phone number or IP address must wait
+// before requesting a code, or 0 if it may request one now
+func otpRetryAfter(ctx context.Context, phone, ipAddress string, now time.Time) (time.Duration, error) {
+	var last models.OTPCode
+	err := otpCollection.FindOne(ctx, bson.M{"phone": phone},
+		options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}}),
+	).Decode(&last)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return 0, err
+	}
+	if err == nil && now.Sub(last.CreatedAt) < otpResendCooldown {
+		return otpResendCooldown - now.Sub(last.CreatedAt), nil
+	}
+
+	limits := []struct {
+		filter bson.M
+		max    int64
+	}{
+		{bson.M{"phone": phone}, otpMaxPerPhone},
+		{bson.M{"ip_address": ipAddress}, otpMaxPerIP},
+	}
+	for _, limit := range limits {
+		limit.filter["created_at"] = bson.M{"$gt": now.Add(-otpRateWindow)}
+		count, err := otpCollection.CountDocuments(ctx, limit.filter)
+		if err != nil {
+			return 0, err
+		}
+		if count < limit.max {
+			continue
+		}
+
+		// Wait until the oldest code in the window drops out of it
+		var oldest models.OTPCode
+		err = otpCollection.FindOne(ctx, limit.filter,
+			options.FindOne().SetSort(bson.D{{Key: "created_at", Value: 1}}),
+		).Decode(&oldest)
+		if err != nil {
+			return 0, err
+		}
+		return oldest.CreatedAt.Add(otpRateWindow).Sub(now), nil
+	}
+
+	return 0, nil
+}
+
+// VerifyOTP redeems a login code and starts a session for the user with that
+// phone number, creating the user if there is none. When linkUserID is set the
+// phone number is added to that user instead. Users with two-factor
+// authentication get a challenge instead of a session.
+func VerifyOTP(phone, code string, linkUserID primitive.ObjectID, userAgent, ipAddress string) (*models.LoginResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Only the latest code counts, and every try uses up an attempt
+	now := time.Now()
+	var otp models.OTPCode
+	err := otpCollection.FindOneAndUpdate(ctx,
+		bson.M{"phone": phone, "consumed_at": bson.M{"$exists": false}, "expires_at": bson.M{"$gt": now}},
+		bson.M{"$inc": bson.M{"attempts": 1}},
+		options.FindOneAndUpdate().
+			SetSort(bson.D{{Key: "created_at", Value: -1}}).
+			SetReturnDocument(options.After),
+	).Decode(&otp)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrInvalidOTP
+		}
+		return nil, err
+	}
+	if otp.Attempts > otpMaxAttempts {
+		return nil, ErrOTPTooManyAttempts
+	}
+	if !utils.CompareOTPHash(phone, code, otp.CodeHash) {
+		recordLoginEvent(models.LoginEvent{
+			Phone:         phone,
+			Method:        models.LoginMethodOTP,
+			FailureReason: models.LoginFailureInvalidCode,
+			IPAddress:     ipAddress,
+			UserAgent:     userAgent,
+		})
+		return nil, ErrInvalidOTP
+	}
+
+	result, err := otpCollection.UpdateOne(ctx,
+		bson.M{"_id": otp.ID, "consumed_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"consumed_at": now}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, ErrInvalidOTP
+	}
+
+	var user *models.User
+	if linkUserID.IsZero() {
+		user, err = findOrCreatePhoneUser(ctx, phone)
+	} else {
+		user, err = linkPhoneToUser(ctx, linkUserID, phone)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return startLogin(user, models.LoginMethodOTP, userAgent, ipAddress)
+}
+
+// findOrCreatePhoneUser returns the active user with a phone number, creating a customer account if there is none
+func findOrCreatePhoneUser(ctx context.Context, phone string) (*models.User, error) {
+	var user models.User
+	err := userCollection.FindOne(ctx, bson.M{"phone": phone}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		user = models.User{
+			ID:        primitive.NewObjectID(),
+			Phone:     phone,
+			Role:      models.RoleUser,
+			IsActive:  true,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}
+		_, err = userCollection.InsertOne(ctx, user)
+		if mongo.IsDuplicateKeyError(err) {
+			// Created by a concurrent login with the same number
+			err = userCollection.FindOne(ctx, bson.M{"phone": phone}).Decode(&user)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, ErrUserInactive
+	}
+
+	return &user, nil
+}
+
+// linkPhoneToUser sets a verified phone number on an existing user
+func linkPhoneToUser(ctx context.Context, userID primitive.ObjectID, phone string) (*models.User, error) {
+	var owner models.User
+	err := userCollection.FindOne(ctx, bson.M{"phone": phone}).Decode(&owner)
+	if err == nil && owner.ID != userID {
+		return nil, ErrPhoneInUse
+	}
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	_, err = userCollection.UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{"$set": bson.M{"phone": phone, "updated_at": time.Now()}},
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, ErrPhoneInUse
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return getActiveUser(userID)
+}
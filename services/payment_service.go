@@ -0,0 +1,740 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"ordernew/config"
+	"ordernew/models"
+	"ordernew/money"
+	"ordernew/payments"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var paymentCollection *mongo.Collection
+var paymentWebhookCollection *mongo.Collection
+var paymentProvider payments.Provider
+
+// paymentWebhookRetention is how long processed webhook IDs are kept to
+// recognise redeliveries; gateways stop retrying well before that
+const paymentWebhookRetention = 30 * 24 * time.Hour
+
+// paymentLockTimeout bounds how long a request that never finishes keeps an order
+// or sub-bill locked for new payments
+const paymentLockTimeout = 30 * time.Second
+
+var (
+	// ErrPaymentNotAllowed is returned when the caller may not take or change a payment
+	ErrPaymentNotAllowed = errors.New("you are not allowed to perform this payment operation")
+	// ErrOrderNotPayable is returned when paying an order that was cancelled or rejected,
+	// an order paid through sub-bills, or a sub-bill of a closed dining session
+	ErrOrderNotPayable = errors.New("order cannot be paid")
+	// ErrInvalidPaymentAmount is returned for an amount above what is due, capturable or refundable
+	ErrInvalidPaymentAmount = errors.New("invalid payment amount")
+	// ErrPaymentStateConflict is returned when a payment is not in a state that allows the operation
+	ErrPaymentStateConflict = errors.New("payment cannot be changed in its current state")
+	// ErrSandboxUnavailable is returned when simulating a payment without the mock provider
+	ErrSandboxUnavailable = errors.New("payment sandbox is only available with PAYMENT_PROVIDER=mock")
+)
+
+// InitPaymentCollection initializes the payment and payment webhook collections
+func InitPaymentCollection() {
+	paymentCollection = config.GetCollection("payments")
+	paymentWebhookCollection = config.GetCollection("payment_webhook_events")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := paymentCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "order_id", Value: 1}, {Key: "created_at", Value: 1}}},
+		{Keys: bson.D{{Key: "sub_bill_id", Value: 1}, {Key: "created_at", Value: 1}}},
+		{
+			Keys: bson.D{{Key: "provider", Value: 1}, {Key: "provider_payment_id", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"provider_payment_id": bson.M{"$type": "string"}}),
+		},
+	})
+	if err != nil {
+		log.Println("Warning: failed to create payment indexes:", err)
+	}
+
+	// A webhook is processed once per provider event
+	_, err = paymentWebhookCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "provider", Value: 1}, {Key: "event_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "processed_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(int32(paymentWebhookRetention.Seconds()))},
+	})
+	if err != nil {
+		log.Println("Warning: failed to create payment webhook indexes:", err)
+	}
+}
+
+// InitPaymentProvider sets up the payment provider configured by PAYMENT_PROVIDER
+func InitPaymentProvider() error {
+	provider, err := payments.New()
+	if err != nil {
+		return err
+	}
+	paymentProvider = provider
+	return nil
+}
+
+// CreatePayment starts paying an order, in full or in part. Customers pay their own
+// orders by card or at the counter; store staff may also record cash, which is captured
+// at once. For card payments the provider's client secret is returned as well.
+func CreatePayment(orderID string, req models.CreatePaymentRequest, userID primitive.ObjectID, role string) (*models.Payment, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	order, err := GetOrderByID(orderID)
+	if err != nil {
+		return nil, "", err
+	}
+	if order.Status == models.OrderStatusCancelled || order.Status == models.OrderStatusRejected {
+		return nil, "", fmt.Errorf("%w: the order is %s", ErrOrderNotPayable, order.Status)
+	}
+
+	store, err := GetStoreByID(order.StoreID.Hex())
+	if err != nil {
+		return nil, "", err
+	}
+	isStaff := HasStorePermission(store, userID, role, models.PermissionPaymentsManage)
+	isCustomer := !order.CustomerID.IsZero() && order.CustomerID == userID
+	if !isStaff && (!isCustomer || req.Method == models.PaymentMethodCash) {
+		return nil, "", ErrPaymentNotAllowed
+	}
+
+	// Orders at a table lock their dining session, so the bill cannot be split meanwhile
+	lockCollection, lockID := orderCollection, order.ID
+	if !order.SessionID.IsZero() {
+		lockCollection, lockID = diningSessionCollection, order.SessionID
+	}
+	unlock, err := lockForPayment(ctx, lockCollection, lockID)
+	if err != nil {
+		return nil, "", err
+	}
+	defer unlock()
+
+	if !order.SessionID.IsZero() {
+		bills, err := findSubBills(ctx, order.SessionID)
+		if err != nil {
+			return nil, "", err
+		}
+		if len(bills) > 0 && bills[0].CoversOrder(order.ID) {
+			return nil, "", fmt.Errorf("%w: the table's bill is split, pay its sub-bills instead", ErrOrderNotPayable)
+		}
+	}
+
+	due, err := orderAmountDue(ctx, order)
+	if err != nil {
+		return nil, "", err
+	}
+	amount, err := paymentAmount(req.Amount, due, order.Currency)
+	if err != nil {
+		return nil, "", err
+	}
+
+	payment := &models.Payment{
+		OrderID:    order.ID,
+		StoreID:    order.StoreID,
+		CustomerID: order.CustomerID,
+		Method:     req.Method,
+		Currency:   order.Currency,
+		Amount:     amount,
+	}
+	clientSecret, err := startPayment(ctx, payment, fmt.Sprintf("Order %s", order.ID.Hex()), userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return payment, clientSecret, nil
+}
+
+// CreateSubBillPayment starts paying a sub-bill of a dining session, in full or in part.
+// Guests who ordered at the table pay by card or at the counter; store staff may also
+// record cash. For card payments the provider's client secret is returned as well.
+func CreateSubBillPayment(billID string, req models.CreatePaymentRequest, userID primitive.ObjectID, role string) (*models.Payment, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	bill, err := GetSubBillByID(billID)
+	if err != nil {
+		return nil, "", err
+	}
+	session, err := GetDiningSessionByID(bill.SessionID.Hex())
+	if err != nil {
+		return nil, "", err
+	}
+	if session.Status != models.DiningSessionOpen {
+		return nil, "", fmt.Errorf("%w: the dining session is closed", ErrOrderNotPayable)
+	}
+
+	store, err := GetStoreByID(bill.StoreID.Hex())
+	if err != nil {
+		return nil, "", err
+	}
+	isStaff := HasStorePermission(store, userID, role, models.PermissionPaymentsManage)
+	isCustomer := IsDiningSessionCustomer(session.ID, userID)
+	if !isStaff && (!isCustomer || req.Method == models.PaymentMethodCash) {
+		return nil, "", ErrPaymentNotAllowed
+	}
+
+	// The session is locked rather than the bill, so the bill cannot be split again meanwhile
+	unlock, err := lockForPayment(ctx, diningSessionCollection, session.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	defer unlock()
+	if bill, err = GetSubBillByID(billID); err != nil {
+		return nil, "", fmt.Errorf("%w: the bill was split again, please reload it", ErrOrderNotPayable)
+	}
+
+	due, err := subBillAmountDue(ctx, bill)
+	if err != nil {
+		return nil, "", err
+	}
+	amount, err := paymentAmount(req.Amount, due, bill.Currency)
+	if err != nil {
+		return nil, "", err
+	}
+
+	payment := &models.Payment{
+		SubBillID: bill.ID,
+		StoreID:   bill.StoreID,
+		Method:    req.Method,
+		Currency:  bill.Currency,
+		Amount:    amount,
+	}
+	if isCustomer {
+		payment.CustomerID = userID
+	}
+	clientSecret, err := startPayment(ctx, payment, fmt.Sprintf("%s, table %s", bill.Label, session.TableName), userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return payment, clientSecret, nil
+}
+
+// GetPaymentByID retrieves a payment by ID
+func GetPaymentByID(paymentID string) (*models.Payment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(paymentID)
+	if err != nil {
+		return nil, errors.New("invalid payment ID")
+	}
+
+	var payment models.Payment
+	err = paymentCollection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&payment)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("payment not found")
+		}
+		return nil, err
+	}
+
+	return &payment, nil
+}
+
+// GetPaymentsByOrder retrieves every payment attempt of an order, oldest first
+func GetPaymentsByOrder(orderID primitive.ObjectID) ([]models.Payment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return findOrderPayments(ctx, orderID)
+}
+
+// GetPaymentsBySubBill retrieves every payment attempt of a sub-bill, oldest first
+func GetPaymentsBySubBill(billID primitive.ObjectID) ([]models.Payment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return findSubBillPayments(ctx, billID)
+}
+
+// CapturePayment takes the money of an authorized card payment or a pay-at-counter
+// payment the customer has paid. A smaller amount than authorized captures part of it.
+func CapturePayment(paymentID string, req models.CapturePaymentRequest, userID primitive.ObjectID) (*models.Payment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	payment, err := GetPaymentByID(paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case payment.Method == models.PaymentMethodCard && payment.Status == models.PaymentStatusAuthorized:
+	case payment.Method == models.PaymentMethodPayAtCounter && payment.Status == models.PaymentStatusPending:
+	default:
+		return nil, fmt.Errorf("%w: a %s payment that is %s cannot be captured", ErrPaymentStateConflict, payment.Method, payment.Status)
+	}
+
+	amount := req.Amount
+	if amount == 0 {
+		amount = payment.Amount
+	}
+	if amount > payment.Amount {
+		return nil, fmt.Errorf("%w: at most %s can be captured", ErrInvalidPaymentAmount, money.Format(payment.Amount, payment.Currency, config.AppConfig.DefaultLocale))
+	}
+
+	if payment.Method == models.PaymentMethodCard {
+		if err := paymentProvider.Capture(payment.ProviderPaymentID, amount); err != nil {
+			return nil, fmt.Errorf("payment provider error: %w", err)
+		}
+	}
+
+	event := models.PaymentEvent{Type: models.PaymentEventCaptured, Amount: amount, Actor: userID, At: time.Now()}
+	if err := updatePaymentState(ctx, payment, bson.M{
+		"status":          models.PaymentStatusCaptured,
+		"amount_captured": amount,
+	}, event); err != nil {
+		return nil, err
+	}
+
+	return GetPaymentByID(paymentID)
+}
+
+// VoidPayment cancels a payment that has not been captured, releasing any held funds
+func VoidPayment(paymentID string, userID primitive.ObjectID) (*models.Payment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	payment, err := GetPaymentByID(paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := voidPayment(ctx, payment, userID, ""); err != nil {
+		return nil, err
+	}
+
+	return GetPaymentByID(paymentID)
+}
+
+// RefundPayment gives back part or all of a captured payment
+func RefundPayment(paymentID string, req models.RefundPaymentRequest, userID primitive.ObjectID) (*models.Payment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	payment, err := GetPaymentByID(paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if payment.Status != models.PaymentStatusCaptured && payment.Status != models.PaymentStatusPartiallyRefunded {
+		return nil, fmt.Errorf("%w: a payment that is %s cannot be refunded", ErrPaymentStateConflict, payment.Status)
+	}
+
+	refundable := payment.Net()
+	amount := req.Amount
+	if amount == 0 {
+		amount = refundable
+	}
+	if amount > refundable {
+		return nil, fmt.Errorf("%w: at most %s can be refunded", ErrInvalidPaymentAmount, money.Format(refundable, payment.Currency, config.AppConfig.DefaultLocale))
+	}
+
+	event := models.PaymentEvent{Type: models.PaymentEventRefunded, Amount: amount, Actor: userID, Note: req.Reason, At: time.Now()}
+	if payment.Method == models.PaymentMethodCard {
+		refundID, err := paymentProvider.Refund(payment.ProviderPaymentID, amount)
+		if err != nil {
+			return nil, fmt.Errorf("payment provider error: %w", err)
+		}
+		event.Reference = refundID
+	}
+
+	status := models.PaymentStatusPartiallyRefunded
+	if amount == refundable {
+		status = models.PaymentStatusRefunded
+	}
+	if err := updatePaymentState(ctx, payment, bson.M{
+		"status":          status,
+		"amount_refunded": payment.AmountRefunded + amount,
+	}, event); err != nil {
+		return nil, err
+	}
+
+	return GetPaymentByID(paymentID)
+}
+
+// HandlePaymentWebhook processes a provider webhook. Each event is applied
+// once: a redelivered event is acknowledged without doing anything.
+func HandlePaymentWebhook(payload []byte, signature string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	event, err := paymentProvider.ParseWebhook(payload, signature)
+	if err != nil {
+		return err
+	}
+
+	record := models.PaymentWebhookEvent{
+		Provider:    paymentProvider.Name(),
+		EventID:     event.ID,
+		Type:        event.Type,
+		ProcessedAt: time.Now(),
+	}
+	result, err := paymentWebhookCollection.InsertOne(ctx, record)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil
+		}
+		return err
+	}
+
+	if err := applyPaymentEvent(ctx, event); err != nil {
+		// Forget the event so the provider's retry is processed
+		if _, delErr := paymentWebhookCollection.DeleteOne(ctx, bson.M{"_id": result.InsertedID}); delErr != nil {
+			log.Println("Warning: failed to forget payment webhook:", delErr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// ConfirmSandboxPayment simulates the customer confirming a card payment with the
+// mock provider. The resulting webhook goes through HandlePaymentWebhook, as a real
+// one would.
+func ConfirmSandboxPayment(paymentID string, succeed bool) (*models.Payment, error) {
+	mock, ok := paymentProvider.(*payments.MockProvider)
+	if !ok {
+		return nil, ErrSandboxUnavailable
+	}
+
+	payment, err := GetPaymentByID(paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if payment.Method != models.PaymentMethodCard || payment.Status != models.PaymentStatusPending {
+		return nil, fmt.Errorf("%w: only pending card payments can be confirmed", ErrPaymentStateConflict)
+	}
+
+	payload, signature, err := mock.Confirm(payment.ProviderPaymentID, payment.ID.Hex(), payment.Amount, succeed)
+	if err != nil {
+		return nil, err
+	}
+	if err := HandlePaymentWebhook(payload, signature); err != nil {
+		return nil, err
+	}
+
+	return GetPaymentByID(paymentID)
+}
+
+// lockForPayment takes the payment lock of an order or dining session, so that two requests
+// cannot both start a payment for the same balance, nor split a bill while it is being paid. The returned function releases it;
+// a lock that is never released expires after paymentLockTimeout.
+func lockForPayment(ctx context.Context, collection *mongo.Collection, id primitive.ObjectID) (func(), error) {
+	now := time.Now()
+	token := primitive.NewObjectID()
+	result, err := collection.UpdateOne(ctx, bson.M{
+		"_id": id,
+		"$or": bson.A{
+			bson.M{"payment_lock": bson.M{"$exists": false}},
+			bson.M{"payment_lock.expires_at": bson.M{"$lt": now}},
+		},
+	}, bson.M{
+		"$set": bson.M{"payment_lock": bson.M{"token": token, "expires_at": now.Add(paymentLockTimeout)}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, fmt.Errorf("%w: another payment or bill split is in progress, please retry", ErrPaymentStateConflict)
+	}
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_, err := collection.UpdateOne(ctx,
+			bson.M{"_id": id, "payment_lock.token": token},
+			bson.M{"$unset": bson.M{"payment_lock": ""}},
+		)
+		if err != nil {
+			log.Println("Warning: failed to release payment lock:", err)
+		}
+	}, nil
+}
+
+// paymentAmount checks the amount asked for against what is due; no amount means all of it
+func paymentAmount(requested, due money.Amount, currency string) (money.Amount, error) {
+	amount := requested
+	if amount == 0 {
+		amount = due
+	}
+	if amount <= 0 {
+		return 0, fmt.Errorf("%w: nothing is left to pay", ErrInvalidPaymentAmount)
+	}
+	if amount > due {
+		return 0, fmt.Errorf("%w: only %s is left to pay", ErrInvalidPaymentAmount, money.Format(due, currency, config.AppConfig.DefaultLocale))
+	}
+	return amount, nil
+}
+
+// startPayment records a new payment for an order or a sub-bill. Card payments get an
+// intent from the provider, whose client secret is returned; cash is captured at once.
+func startPayment(ctx context.Context, payment *models.Payment, description string, userID primitive.ObjectID) (string, error) {
+	now := time.Now()
+	payment.ID = primitive.NewObjectID()
+	payment.Status = models.PaymentStatusPending
+	payment.Events = []models.PaymentEvent{
+		{Type: models.PaymentEventCreated, Amount: payment.Amount, Actor: userID, At: now},
+	}
+	payment.CreatedBy = userID
+	payment.CreatedAt = now
+	payment.UpdatedAt = now
+
+	var clientSecret string
+	switch payment.Method {
+	case models.PaymentMethodCard:
+		intent, err := paymentProvider.CreateIntent(payments.IntentRequest{
+			Amount:      payment.Amount,
+			Currency:    payment.Currency,
+			Reference:   payment.ID.Hex(),
+			Description: description,
+		})
+		if err != nil {
+			return "", fmt.Errorf("payment provider error: %w", err)
+		}
+		payment.Provider = paymentProvider.Name()
+		payment.ProviderPaymentID = intent.ID
+		clientSecret = intent.ClientSecret
+	case models.PaymentMethodCash:
+		payment.Status = models.PaymentStatusCaptured
+		payment.AmountCaptured = payment.Amount
+		payment.Events = append(payment.Events, models.PaymentEvent{Type: models.PaymentEventCaptured, Amount: payment.Amount, Actor: userID, At: now})
+	}
+
+	if _, err := paymentCollection.InsertOne(ctx, payment); err != nil {
+		return "", err
+	}
+	if err := refreshPaymentTarget(ctx, payment); err != nil {
+		log.Println("Warning: failed to update payment status:", err)
+	}
+
+	return clientSecret, nil
+}
+
+// voidOrderPayments voids the open payments of an order that will not be fulfilled.
+// Captured payments are left for staff to refund.
+func voidOrderPayments(order *models.Order, userID primitive.ObjectID) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	orderPayments, err := findOrderPayments(ctx, order.ID)
+	if err != nil {
+		log.Println("Warning: failed to load order payments:", err)
+		return
+	}
+	for i := range orderPayments {
+		if !orderPayments[i].IsOpen() {
+			continue
+		}
+		if err := voidPayment(ctx, &orderPayments[i], userID, "order "+order.Status); err != nil {
+			log.Println("Warning: failed to void payment:", err)
+		}
+	}
+}
+
+// voidPayment voids an open payment with its provider and records it
+func voidPayment(ctx context.Context, payment *models.Payment, userID primitive.ObjectID, note string) error {
+	if !payment.IsOpen() {
+		return fmt.Errorf("%w: a payment that is %s cannot be voided", ErrPaymentStateConflict, payment.Status)
+	}
+	if payment.Method == models.PaymentMethodCard {
+		if err := paymentProvider.Void(payment.ProviderPaymentID); err != nil {
+			return fmt.Errorf("payment provider error: %w", err)
+		}
+	}
+
+	event := models.PaymentEvent{Type: models.PaymentEventVoided, Amount: payment.Amount, Actor: userID, Note: note, At: time.Now()}
+	return updatePaymentState(ctx, payment, bson.M{"status": models.PaymentStatusVoided}, event)
+}
+
+// applyPaymentEvent moves the payment of a provider event to its new state.
+// Events that do not move the payment forward, e.g. a late authorization of a
+// captured payment, are ignored.
+func applyPaymentEvent(ctx context.Context, event *payments.Event) error {
+	var payment models.Payment
+	err := paymentCollection.FindOne(ctx, bson.M{
+		"provider":            paymentProvider.Name(),
+		"provider_payment_id": event.IntentID,
+	}).Decode(&payment)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return fmt.Errorf("no payment for intent %s", event.IntentID)
+		}
+		return err
+	}
+
+	amount := event.Amount
+	if amount == 0 {
+		amount = payment.Amount
+	}
+	entry := models.PaymentEvent{Amount: amount, Reference: event.ID, At: time.Now()}
+	var set bson.M
+
+	switch {
+	case event.Type == payments.EventAuthorized && payment.Status == models.PaymentStatusPending:
+		entry.Type = models.PaymentEventAuthorized
+		set = bson.M{"status": models.PaymentStatusAuthorized}
+	case event.Type == payments.EventCaptured && payment.IsOpen():
+		entry.Type = models.PaymentEventCaptured
+		set = bson.M{"status": models.PaymentStatusCaptured, "amount_captured": amount}
+	case event.Type == payments.EventFailed && payment.Status == models.PaymentStatusPending:
+		entry.Type = models.PaymentEventFailed
+		entry.Note = event.FailureReason
+		set = bson.M{"status": models.PaymentStatusFailed, "failure_reason": event.FailureReason}
+	default:
+		return nil
+	}
+
+	err = updatePaymentState(ctx, &payment, set, entry)
+	if errors.Is(err, ErrPaymentStateConflict) {
+		// Changed by staff in the meantime; the event is stale
+		return nil
+	}
+	return err
+}
+
+// updatePaymentState applies a state change to a payment, adds it to the ledger and
+// updates the order or sub-bill. The change only applies if the payment was not changed meanwhile.
+func updatePaymentState(ctx context.Context, payment *models.Payment, set bson.M, event models.PaymentEvent) error {
+	set["updated_at"] = event.At
+	filter := bson.M{"_id": payment.ID, "status": payment.Status, "amount_refunded": payment.AmountRefunded}
+	update := bson.M{
+		"$set":  set,
+		"$push": bson.M{"events": event},
+	}
+
+	result, err := paymentCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: it was changed by someone else, please retry", ErrPaymentStateConflict)
+	}
+
+	if err := refreshPaymentTarget(ctx, payment); err != nil {
+		log.Println("Warning: failed to update payment status:", err)
+	}
+	return nil
+}
+
+// refreshPaymentTarget updates the payment status of the order or sub-bill a payment is for
+func refreshPaymentTarget(ctx context.Context, payment *models.Payment) error {
+	if !payment.SubBillID.IsZero() {
+		return refreshSubBillPayment(ctx, payment.SubBillID)
+	}
+	order, err := GetOrderByID(payment.OrderID.Hex())
+	if err != nil {
+		return err
+	}
+	return refreshOrderPayment(ctx, order)
+}
+
+// orderAmountDue returns what is left to pay on an order, counting payments
+// that are still pending or authorized as paid
+func orderAmountDue(ctx context.Context, order *models.Order) (money.Amount, error) {
+	orderPayments, err := findOrderPayments(ctx, order.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	due := order.Total
+	for _, payment := range orderPayments {
+		if payment.IsOpen() {
+			due -= payment.Amount
+		} else {
+			due -= payment.Net()
+		}
+	}
+	return max(due, 0), nil
+}
+
+// refreshOrderPayment works out the payment status and amount paid of an order from its
+// payments. An order that becomes fully paid moves on in its workflow.
+func refreshOrderPayment(ctx context.Context, order *models.Order) error {
+	orderPayments, err := findOrderPayments(ctx, order.ID)
+	if err != nil {
+		return err
+	}
+
+	var paid, refunded money.Amount
+	authorized := false
+	for _, payment := range orderPayments {
+		paid += payment.Net()
+		refunded += payment.AmountRefunded
+		if payment.Status == models.PaymentStatusAuthorized {
+			authorized = true
+		}
+	}
+
+	status := models.OrderPaymentUnpaid
+	switch {
+	case paid > 0 && paid >= order.Total:
+		status = models.OrderPaymentPaid
+	case paid > 0:
+		status = models.OrderPaymentPartiallyPaid
+	case authorized:
+		status = models.OrderPaymentAuthorized
+	case refunded > 0:
+		status = models.OrderPaymentRefunded
+	}
+
+	_, err = orderCollection.UpdateOne(ctx, bson.M{"_id": order.ID}, bson.M{
+		"$set": bson.M{
+			"payment_status": status,
+			"amount_paid":    paid,
+			"updated_at":     time.Now(),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if status == models.OrderPaymentPaid && order.PaymentStatus != models.OrderPaymentPaid {
+		return advancePaidOrder(ctx, order)
+	}
+	return nil
+}
+
+// findOrderPayments loads the payments of an order, oldest first
+func findOrderPayments(ctx context.Context, orderID primitive.ObjectID) ([]models.Payment, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := paymentCollection.Find(ctx, bson.M{"order_id": orderID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	orderPayments := []models.Payment{}
+	if err = cursor.All(ctx, &orderPayments); err != nil {
+		return nil, err
+	}
+	return orderPayments, nil
+}
+
+// findSubBillPayments loads the payments of a sub-bill, oldest first
+func findSubBillPayments(ctx context.Context, billID primitive.ObjectID) ([]models.Payment, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := paymentCollection.Find(ctx, bson.M{"sub_bill_id": billID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	billPayments := []models.Payment{}
+	if err = cursor.All(ctx, &billPayments); err != nil {
+		return nil, err
+	}
+	return billPayments, nil
+}
@@ -7,6 +7,7 @@ import (
 
 	"ordernew/config"
 	"ordernew/models"
+	"ordernew/money"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -49,12 +50,22 @@ func (s *ProductService) CreateProduct(req models.CreateProductRequest, userID s
 		return nil, errors.New("product with this SKU already exists")
 	}
 
+	currency := req.Currency
+	if currency == "" {
+		currency = config.AppConfig.DefaultCurrency
+	}
+	currency, err = money.NormalizeCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create product
 	product := models.Product{
 		ID:          primitive.NewObjectID(),
 		Name:        req.Name,
 		Description: req.Description,
 		Price:       req.Price,
+		Currency:    currency,
 		Quantity:    req.Quantity,
 		Category:    req.Category,
 		SKU:         req.SKU,
@@ -0,0 +1,306 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"ordernew/mailer"
+	"ordernew/models"
+	"ordernew/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	// ErrIncorrectPassword is returned when the password confirming an account change is wrong
+	ErrIncorrectPassword = errors.New("password is incorrect")
+	// ErrEmailInUse is returned when changing to an email address another account uses
+	ErrEmailInUse = errors.New("email is already used by another account")
+	// ErrUserOwnsStores is returned when a store owner tries to delete their account
+	ErrUserOwnsStores = errors.New("transfer or delete your stores before deleting your account")
+)
+
+// ChangePassword sets a new password after checking the current one, and
+// signs the user out of every session except the one making the change.
+// Users who log in by phone and have no password yet can set one directly.
+func (s *UserService) ChangePassword(userID primitive.ObjectID, req models.ChangePasswordRequest, keepSessionID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := getActiveUser(userID)
+	if err != nil {
+		return err
+	}
+	if err := checkCurrentPassword(user, req.CurrentPassword); err != nil {
+		return err
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		return errors.New("failed to hash password")
+	}
+
+	_, err = s.collection.UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{
+			"$set":   bson.M{"password": hashedPassword, "updated_at": time.Now()},
+			"$unset": bson.M{"failed_login_attempts": "", "locked_until": ""},
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	return RevokeOtherSessions(userID, keepSessionID)
+}
+
+// ChangeEmail moves an account to a new email address after checking the
+// password. The new address has to be verified again, and the old address is
+// told about the change.
+func (s *UserService) ChangeEmail(userID primitive.ObjectID, req models.ChangeEmailRequest) (*models.UserResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := getActiveUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCurrentPassword(user, req.Password); err != nil {
+		return nil, err
+	}
+	if req.Email == user.Email {
+		response := user.ToUserResponse()
+		return &response, nil
+	}
+
+	count, err := s.collection.CountDocuments(ctx, bson.M{"email": req.Email, "_id": bson.M{"$ne": userID}})
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrEmailInUse
+	}
+
+	_, err = s.collection.UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{
+			"$set":   bson.M{"email": req.Email, "email_verified": false, "updated_at": time.Now()},
+			"$unset": bson.M{"email_verified_at": ""},
+		},
+	)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrEmailInUse
+		}
+		return nil, err
+	}
+
+	oldEmail := user.Email
+	user.Email = req.Email
+	if err := SendVerificationEmail(user); err != nil {
+		log.Println("Warning: failed to send verification email:", err)
+	}
+	if oldEmail != "" {
+		err := mailSender.Send(mailer.Message{
+			To:      oldEmail,
+			Subject: "Your email address was changed",
+			Body: fmt.Sprintf("Hi %s,\n\nThe email address of your account was changed to %s. If you did not make this change, please contact us right away.\n",
+				user.Name, req.Email),
+		})
+		if err != nil {
+			log.Println("Warning: failed to send email change notice:", err)
+		}
+	}
+
+	return s.GetUserByID(userID.Hex())
+}
+
+// DeleteOwnAccount deletes the account of the user making the request after
+// checking the password. Store owners have to hand over their stores first.
+func (s *UserService) DeleteOwnAccount(userID primitive.ObjectID, password string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := getActiveUser(userID)
+	if err != nil {
+		return err
+	}
+	if err := checkCurrentPassword(user, password); err != nil {
+		return err
+	}
+
+	count, err := storeCollection.CountDocuments(ctx, bson.M{"owner_id": userID})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrUserOwnsStores
+	}
+
+	return s.DeleteUser(userID.Hex())
+}
+
+// checkCurrentPassword confirms a sensitive account change with the user's
+// password. Accounts without a password, created by phone login, skip the check.
+func checkCurrentPassword(user *models.User, password string) error {
+	if user.Password == "" {
+		return nil
+	}
+	if !utils.CheckPasswordHash(password, user.Password) {
+		return ErrIncorrectPassword
+	}
+	return nil
+}
+
+// GetSavedAddresses returns the delivery addresses saved on a user's account
+func GetSavedAddresses(userID primitive.ObjectID) ([]models.SavedAddress, error) {
+	user, err := getActiveUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Addresses == nil {
+		return []models.SavedAddress{}, nil
+	}
+	return user.Addresses, nil
+}
+
+// AddSavedAddress saves a delivery address on a user's account. The first
+// address saved becomes the default.
+func AddSavedAddress(userID primitive.ObjectID, req models.SavedAddressRequest) (*models.SavedAddress, error) {
+	if err := validateSavedAddress(req); err != nil {
+		return nil, err
+	}
+
+	user, err := getActiveUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(user.Addresses) >= models.MaxSavedAddresses {
+		return nil, fmt.Errorf("you can save at most %d addresses", models.MaxSavedAddresses)
+	}
+
+	now := time.Now()
+	address := models.SavedAddress{
+		ID:           primitive.NewObjectID(),
+		Label:        req.Label,
+		Address:      req.Address,
+		Instructions: req.Instructions,
+		IsDefault:    req.IsDefault || len(user.Addresses) == 0,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	addresses := append(user.Addresses, address)
+	if address.IsDefault {
+		setDefaultAddress(addresses, address.ID)
+	}
+	if err := saveAddresses(user, addresses); err != nil {
+		return nil, err
+	}
+
+	return &address, nil
+}
+
+// UpdateSavedAddress replaces one of a user's saved delivery addresses
+func UpdateSavedAddress(userID primitive.ObjectID, addressID string, req models.SavedAddressRequest) (*models.SavedAddress, error) {
+	if err := validateSavedAddress(req); err != nil {
+		return nil, err
+	}
+
+	user, index, err := findSavedAddress(userID, addressID)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := user.Addresses
+	address := &addresses[index]
+	address.Label = req.Label
+	address.Address = req.Address
+	address.Instructions = req.Instructions
+	address.UpdatedAt = time.Now()
+	if req.IsDefault {
+		setDefaultAddress(addresses, address.ID)
+	}
+	if err := saveAddresses(user, addresses); err != nil {
+		return nil, err
+	}
+
+	return address, nil
+}
+
+// DeleteSavedAddress removes one of a user's saved delivery addresses. If it
+// was the default, the oldest remaining address becomes the default.
+func DeleteSavedAddress(userID primitive.ObjectID, addressID string) error {
+	user, index, err := findSavedAddress(userID, addressID)
+	if err != nil {
+		return err
+	}
+
+	wasDefault := user.Addresses[index].IsDefault
+	addresses := append(user.Addresses[:index], user.Addresses[index+1:]...)
+	if wasDefault && len(addresses) > 0 {
+		setDefaultAddress(addresses, addresses[0].ID)
+	}
+
+	return saveAddresses(user, addresses)
+}
+
+// validateSavedAddress checks a delivery address has enough to deliver to
+func validateSavedAddress(req models.SavedAddressRequest) error {
+	if req.Address.Street == "" || req.Address.City == "" {
+		return errors.New("address street and city are required")
+	}
+	return nil
+}
+
+// findSavedAddress loads a user and the index of one of their saved addresses
+func findSavedAddress(userID primitive.ObjectID, addressID string) (*models.User, int, error) {
+	objectID, err := primitive.ObjectIDFromHex(addressID)
+	if err != nil {
+		return nil, 0, errors.New("invalid address ID")
+	}
+
+	user, err := getActiveUser(userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i, address := range user.Addresses {
+		if address.ID == objectID {
+			return user, i, nil
+		}
+	}
+
+	return nil, 0, errors.New("address not found")
+}
+
+// setDefaultAddress marks one address as the default and clears the others
+func setDefaultAddress(addresses []models.SavedAddress, defaultID primitive.ObjectID) {
+	for i := range addresses {
+		addresses[i].IsDefault = addresses[i].ID == defaultID
+	}
+}
+
+// saveAddresses writes a user's addresses back. The write only applies if the
+// user was not changed since it was read, so concurrent edits are not lost.
+func saveAddresses(user *models.User, addresses []models.SavedAddress) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := userCollection.UpdateOne(ctx,
+		bson.M{"_id": user.ID, "updated_at": user.UpdatedAt},
+		bson.M{"$set": bson.M{"addresses": addresses, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("addresses were changed at the same time, please try again")
+	}
+
+	return nil
+}
@@ -0,0 +1,530 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"ordernew/config"
+	"ordernew/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var promotionCollection *mongo.Collection
+var promotionUsageCollection *mongo.Collection
+
+var (
+	// ErrInvalidPromotion is returned for a promotion with inconsistent conditions or effect
+	ErrInvalidPromotion = errors.New("invalid promotion")
+	// ErrPromoCodeTaken is returned when another promotion of the store already uses a code
+	ErrPromoCodeTaken = errors.New("promo code is already used by another promotion")
+	// ErrInvalidPromoCode is returned for a promo code that is unknown, inactive or out of its dates
+	ErrInvalidPromoCode = errors.New("invalid promo code")
+	// ErrPromoCodeNotApplicable is returned when a promo code's conditions are not met by the order
+	ErrPromoCodeNotApplicable = errors.New("promo code does not apply to this order")
+	// ErrPromotionUsedUp is returned when a promotion reached its usage limit, possibly during checkout
+	ErrPromotionUsedUp = errors.New("promotion is no longer available")
+)
+
+// promoCodePattern lists the characters a promo code may contain
+var promoCodePattern = regexp.MustCompile(`^[A-Z0-9_-]+$`)
+
+// InitPromotionCollection initializes the promotion and promotion usage collections
+func InitPromotionCollection() {
+	promotionCollection = config.GetCollection("promotions")
+	promotionUsageCollection = config.GetCollection("promotion_usages")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// A code identifies one promotion per store
+	_, err := promotionCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "store_id", Value: 1}, {Key: "code", Value: 1}},
+		Options: options.Index().
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"code": bson.M{"$type": "string"}}),
+	})
+	if err != nil {
+		log.Println("Warning: failed to create promotion code index:", err)
+	}
+
+	// One usage counter per promotion and customer
+	_, err = promotionUsageCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "promotion_id", Value: 1}, {Key: "user_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		log.Println("Warning: failed to create promotion usage index:", err)
+	}
+}
+
+// CreatePromotion creates a new promotion for a store
+func CreatePromotion(req models.CreatePromotionRequest) (*models.Promotion, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	storeID, err := primitive.ObjectIDFromHex(req.StoreID)
+	if err != nil {
+		return nil, errors.New("invalid store ID")
+	}
+
+	// Verify store exists
+	_, err = GetStoreByID(req.StoreID)
+	if err != nil {
+		return nil, errors.New("store not found")
+	}
+
+	code, err := normalizePromoCode(req.Code)
+	if err != nil {
+		return nil, err
+	}
+	conditions, err := buildPromotionConditions(storeID, req.Conditions)
+	if err != nil {
+		return nil, err
+	}
+	if err := validatePromotionEffect(&req.Effect); err != nil {
+		return nil, err
+	}
+
+	promotion := &models.Promotion{
+		StoreID:      storeID,
+		Name:         req.Name,
+		Description:  req.Description,
+		Code:         code,
+		Conditions:   *conditions,
+		Effect:       req.Effect,
+		Priority:     req.Priority,
+		UsageLimit:   req.UsageLimit,
+		PerUserLimit: req.PerUserLimit,
+		IsActive:     true,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+
+	result, err := promotionCollection.InsertOne(ctx, promotion)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrPromoCodeTaken
+		}
+		return nil, err
+	}
+
+	promotion.ID = result.InsertedID.(primitive.ObjectID)
+	return promotion, nil
+}
+
+// GetPromotionByID retrieves a promotion by ID
+func GetPromotionByID(promotionID string) (*models.Promotion, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(promotionID)
+	if err != nil {
+		return nil, errors.New("invalid promotion ID")
+	}
+
+	var promotion models.Promotion
+	err = promotionCollection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&promotion)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("promotion not found")
+		}
+		return nil, err
+	}
+
+	return &promotion, nil
+}
+
+// GetPromotionsByStore retrieves all promotions of a store, in the order they apply
+func GetPromotionsByStore(storeID string) ([]models.Promotion, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(storeID)
+	if err != nil {
+		return nil, errors.New("invalid store ID")
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "priority", Value: -1}, {Key: "created_at", Value: 1}})
+	cursor, err := promotionCollection.Find(ctx, bson.M{"store_id": objectID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var promotions []models.Promotion
+	if err = cursor.All(ctx, &promotions); err != nil {
+		return nil, err
+	}
+
+	return promotions, nil
+}
+
+// UpdatePromotion updates an existing promotion
+func UpdatePromotion(promotionID string, req models.UpdatePromotionRequest) (*models.Promotion, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	promotion, err := GetPromotionByID(promotionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build update document
+	update := bson.M{
+		"$set": bson.M{
+			"updated_at": time.Now(),
+		},
+	}
+
+	if req.Name != "" {
+		update["$set"].(bson.M)["name"] = req.Name
+	}
+	if req.Description != "" {
+		update["$set"].(bson.M)["description"] = req.Description
+	}
+	if req.Code != nil {
+		code, err := normalizePromoCode(*req.Code)
+		if err != nil {
+			return nil, err
+		}
+		if code == "" {
+			update["$unset"] = bson.M{"code": ""}
+		} else {
+			update["$set"].(bson.M)["code"] = code
+		}
+	}
+	if req.Conditions != nil {
+		conditions, err := buildPromotionConditions(promotion.StoreID, *req.Conditions)
+		if err != nil {
+			return nil, err
+		}
+		update["$set"].(bson.M)["conditions"] = conditions
+	}
+	if req.Effect != nil {
+		if err := validatePromotionEffect(req.Effect); err != nil {
+			return nil, err
+		}
+		update["$set"].(bson.M)["effect"] = req.Effect
+	}
+	if req.Priority != nil {
+		update["$set"].(bson.M)["priority"] = *req.Priority
+	}
+	if req.UsageLimit != nil {
+		update["$set"].(bson.M)["usage_limit"] = *req.UsageLimit
+	}
+	if req.PerUserLimit != nil {
+		update["$set"].(bson.M)["per_user_limit"] = *req.PerUserLimit
+	}
+	if req.IsActive != nil {
+		update["$set"].(bson.M)["is_active"] = *req.IsActive
+	}
+
+	_, err = promotionCollection.UpdateOne(ctx, bson.M{"_id": promotion.ID}, update)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrPromoCodeTaken
+		}
+		return nil, err
+	}
+
+	return GetPromotionByID(promotionID)
+}
+
+// DeletePromotion deletes a promotion and its usage counters.
+// Orders placed with it keep their discounts.
+func DeletePromotion(promotionID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(promotionID)
+	if err != nil {
+		return errors.New("invalid promotion ID")
+	}
+
+	result, err := promotionCollection.DeleteOne(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return errors.New("promotion not found")
+	}
+
+	_, err = promotionUsageCollection.DeleteMany(ctx, bson.M{"promotion_id": objectID})
+	return err
+}
+
+// findApplicablePromotions returns the promotions that may apply to an order placed now:
+// the store's running automatic promotions, by priority, followed by the promotion of
+// code when one is given. Promotions the customer or the store has used up are left
+// out; for the code they are an error.
+func findApplicablePromotions(store *models.Store, code string, customerID primitive.ObjectID) ([]models.Promotion, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	code, err := normalizePromoCode(code)
+	if err != nil {
+		return nil, ErrInvalidPromoCode
+	}
+
+	filter := bson.M{"store_id": store.ID, "is_active": true, "code": bson.M{"$exists": false}}
+	if code != "" {
+		filter = bson.M{"store_id": store.ID, "is_active": true, "$or": bson.A{
+			bson.M{"code": bson.M{"$exists": false}},
+			bson.M{"code": code},
+		}}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "priority", Value: -1}, {Key: "created_at", Value: 1}})
+	cursor, err := promotionCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var found []models.Promotion
+	if err = cursor.All(ctx, &found); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().In(store.Location())
+	promotions := make([]models.Promotion, 0, len(found))
+	var coded *models.Promotion
+	for i := range found {
+		promotion := found[i]
+		if promotion.Code != "" {
+			coded = &found[i]
+			continue
+		}
+		if !promotion.IsRunningAt(now) {
+			continue
+		}
+		if err := checkPromotionUsage(ctx, &promotion, customerID); err != nil {
+			continue
+		}
+		promotions = append(promotions, promotion)
+	}
+
+	if code == "" {
+		return promotions, nil
+	}
+	if coded == nil || !coded.IsRunningAt(now) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPromoCode, code)
+	}
+	if err := checkPromotionUsage(ctx, coded, customerID); err != nil {
+		return nil, err
+	}
+
+	// The code applies after the automatic promotions
+	return append(promotions, *coded), nil
+}
+
+// checkPromotionUsage checks that a promotion is below its usage limits for a customer.
+// Limits are enforced again, atomically, when an order redeems the promotion.
+func checkPromotionUsage(ctx context.Context, promotion *models.Promotion, customerID primitive.ObjectID) error {
+	if promotion.UsageLimit > 0 && promotion.UsageCount >= promotion.UsageLimit {
+		return fmt.Errorf("%w: %s has been fully redeemed", ErrPromotionUsedUp, promotion.Name)
+	}
+	if promotion.PerUserLimit == 0 {
+		return nil
+	}
+	if customerID.IsZero() {
+		return fmt.Errorf("%w: sign in to use %s", ErrPromoCodeNotApplicable, promotion.Name)
+	}
+
+	var usage models.PromotionUsage
+	err := promotionUsageCollection.FindOne(ctx, bson.M{"promotion_id": promotion.ID, "user_id": customerID}).Decode(&usage)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return err
+	}
+	if usage.Count >= promotion.PerUserLimit {
+		return fmt.Errorf("%w: you have already used %s", ErrPromotionUsedUp, promotion.Name)
+	}
+	return nil
+}
+
+// redeemPromotions claims one use of every promotion applied to an order. Each
+// claim is a conditional update, so concurrent checkouts cannot push a
+// promotion past its usage limits. When a claim fails, the ones made so far
+// are released and the order must be priced again.
+func redeemPromotions(applied []models.AppliedPromotion, promotions []models.Promotion, customerID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	byID := make(map[primitive.ObjectID]*models.Promotion, len(promotions))
+	for i := range promotions {
+		byID[promotions[i].ID] = &promotions[i]
+	}
+
+	var redeemed []models.AppliedPromotion
+	for _, discount := range applied {
+		promotion := byID[discount.PromotionID]
+		if promotion == nil {
+			continue
+		}
+		if err := claimPromotionUse(ctx, promotion, customerID); err != nil {
+			releasePromotions(redeemed, customerID)
+			return err
+		}
+		redeemed = append(redeemed, discount)
+	}
+
+	return nil
+}
+
+// claimPromotionUse counts one use of a promotion, failing if that would exceed its limits
+func claimPromotionUse(ctx context.Context, promotion *models.Promotion, customerID primitive.ObjectID) error {
+	now := time.Now()
+
+	// The limit is read from the stored promotion, so an edit cannot be raced either
+	filter := bson.M{"_id": promotion.ID, "$or": bson.A{
+		bson.M{"usage_limit": 0},
+		bson.M{"$expr": bson.M{"$lt": bson.A{"$usage_count", "$usage_limit"}}},
+	}}
+	result, err := promotionCollection.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"usage_count": 1}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: %s has been fully redeemed", ErrPromotionUsedUp, promotion.Name)
+	}
+
+	if customerID.IsZero() {
+		return nil
+	}
+
+	// Count the use per customer. At the limit the filter misses, and the upsert
+	// then collides with the existing counter on the unique index.
+	usageFilter := bson.M{"promotion_id": promotion.ID, "user_id": customerID}
+	if promotion.PerUserLimit > 0 {
+		usageFilter["count"] = bson.M{"$lt": promotion.PerUserLimit}
+	}
+	_, err = promotionUsageCollection.UpdateOne(ctx, usageFilter,
+		bson.M{"$inc": bson.M{"count": 1}, "$set": bson.M{"updated_at": now}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		// Give back the use counted above
+		if _, undoErr := promotionCollection.UpdateOne(ctx, bson.M{"_id": promotion.ID}, bson.M{"$inc": bson.M{"usage_count": -1}}); undoErr != nil {
+			log.Println("Warning: failed to release promotion use:", undoErr)
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("%w: you have already used %s", ErrPromotionUsedUp, promotion.Name)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// releasePromotions gives back the uses of promotions claimed by an order that
+// failed or was cancelled, so they count toward no limit
+func releasePromotions(applied []models.AppliedPromotion, customerID primitive.ObjectID) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, discount := range applied {
+		_, err := promotionCollection.UpdateOne(ctx,
+			bson.M{"_id": discount.PromotionID, "usage_count": bson.M{"$gt": 0}},
+			bson.M{"$inc": bson.M{"usage_count": -1}},
+		)
+		if err != nil {
+			log.Println("Warning: failed to release promotion use:", err)
+		}
+		if customerID.IsZero() {
+			continue
+		}
+		_, err = promotionUsageCollection.UpdateOne(ctx,
+			bson.M{"promotion_id": discount.PromotionID, "user_id": customerID, "count": bson.M{"$gt": 0}},
+			bson.M{"$inc": bson.M{"count": -1}, "$set": bson.M{"updated_at": time.Now()}},
+		)
+		if err != nil {
+			log.Println("Warning: failed to release promotion use:", err)
+		}
+	}
+}
+
+// normalizePromoCode upper-cases a promo code and checks its characters
+func normalizePromoCode(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code != "" && !promoCodePattern.MatchString(code) {
+		return "", fmt.Errorf("%w: a code may only contain letters, digits, - and _", ErrInvalidPromotion)
+	}
+	return code, nil
+}
+
+// buildPromotionConditions validates promotion conditions and converts their IDs
+func buildPromotionConditions(storeID primitive.ObjectID, req models.PromotionConditionsRequest) (*models.PromotionConditions, error) {
+	conditions := &models.PromotionConditions{
+		CategoryIDs: []primitive.ObjectID{},
+		Tags:        req.Tags,
+		StartsAt:    req.StartsAt,
+		EndsAt:      req.EndsAt,
+		Schedule:    req.Schedule,
+		MinSubtotal: req.MinSubtotal,
+	}
+	if conditions.Tags == nil {
+		conditions.Tags = []string{}
+	}
+
+	for _, id := range req.CategoryIDs {
+		category, err := GetCategoryByID(id)
+		if err != nil {
+			return nil, fmt.Errorf("%w: category %s not found", ErrInvalidPromotion, id)
+		}
+		if category.StoreID != storeID {
+			return nil, fmt.Errorf("%w: category %s does not belong to this store", ErrInvalidPromotion, id)
+		}
+		conditions.CategoryIDs = append(conditions.CategoryIDs, category.ID)
+	}
+
+	if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		return nil, fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidPromotion)
+	}
+	if req.MinSubtotal < 0 {
+		return nil, fmt.Errorf("%w: min_subtotal cannot be negative", ErrInvalidPromotion)
+	}
+	if err := validateAvailability(req.Schedule); err != nil {
+		return nil, err
+	}
+
+	return conditions, nil
+}
+
+// validatePromotionEffect checks that an effect has the values its type needs
+func validatePromotionEffect(effect *models.PromotionEffect) error {
+	switch effect.Type {
+	case models.PromotionPercentOff:
+		if effect.Percent <= 0 || effect.Percent > 100 {
+			return fmt.Errorf("%w: percent must be between 0 and 100", ErrInvalidPromotion)
+		}
+	case models.PromotionAmountOff:
+		if effect.Amount <= 0 {
+			return fmt.Errorf("%w: amount must be greater than zero", ErrInvalidPromotion)
+		}
+	case models.PromotionPriceOverride:
+		if effect.Amount < 0 {
+			return fmt.Errorf("%w: amount cannot be negative", ErrInvalidPromotion)
+		}
+	case models.PromotionFreeItem:
+		if effect.BuyQuantity < 1 {
+			return fmt.Errorf("%w: buy_quantity must be at least 1", ErrInvalidPromotion)
+		}
+		if effect.FreeQuantity < 0 {
+			return fmt.Errorf("%w: free_quantity cannot be negative", ErrInvalidPromotion)
+		}
+		if effect.FreeQuantity == 0 {
+			effect.FreeQuantity = 1
+		}
+	default:
+		return fmt.Errorf("%w: unknown effect type %q", ErrInvalidPromotion, effect.Type)
+	}
+	return nil
+}
@@ -0,0 +1,289 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"ordernew/config"
+	"ordernew/models"
+	"ordernew/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var sessionCollection *mongo.Collection
+
+var (
+	// ErrInvalidRefreshToken is returned when a refresh token is unknown, expired or revoked
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	// ErrSessionRevoked is returned when an access token belongs to a session that is no longer valid
+	ErrSessionRevoked = errors.New("session has been revoked")
+	// ErrUserInactive is returned when the user of a session is disabled or deleted
+	ErrUserInactive = errors.New("user account is inactive")
+)
+
+// InitSessionCollection initializes the session collection
+func InitSessionCollection() {
+	sessionCollection = config.GetCollection("sessions")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Let MongoDB remove sessions once their refresh token expires
+	_, err := sessionCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		{Keys: bson.D{{Key: "refresh_token_hash", Value: 1}}},
+		{Keys: bson.D{{Key: "previous_refresh_token_hash", Value: 1}}, Options: options.Index().SetSparse(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+	})
+	if err != nil {
+		log.Println("Warning: failed to create session indexes:", err)
+	}
+}
+
+// refreshTokenTTL returns how long a session lasts without being refreshed
+func refreshTokenTTL() time.Duration {
+	ttl, err := time.ParseDuration(config.AppConfig.RefreshTokenTTL)
+	if err != nil {
+		ttl = 30 * 24 * time.Hour // Default to 30 days
+	}
+	return ttl
+}
+
+// CreateSession starts a session for a user and returns its first token pair
+func CreateSession(user *models.User, userAgent, ipAddress string) (*models.TokenPair, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	refreshToken, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, errors.New("failed to generate refresh token")
+	}
+
+	now := time.Now()
+	session := &models.Session{
+		UserID:           user.ID,
+		RefreshTokenHash: utils.HashToken(refreshToken),
+		UserAgent:        userAgent,
+		IPAddress:        ipAddress,
+		ExpiresAt:        now.Add(refreshTokenTTL()),
+		LastUsedAt:       now,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+
+	result, err := sessionCollection.InsertOne(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+	session.ID = result.InsertedID.(primitive.ObjectID)
+
+	return issueTokenPair(user, session.ID, refreshToken)
+}
+
+// RefreshSession exchanges a refresh token for a new token pair. The refresh
+// token is rotated: the old one stops working, and presenting it again revokes
+// the whole session, since it means the token was stolen.
+func RefreshSession(refreshToken, userAgent, ipAddress string) (*models.TokenPair, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tokenHash := utils.HashToken(refreshToken)
+	now := time.Now()
+
+	var session models.Session
+	err := sessionCollection.FindOne(ctx, bson.M{"refresh_token_hash": tokenHash}).Decode(&session)
+	if err == mongo.ErrNoDocuments {
+		// A rotated token being reused: revoke the session it belonged to
+		revokeResult, revokeErr := sessionCollection.UpdateOne(ctx,
+			bson.M{"previous_refresh_token_hash": tokenHash, "revoked_at": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"revoked_at": now, "updated_at": now}},
+		)
+		if revokeErr == nil && revokeResult.ModifiedCount > 0 {
+			log.Println("Warning: reused refresh token detected, session revoked")
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if session.RevokedAt != nil || !session.ExpiresAt.After(now) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := getActiveUser(session.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	newToken, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, errors.New("failed to generate refresh token")
+	}
+
+	// Filter on the old hash so a token can only be rotated once
+	update := bson.M{
+		"$set": bson.M{
+			"refresh_token_hash":          utils.HashToken(newToken),
+			"previous_refresh_token_hash": tokenHash,
+			"user_agent":                  userAgent,
+			"ip_address":                  ipAddress,
+			"expires_at":                  now.Add(refreshTokenTTL()),
+			"last_used_at":                now,
+			"updated_at":                  now,
+		},
+	}
+	result, err := sessionCollection.UpdateOne(ctx, bson.M{"_id": session.ID, "refresh_token_hash": tokenHash}, update)
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return issueTokenPair(user, session.ID, newToken)
+}
+
+// AuthenticateSession checks that an access token's session is still valid
+// and returns its user, who must still exist and be active
+func AuthenticateSession(claims *utils.Claims) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	sessionID, err := primitive.ObjectIDFromHex(claims.SessionID)
+	if err != nil {
+		return nil, ErrSessionRevoked
+	}
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		return nil, ErrSessionRevoked
+	}
+
+	var session models.Session
+	err = sessionCollection.FindOne(ctx, bson.M{"_id": sessionID, "user_id": userID}).Decode(&session)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrSessionRevoked
+		}
+		return nil, err
+	}
+	if session.RevokedAt != nil || !session.ExpiresAt.After(time.Now()) {
+		return nil, ErrSessionRevoked
+	}
+
+	return getActiveUser(userID)
+}
+
+// GetActiveSessions returns the sessions of a user that are neither revoked nor expired, newest first
+func GetActiveSessions(userID primitive.ObjectID) ([]models.Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := sessionCollection.Find(ctx,
+		bson.M{"user_id": userID, "revoked_at": bson.M{"$exists": false}, "expires_at": bson.M{"$gt": time.Now()}},
+		options.Find().SetSort(bson.D{{Key: "last_used_at", Value: -1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var sessions []models.Session
+	if err = cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession ends one session of a user
+func RevokeSession(sessionID string, userID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return errors.New("invalid session ID")
+	}
+
+	now := time.Now()
+	result, err := sessionCollection.UpdateOne(ctx,
+		bson.M{"_id": objectID, "user_id": userID, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": now, "updated_at": now}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("session not found")
+	}
+
+	return nil
+}
+
+// RevokeAllSessions ends every session of a user
+func RevokeAllSessions(userID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	_, err := sessionCollection.UpdateMany(ctx,
+		bson.M{"user_id": userID, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": now, "updated_at": now}},
+	)
+	return err
+}
+
+// RevokeOtherSessions ends every session of a user except one
+func RevokeOtherSessions(userID primitive.ObjectID, keepSessionID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"user_id": userID, "revoked_at": bson.M{"$exists": false}}
+	if keepID, err := primitive.ObjectIDFromHex(keepSessionID); err == nil {
+		filter["_id"] = bson.M{"$ne": keepID}
+	}
+
+	now := time.Now()
+	_, err := sessionCollection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"revoked_at": now, "updated_at": now}})
+	return err
+}
+
+// issueTokenPair signs an access token for a session and pairs it with its refresh token
+func issueTokenPair(user *models.User, sessionID primitive.ObjectID, refreshToken string) (*models.TokenPair, error) {
+	accessToken, err := utils.GenerateToken(user.ID.Hex(), user.Email, user.Role, sessionID.Hex())
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+
+	return &models.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(utils.AccessTokenExpiry().Seconds()),
+	}, nil
+}
+
+// getActiveUser loads a user and checks the account is active
+func getActiveUser(userID primitive.ObjectID) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var user models.User
+	err := userCollection.FindOne(ctx, bson.M{"_id": userID}).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrUserInactive
+		}
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, ErrUserInactive
+	}
+
+	return &user, nil
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"ordernew/config"
+	"ordernew/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var settingsCollection *mongo.Collection
+
+const securitySettingsID = "security"
+
+// Security settings are read on authenticated requests, so they are cached briefly
+const securitySettingsCacheTTL = 30 * time.Second
+
+var securitySettingsCache struct {
+	sync.Mutex
+	settings *models.SecuritySettings
+	loadedAt time.Time
+}
+
+// InitSettingsCollection initializes the settings collection
+func InitSettingsCollection() {
+	settingsCollection = config.GetCollection("settings")
+}
+
+// GetSecuritySettings returns the security settings, with defaults if they were never saved
+func GetSecuritySettings() (*models.SecuritySettings, error) {
+	securitySettingsCache.Lock()
+	defer securitySettingsCache.Unlock()
+
+	if securitySettingsCache.settings != nil && time.Since(securitySettingsCache.loadedAt) < securitySettingsCacheTTL {
+		settings := *securitySettingsCache.settings
+		return &settings, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	settings := models.SecuritySettings{ID: securitySettingsID}
+	err := settingsCollection.FindOne(ctx, bson.M{"_id": securitySettingsID}).Decode(&settings)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	securitySettingsCache.settings = &settings
+	securitySettingsCache.loadedAt = time.Now()
+	return &settings, nil
+}
+
+// UpdateSecuritySettings saves the security settings
+func UpdateSecuritySettings(req models.UpdateSecuritySettingsRequest, updatedBy primitive.ObjectID) (*models.SecuritySettings, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var settings models.SecuritySettings
+	err := settingsCollection.FindOneAndUpdate(ctx,
+		bson.M{"_id": securitySettingsID},
+		bson.M{"$set": bson.M{
+			"require_owner_two_factor": *req.RequireOwnerTwoFactor,
+			"updated_by":               updatedBy,
+			"updated_at":               time.Now(),
+		}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&settings)
+	if err != nil {
+		return nil, err
+	}
+
+	securitySettingsCache.Lock()
+	securitySettingsCache.settings = &settings
+	securitySettingsCache.loadedAt = time.Now()
+	securitySettingsCache.Unlock()
+
+	return &settings, nil
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"errors"
+
+	"ordernew/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrStoreAccessDenied is returned when a user may not manage a store
+var ErrStoreAccessDenied = errors.New("you do not have access to this store")
+
+// ResolveStoreRole returns the role a user holds in a store, or "" if none.
+// Admins act as owners of every store.
+func ResolveStoreRole(store *models.Store, userID primitive.ObjectID, role string) string {
+	if role == models.RoleAdmin {
+		return models.StoreRoleOwner
+	}
+	if userID.IsZero() {
+		return ""
+	}
+	if store.OwnerID == userID {
+		return models.StoreRoleOwner
+	}
+	member, err := GetActiveStoreMembership(store.ID, userID)
+	if err != nil {
+		return ""
+	}
+	return member.Role
+}
+
+// HasStorePermission reports whether a user holds a permission in a store,
+// either through their global role or their role in the store
+func HasStorePermission(store *models.Store, userID primitive.ObjectID, role, permission string) bool {
+	if models.RoleHasPermission(role, permission) {
+		return true
+	}
+	return models.StoreRoleHasPermission(ResolveStoreRole(store, userID, role), permission)
+}
+
+// AuthorizeStoreAccess loads a store and checks that the user holds a
+// permission in it. It returns the store and the user's store role.
+func AuthorizeStoreAccess(storeID string, userID primitive.ObjectID, role, permission string) (*models.Store, string, error) {
+	store, err := GetStoreByID(storeID)
+	if err != nil {
+		return nil, "", err
+	}
+	storeRole := ResolveStoreRole(store, userID, role)
+	if !models.RoleHasPermission(role, permission) && !models.StoreRoleHasPermission(storeRole, permission) {
+		return nil, "", ErrStoreAccessDenied
+	}
+	return store, storeRole, nil
+}
@@ -0,0 +1,384 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"ordernew/config"
+	"ordernew/mailer"
+	"ordernew/models"
+	"ordernew/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// storeInviteTTL is how long a store invite can be accepted
+const storeInviteTTL = 7 * 24 * time.Hour
+
+var storeMemberCollection *mongo.Collection
+
+var (
+	// ErrStoreMemberExists is returned when the email already has a membership or pending invite
+	ErrStoreMemberExists = errors.New("this email is already a member of the store or has a pending invite")
+	// ErrStoreInviteInvalid is returned when an invite token is unknown, used or expired
+	ErrStoreInviteInvalid = errors.New("invite is invalid or has expired")
+	// ErrStoreInviteEmailMismatch is returned when an invite is accepted by a different user
+	ErrStoreInviteEmailMismatch = errors.New("invite was sent to a different email address")
+)
+
+// InitStoreMemberCollection initializes the store member collection
+func InitStoreMemberCollection() {
+	storeMemberCollection = config.GetCollection("store_members")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// An email has at most one membership per store
+	_, err := storeMemberCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "store_id", Value: 1}, {Key: "email", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		log.Println("Warning: failed to create store member index:", err)
+	}
+}
+
+// InviteStoreMember invites an email address to a store with a staff role and
+// emails the invite token to that address. Only owners and admins may grant
+// the manager role. An expired invite for the same email is replaced.
+func InviteStoreMember(store *models.Store, req models.InviteStoreMemberRequest, invitedBy primitive.ObjectID, inviterRole string) (*models.StoreMember, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := checkStaffRoleGrant(inviterRole, req.Role); err != nil {
+		return nil, err
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+
+	// The owner already has every permission
+	var owner models.User
+	err := userCollection.FindOne(ctx, bson.M{"_id": store.OwnerID}).Decode(&owner)
+	if err == nil && strings.EqualFold(owner.Email, email) {
+		return nil, errors.New("the store owner cannot be invited")
+	}
+
+	var existing models.StoreMember
+	err = storeMemberCollection.FindOne(ctx, bson.M{"store_id": store.ID, "email": email}).Decode(&existing)
+	if err == nil {
+		stillPending := existing.InviteExpiresAt != nil && existing.InviteExpiresAt.After(time.Now())
+		if existing.Status == models.StoreMemberActive || stillPending {
+			return nil, ErrStoreMemberExists
+		}
+	} else if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, errors.New("failed to generate invite token")
+	}
+
+	// Only the invited address learns the token; without the email the invite cannot be accepted
+	if err := sendStoreInviteEmail(store, email, req.Role, token); err != nil {
+		log.Println("Warning: failed to send store invite email:", err)
+		return nil, errors.New("failed to send invite email")
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(storeInviteTTL)
+	member := &models.StoreMember{
+		StoreID:         store.ID,
+		Email:           email,
+		Role:            req.Role,
+		Status:          models.StoreMemberInvited,
+		InviteTokenHash: utils.HashToken(token),
+		InviteExpiresAt: &expiresAt,
+		InvitedBy:       invitedBy,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+
+	if !existing.ID.IsZero() {
+		member.ID = existing.ID
+		member.CreatedAt = existing.CreatedAt
+		_, err = storeMemberCollection.ReplaceOne(ctx, bson.M{"_id": existing.ID}, member)
+		if err != nil {
+			return nil, err
+		}
+		return member, nil
+	}
+
+	result, err := storeMemberCollection.InsertOne(ctx, member)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrStoreMemberExists
+		}
+		return nil, err
+	}
+
+	member.ID = result.InsertedID.(primitive.ObjectID)
+	return member, nil
+}
+
+// sendStoreInviteEmail sends the invite token to the invited address
+func sendStoreInviteEmail(store *models.Store, email, role, token string) error {
+	return mailSender.Send(mailer.Message{
+		To:      email,
+		Subject: fmt.Sprintf("You are invited to join %s", store.Name),
+		Body: fmt.Sprintf("Hi,\n\nYou have been invited to join %s as %s. Sign in with this email address and accept the invite by opening this link:\n\n%s\n\nThe invite expires in 7 days.\n",
+			store.Name, role, appLink("/store-invites/accept", token)),
+	})
+}
+
+// AcceptStoreInvite redeems an invite token for the authenticated user, whose
+// email must match the invited address
+func AcceptStoreInvite(token string, userID primitive.ObjectID, email string) (*models.StoreMember, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var member models.StoreMember
+	err := storeMemberCollection.FindOne(ctx, bson.M{
+		"invite_token_hash": utils.HashToken(token),
+		"status":            models.StoreMemberInvited,
+		"invite_expires_at": bson.M{"$gt": time.Now()},
+	}).Decode(&member)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrStoreInviteInvalid
+		}
+		return nil, err
+	}
+	if !strings.EqualFold(member.Email, email) {
+		return nil, ErrStoreInviteEmailMismatch
+	}
+
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"user_id":     userID,
+			"status":      models.StoreMemberActive,
+			"accepted_at": now,
+			"updated_at":  now,
+		},
+		"$unset": bson.M{"invite_token_hash": "", "invite_expires_at": ""},
+	}
+
+	// Filter on the status so a token can only be redeemed once
+	result, err := storeMemberCollection.UpdateOne(ctx, bson.M{"_id": member.ID, "status": models.StoreMemberInvited}, update)
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, ErrStoreInviteInvalid
+	}
+
+	return GetStoreMemberByID(member.ID.Hex())
+}
+
+// GetStoreMemberByID retrieves a store member by ID
+func GetStoreMemberByID(memberID string) (*models.StoreMember, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(memberID)
+	if err != nil {
+		return nil, errors.New("invalid member ID")
+	}
+
+	var member models.StoreMember
+	err = storeMemberCollection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&member)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("member not found")
+		}
+		return nil, err
+	}
+
+	return &member, nil
+}
+
+// GetStoreMembers retrieves the members and pending invites of a store
+func GetStoreMembers(storeID string) ([]models.StoreMember, error) {
+	objectID, err := primitive.ObjectIDFromHex(storeID)
+	if err != nil {
+		return nil, errors.New("invalid store ID")
+	}
+
+	return findStoreMembers(bson.M{"store_id": objectID})
+}
+
+// GetPendingStoreInvites retrieves the open invites sent to an email address
+func GetPendingStoreInvites(email string) ([]models.StoreMember, error) {
+	return findStoreMembers(bson.M{
+		"email":             strings.ToLower(email),
+		"status":            models.StoreMemberInvited,
+		"invite_expires_at": bson.M{"$gt": time.Now()},
+	})
+}
+
+// GetActiveStoreMembership retrieves a user's active membership of a store
+func GetActiveStoreMembership(storeID, userID primitive.ObjectID) (*models.StoreMember, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var member models.StoreMember
+	err := storeMemberCollection.FindOne(ctx, bson.M{
+		"store_id": storeID,
+		"user_id":  userID,
+		"status":   models.StoreMemberActive,
+	}).Decode(&member)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("member not found")
+		}
+		return nil, err
+	}
+
+	return &member, nil
+}
+
+// GetMyStores retrieves the stores a user owns, followed by the stores where
+// the user is active staff, each with the user's role in it
+func GetMyStores(userID primitive.ObjectID) ([]models.MyStoreResponse, error) {
+	owned, err := GetStoresByOwner(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	myStores := []models.MyStoreResponse{}
+	for _, store := range owned {
+		myStores = append(myStores, models.MyStoreResponse{StoreResponse: store.ToStoreResponse(), Role: models.StoreRoleOwner})
+	}
+
+	memberships, err := findStoreMembers(bson.M{"user_id": userID, "status": models.StoreMemberActive})
+	if err != nil {
+		return nil, err
+	}
+	if len(memberships) == 0 {
+		return myStores, nil
+	}
+
+	roles := make(map[primitive.ObjectID]string, len(memberships))
+	storeIDs := make([]primitive.ObjectID, 0, len(memberships))
+	for _, membership := range memberships {
+		roles[membership.StoreID] = membership.Role
+		storeIDs = append(storeIDs, membership.StoreID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := storeCollection.Find(ctx, bson.M{"_id": bson.M{"$in": storeIDs}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var staffed []models.Store
+	if err = cursor.All(ctx, &staffed); err != nil {
+		return nil, err
+	}
+	for _, store := range staffed {
+		myStores = append(myStores, models.MyStoreResponse{StoreResponse: store.ToStoreResponse(), Role: roles[store.ID]})
+	}
+
+	return myStores, nil
+}
+
+// UpdateStoreMemberRole changes the role of a member of the given store
+func UpdateStoreMemberRole(storeID primitive.ObjectID, memberID string, req models.UpdateStoreMemberRequest, actorRole string) (*models.StoreMember, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	member, err := getStoreMemberOfStore(storeID, memberID)
+	if err != nil {
+		return nil, err
+	}
+	// Managers may neither promote to nor demote from manager
+	if err := checkStaffRoleGrant(actorRole, req.Role); err != nil {
+		return nil, err
+	}
+	if err := checkStaffRoleGrant(actorRole, member.Role); err != nil {
+		return nil, err
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"role":       req.Role,
+			"updated_at": time.Now(),
+		},
+	}
+
+	_, err = storeMemberCollection.UpdateOne(ctx, bson.M{"_id": member.ID}, update)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetStoreMemberByID(memberID)
+}
+
+// RemoveStoreMember removes a member or revokes an invite of the given store
+func RemoveStoreMember(storeID primitive.ObjectID, memberID string, actorRole string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	member, err := getStoreMemberOfStore(storeID, memberID)
+	if err != nil {
+		return err
+	}
+	if err := checkStaffRoleGrant(actorRole, member.Role); err != nil {
+		return err
+	}
+
+	_, err = storeMemberCollection.DeleteOne(ctx, bson.M{"_id": member.ID})
+	return err
+}
+
+// getStoreMemberOfStore retrieves a member and checks it belongs to the store
+func getStoreMemberOfStore(storeID primitive.ObjectID, memberID string) (*models.StoreMember, error) {
+	member, err := GetStoreMemberByID(memberID)
+	if err != nil {
+		return nil, err
+	}
+	if member.StoreID != storeID {
+		return nil, errors.New("member not found")
+	}
+	return member, nil
+}
+
+// checkStaffRoleGrant checks that a store role may grant or revoke another role.
+// Only owners (and admins, who act as owners) may manage managers.
+func checkStaffRoleGrant(actorRole, role string) error {
+	if role == models.StoreRoleManager && actorRole != models.StoreRoleOwner {
+		return ErrStoreAccessDenied
+	}
+	return nil
+}
+
+// findStoreMembers retrieves the store members matching a filter, oldest first
+func findStoreMembers(filter bson.M) ([]models.StoreMember, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := storeMemberCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var members []models.StoreMember
+	if err = cursor.All(ctx, &members); err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
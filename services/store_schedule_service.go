@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"ordernew/config"
+	"ordernew/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrInvalidOpeningHours is returned when a store's time zone or opening hours cannot be used
+var ErrInvalidOpeningHours = errors.New("invalid opening hours")
+
+// legacyTimeLayouts are the formats found in the old free-form opening_time/closing_time fields
+var legacyTimeLayouts = []string{"15:04", "3:04 PM", "3:04PM", "3 PM", "3PM", "15.04"}
+
+// StartStoreScheduler starts a background loop that opens and closes stores
+// according to their opening hours
+func StartStoreScheduler() {
+	interval, err := time.ParseDuration(config.AppConfig.StoreSchedulerInterval)
+	if err != nil || interval <= 0 {
+		interval = time.Minute // Default to 1 minute
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := ApplyStoreSchedules(time.Now()); err != nil {
+				log.Println("Warning: store scheduler failed:", err)
+			}
+			<-ticker.C
+		}
+	}()
+
+	log.Printf("Store scheduler started (every %s)", interval)
+}
+
+// ApplyStoreSchedules sets is_open on every scheduled store whose opening hours
+// changed state since the last run. Only transitions are applied, so a store
+// opened or closed by hand keeps that status until its next scheduled change.
+func ApplyStoreSchedules(now time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cursor, err := storeCollection.Find(ctx, bson.M{
+		"is_active":     true,
+		"opening_hours": bson.M{"$ne": nil},
+	})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var stores []models.Store
+	if err = cursor.All(ctx, &stores); err != nil {
+		return err
+	}
+
+	for _, store := range stores {
+		state := models.ScheduleStateClosed
+		if store.IsWithinOpeningHours(now) {
+			state = models.ScheduleStateOpen
+		}
+		if state == store.ScheduleState {
+			continue
+		}
+
+		// Filter on the previous state so concurrent runs apply a transition once
+		filter := bson.M{"_id": store.ID, "schedule_state": store.ScheduleState}
+		if store.ScheduleState == "" {
+			filter["schedule_state"] = bson.M{"$exists": false}
+		}
+		update := bson.M{
+			"$set": bson.M{
+				"is_open":        state == models.ScheduleStateOpen,
+				"schedule_state": state,
+				"updated_at":     now,
+			},
+		}
+		if _, err := storeCollection.UpdateOne(ctx, filter, update); err != nil {
+			log.Printf("Warning: failed to apply schedule to store %s: %v", store.ID.Hex(), err)
+			continue
+		}
+		log.Printf("Store %s is now %s (scheduled)", store.ID.Hex(), state)
+	}
+
+	return nil
+}
+
+// MigrateStoreOpeningHours converts the legacy free-form opening_time and
+// closing_time fields into a weekly schedule and removes them. Values that
+// cannot be parsed leave the store without a schedule.
+func MigrateStoreOpeningHours() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	filter := bson.M{"$or": bson.A{
+		bson.M{"opening_time": bson.M{"$exists": true}},
+		bson.M{"closing_time": bson.M{"$exists": true}},
+	}}
+	cursor, err := storeCollection.Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	migrated := 0
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID           primitive.ObjectID   `bson:"_id"`
+			OpeningTime  string               `bson:"opening_time"`
+			ClosingTime  string               `bson:"closing_time"`
+			OpeningHours *models.OpeningHours `bson:"opening_hours"`
+			Timezone     string               `bson:"timezone"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+
+		update := bson.M{"$unset": bson.M{"opening_time": "", "closing_time": ""}}
+		set := bson.M{}
+		if doc.Timezone == "" {
+			set["timezone"] = config.AppConfig.DefaultTimezone
+		}
+		if doc.OpeningHours == nil {
+			openTime, openErr := parseLegacyTime(doc.OpeningTime)
+			closeTime, closeErr := parseLegacyTime(doc.ClosingTime)
+			if openErr == nil && closeErr == nil {
+				hours := &models.OpeningHours{Weekly: map[string][]models.TimeInterval{}, Overrides: []models.ScheduleOverride{}}
+				for _, day := range models.Weekdays {
+					hours.Weekly[day] = []models.TimeInterval{{Open: openTime, Close: closeTime}}
+				}
+				set["opening_hours"] = hours
+			} else if doc.OpeningTime != "" || doc.ClosingTime != "" {
+				log.Printf("Warning: could not migrate opening hours %q-%q of store %s", doc.OpeningTime, doc.ClosingTime, doc.ID.Hex())
+			}
+		}
+		if len(set) > 0 {
+			update["$set"] = set
+		}
+
+		if _, err := storeCollection.UpdateOne(ctx, bson.M{"_id": doc.ID}, update); err != nil {
+			return err
+		}
+		migrated++
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	if migrated > 0 {
+		log.Printf("Migrated opening hours of %d store(s)", migrated)
+	}
+	return nil
+}
+
+// parseLegacyTime converts an old free-form time such as "9:00 AM" to "HH:MM"
+func parseLegacyTime(value string) (string, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	for _, layout := range legacyTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Format("15:04"), nil
+		}
+	}
+	return "", fmt.Errorf("unrecognised time %q", value)
+}
+
+// validateTimezone checks that name is a known IANA time zone
+func validateTimezone(name string) error {
+	if _, err := time.LoadLocation(name); err != nil {
+		return fmt.Errorf("%w: unknown time zone %q", ErrInvalidOpeningHours, name)
+	}
+	return nil
+}
+
+// validateOpeningHours checks the days, times and override dates of a schedule
+func validateOpeningHours(hours *models.OpeningHours) error {
+	for day, intervals := range hours.Weekly {
+		if !containsString(models.Weekdays, day) {
+			return fmt.Errorf("%w: invalid day %q, expected one of %s", ErrInvalidOpeningHours, day, strings.Join(models.Weekdays, ", "))
+		}
+		if err := validateTimeIntervals(intervals); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidOpeningHours, day, err)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, override := range hours.Overrides {
+		if _, err := time.Parse(models.OverrideDateLayout, override.Date); err != nil {
+			return fmt.Errorf("%w: invalid override date %q, expected YYYY-MM-DD", ErrInvalidOpeningHours, override.Date)
+		}
+		if seen[override.Date] {
+			return fmt.Errorf("%w: duplicate override for %s", ErrInvalidOpeningHours, override.Date)
+		}
+		seen[override.Date] = true
+		if err := validateTimeIntervals(override.Intervals); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidOpeningHours, override.Date, err)
+		}
+	}
+
+	return nil
+}
+
+// validateTimeIntervals checks the format of opening intervals
+func validateTimeIntervals(intervals []models.TimeInterval) error {
+	for _, interval := range intervals {
+		openAt, err := models.ParseClock(interval.Open)
+		if err != nil {
+			return err
+		}
+		if openAt == 24*60 {
+			return errors.New("an interval cannot open at 24:00")
+		}
+		if _, err := models.ParseClock(interval.Close); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -8,6 +8,8 @@ import (
 
 	"ordernew/config"
 	"ordernew/models"
+	"ordernew/money"
+	"ordernew/utils"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -16,6 +18,9 @@ import (
 
 var storeCollection *mongo.Collection
 
+// ErrInvalidStoreLocale is returned for an unknown store currency or locale
+var ErrInvalidStoreLocale = errors.New("invalid currency or locale")
+
 func init() {
 	// This will be set after database connection
 	// storeCollection will be initialized in InitCollections
@@ -31,20 +36,57 @@ func CreateStore(req models.CreateStoreRequest, ownerID primitive.ObjectID) (*mo
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	timezone := req.Timezone
+	if timezone == "" {
+		timezone = config.AppConfig.DefaultTimezone
+	}
+	if err := validateTimezone(timezone); err != nil {
+		return nil, err
+	}
+	if req.OpeningHours != nil {
+		if err := validateOpeningHours(req.OpeningHours); err != nil {
+			return nil, err
+		}
+	}
+	if req.Tax != nil {
+		if err := validateTaxSettings(req.Tax); err != nil {
+			return nil, err
+		}
+	}
+
+	currency := req.Currency
+	if currency == "" {
+		currency = config.AppConfig.DefaultCurrency
+	}
+	currency, err := money.NormalizeCurrency(currency)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidStoreLocale, err)
+	}
+	locale := req.Locale
+	if locale == "" {
+		locale = config.AppConfig.DefaultLocale
+	}
+	if err := validateLocale(locale); err != nil {
+		return nil, err
+	}
+
 	// Create store
 	store := &models.Store{
-		Name:        req.Name,
-		Description: req.Description,
-		Address:     req.Address,
-		Phone:       req.Phone,
-		Email:       req.Email,
-		OwnerID:     ownerID,
-		IsOpen:      true,
-		IsActive:    true,
-		OpeningTime: req.OpeningTime,
-		ClosingTime: req.ClosingTime,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		Name:         req.Name,
+		Description:  req.Description,
+		Address:      req.Address,
+		Phone:        req.Phone,
+		Email:        req.Email,
+		OwnerID:      ownerID,
+		IsOpen:       true,
+		IsActive:     true,
+		Timezone:     timezone,
+		Currency:     currency,
+		Locale:       locale,
+		OpeningHours: req.OpeningHours,
+		Tax:          req.Tax,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
 	}
 
 	// Generate QR code data (storeID will be set after insert)
@@ -52,10 +94,11 @@ func CreateStore(req models.CreateStoreRequest, ownerID primitive.ObjectID) (*mo
 	if err != nil {
 		return nil, err
 	}
+	forgetStoreOwner(ownerID)
 
-	// Set the ID and generate QR code data
+	// Set the ID and generate QR code data (the customer menu URL)
 	store.ID = result.InsertedID.(primitive.ObjectID)
-	store.QRCodeData = fmt.Sprintf("store_id=%s", store.ID.Hex())
+	store.QRCodeData = utils.BuildMenuURL(store.ID.Hex(), "")
 
 	// Update with QR code data
 	update := bson.M{
@@ -172,11 +215,40 @@ func UpdateStore(storeID string, req models.UpdateStoreRequest) (*models.Store,
 	if req.IsActive != nil {
 		update["$set"].(bson.M)["is_active"] = *req.IsActive
 	}
-	if req.OpeningTime != "" {
-		update["$set"].(bson.M)["opening_time"] = req.OpeningTime
+	if req.Timezone != "" {
+		if err := validateTimezone(req.Timezone); err != nil {
+			return nil, err
+		}
+		update["$set"].(bson.M)["timezone"] = req.Timezone
+	}
+	if req.Locale != "" {
+		if err := validateLocale(req.Locale); err != nil {
+			return nil, err
+		}
+		update["$set"].(bson.M)["locale"] = req.Locale
 	}
-	if req.ClosingTime != "" {
-		update["$set"].(bson.M)["closing_time"] = req.ClosingTime
+	if req.OpeningHours != nil {
+		if err := validateOpeningHours(req.OpeningHours); err != nil {
+			return nil, err
+		}
+		update["$set"].(bson.M)["opening_hours"] = req.OpeningHours
+	}
+	if req.ClearOpeningHours {
+		delete(update["$set"].(bson.M), "opening_hours")
+		update["$unset"] = bson.M{"opening_hours": "", "schedule_state": ""}
+	}
+	if req.Tax != nil {
+		if err := validateTaxSettings(req.Tax); err != nil {
+			return nil, err
+		}
+		update["$set"].(bson.M)["tax"] = req.Tax
+	}
+	if req.ClearTax {
+		delete(update["$set"].(bson.M), "tax")
+		if update["$unset"] == nil {
+			update["$unset"] = bson.M{}
+		}
+		update["$unset"].(bson.M)["tax"] = ""
 	}
 
 	_, err = storeCollection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
@@ -197,13 +269,21 @@ func DeleteStore(storeID string) error {
 		return errors.New("invalid store ID")
 	}
 
-	result, err := storeCollection.DeleteOne(ctx, bson.M{"_id": objectID})
+	var store models.Store
+	err = storeCollection.FindOneAndDelete(ctx, bson.M{"_id": objectID}).Decode(&store)
 	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return errors.New("store not found")
+		}
 		return err
 	}
+	// The owner may no longer own any store, which the two-factor policy looks at
+	forgetStoreOwner(store.OwnerID)
 
-	if result.DeletedCount == 0 {
-		return errors.New("store not found")
+	// Staff memberships and invites go with the store
+	_, err = storeMemberCollection.DeleteMany(ctx, bson.M{"store_id": objectID})
+	if err != nil {
+		return err
 	}
 
 	return nil
@@ -240,3 +320,11 @@ func ToggleStoreStatus(storeID string) (*models.Store, error) {
 
 	return GetStoreByID(storeID)
 }
+
+// validateLocale checks that locale is a BCP 47 tag such as "en-US"
+func validateLocale(locale string) error {
+	if !money.ValidLocale(locale) {
+		return fmt.Errorf("%w: unknown locale %q", ErrInvalidStoreLocale, locale)
+	}
+	return nil
+}
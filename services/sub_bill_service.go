@@ -0,0 +1,667 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"ordernew/config"
+	"ordernew/models"
+	"ordernew/money"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var subBillCollection *mongo.Collection
+
+var (
+	// ErrInvalidSplit is returned for a bill split that does not cover the session's orders exactly
+	ErrInvalidSplit = errors.New("invalid bill split")
+	// ErrSplitLocked is returned when changing the split of a bill that payments were already taken for
+	ErrSplitLocked = errors.New("the bill cannot be split again once payments have been taken")
+	// ErrSessionBalanceDue is returned when closing a dining session that is not fully paid
+	ErrSessionBalanceDue = errors.New("dining session has a balance due")
+)
+
+// InitSubBillCollection initializes the sub-bill collection
+func InitSubBillCollection() {
+	subBillCollection = config.GetCollection("sub_bills")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := subBillCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "session_id", Value: 1}},
+	})
+	if err != nil {
+		log.Println("Warning: failed to create sub-bill index:", err)
+	}
+}
+
+// SplitDiningSession splits the bill of an open dining session into sub-bills that
+// are paid independently. A new split replaces the previous one, as long as nothing
+// has been paid yet.
+func SplitDiningSession(sessionID string, req models.SplitBillRequest) ([]models.SubBill, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	session, err := GetDiningSessionByID(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status != models.DiningSessionOpen {
+		return nil, fmt.Errorf("%w: the dining session is closed", ErrInvalidSplit)
+	}
+
+	// No payment may start on the session while its sub-bills are replaced
+	unlock, err := lockForPayment(ctx, diningSessionCollection, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return splitSession(ctx, session, req)
+}
+
+// splitSession replaces the sub-bills of a dining session. The caller holds the session's payment lock.
+func splitSession(ctx context.Context, session *models.DiningSession, req models.SplitBillRequest) ([]models.SubBill, error) {
+	orders, err := GetOrdersBySession(session.ID)
+	if err != nil {
+		return nil, err
+	}
+	orders = activeSessionOrders(orders)
+	if len(orders) == 0 {
+		return nil, fmt.Errorf("%w: the dining session has no orders to split", ErrInvalidSplit)
+	}
+
+	store, err := GetStoreByID(session.StoreID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkSplitUnlocked(ctx, session.ID, orders); err != nil {
+		return nil, err
+	}
+
+	var bills []models.SubBill
+	switch req.Mode {
+	case models.SplitByItem:
+		bills, err = splitByItem(orders, req.Bills)
+	case models.SplitEvenly:
+		if req.Count < 2 {
+			return nil, fmt.Errorf("%w: an even split needs a count of at least 2", ErrInvalidSplit)
+		}
+		whole := wholeBill(orders)
+		bills = divideBill(whole, allocateAmount(whole.Total, make([]int64, req.Count)))
+	case models.SplitCustom:
+		bills, err = splitCustom(orders, req.Bills)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The tip follows the bill: each sub-bill tips in proportion to what it pays
+	weights := make([]int64, len(bills))
+	for i := range bills {
+		weights[i] = int64(bills[i].Total)
+	}
+	for i, tip := range allocateAmount(req.Tip, weights) {
+		bills[i].Tip = tip
+		bills[i].Total += tip
+	}
+
+	orderIDs := make([]primitive.ObjectID, len(orders))
+	for i, order := range orders {
+		orderIDs[i] = order.ID
+	}
+
+	now := time.Now()
+	documents := make([]interface{}, len(bills))
+	for i := range bills {
+		bill := &bills[i]
+		bill.ID = primitive.NewObjectID()
+		bill.SessionID = session.ID
+		bill.StoreID = session.StoreID
+		bill.Mode = req.Mode
+		bill.OrderIDs = orderIDs
+		bill.Currency = store.Currency
+		bill.Status = models.SubBillOpen
+		bill.CreatedAt = now
+		bill.UpdatedAt = now
+		if i < len(req.Bills) && req.Bills[i].Label != "" {
+			bill.Label = req.Bills[i].Label
+		} else {
+			bill.Label = fmt.Sprintf("Guest %d", i+1)
+		}
+		if bill.Items == nil {
+			bill.Items = []models.SubBillItem{}
+		}
+		documents[i] = bill
+	}
+
+	if _, err := subBillCollection.DeleteMany(ctx, bson.M{"session_id": session.ID}); err != nil {
+		return nil, err
+	}
+	if _, err := subBillCollection.InsertMany(ctx, documents); err != nil {
+		return nil, err
+	}
+
+	return bills, nil
+}
+
+// RemoveDiningSessionSplit removes the sub-bills of a dining session, as long as nothing has been paid yet
+func RemoveDiningSessionSplit(sessionID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	session, err := GetDiningSessionByID(sessionID)
+	if err != nil {
+		return err
+	}
+	if session.Status != models.DiningSessionOpen {
+		return fmt.Errorf("%w: the dining session is closed", ErrInvalidSplit)
+	}
+
+	unlock, err := lockForPayment(ctx, diningSessionCollection, session.ID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	orders, err := GetOrdersBySession(session.ID)
+	if err != nil {
+		return err
+	}
+	if err := checkSplitUnlocked(ctx, session.ID, activeSessionOrders(orders)); err != nil {
+		return err
+	}
+
+	_, err = subBillCollection.DeleteMany(ctx, bson.M{"session_id": session.ID})
+	return err
+}
+
+// prepareSplitForCancel is called before an order is cancelled or rejected. If the order
+// is on the split bill of its dining session, it locks the session and makes sure nothing
+// was paid on the split yet, since guests cannot be charged for an order that will not be
+// served. The returned function must then be called with whether the order was cancelled:
+// it splits the bill again without the order and releases the session.
+func prepareSplitForCancel(ctx context.Context, order *models.Order) (func(cancelled bool), error) {
+	done := func(bool) {}
+	if order.SessionID.IsZero() {
+		return done, nil
+	}
+
+	bills, err := findSubBills(ctx, order.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if len(bills) == 0 || !bills[0].CoversOrder(order.ID) {
+		return done, nil
+	}
+
+	unlock, err := lockForPayment(ctx, diningSessionCollection, order.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := GetDiningSessionByID(order.SessionID.Hex())
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	orders, err := GetOrdersBySession(session.ID)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	if err := checkSplitUnlocked(ctx, session.ID, activeSessionOrders(orders)); err != nil {
+		unlock()
+		return nil, fmt.Errorf("%w: the order is on a split bill that has payments; refund them before cancelling", err)
+	}
+
+	return func(cancelled bool) {
+		defer unlock()
+		if cancelled {
+			resplitWithout(ctx, session, bills, order.ID)
+		}
+	}, nil
+}
+
+// resplitWithout splits a session's bill again in the same way, leaving out an order
+// that was cancelled. A by-item split drops the order's lines and any bill left
+// empty; a custom split cannot keep its amounts, so it is removed, as is any split
+// that no longer works. The caller holds the session's payment lock.
+func resplitWithout(ctx context.Context, session *models.DiningSession, bills []models.SubBill, orderID primitive.ObjectID) {
+	req := models.SplitBillRequest{Mode: bills[0].Mode}
+	for _, bill := range bills {
+		req.Tip += bill.Tip
+	}
+
+	switch req.Mode {
+	case models.SplitEvenly:
+		req.Count = len(bills)
+		for _, bill := range bills {
+			req.Bills = append(req.Bills, models.SplitBillShareRequest{Label: bill.Label})
+		}
+	case models.SplitByItem:
+		for _, bill := range bills {
+			share := models.SplitBillShareRequest{Label: bill.Label}
+			for _, item := range bill.Items {
+				if item.OrderID != orderID {
+					share.Items = append(share.Items, models.SplitBillItemRequest{OrderID: item.OrderID.Hex(), ItemIndex: item.ItemIndex})
+				}
+			}
+			if len(share.Items) > 0 {
+				req.Bills = append(req.Bills, share)
+			}
+		}
+	default:
+		req.Mode = ""
+	}
+
+	if req.Mode != "" {
+		if _, err := splitSession(ctx, session, req); err == nil {
+			return
+		}
+	}
+	if _, err := subBillCollection.DeleteMany(ctx, bson.M{"session_id": session.ID}); err != nil {
+		log.Println("Warning: failed to remove bill split:", err)
+	}
+}
+
+// GetSubBillByID retrieves a sub-bill by ID
+func GetSubBillByID(billID string) (*models.SubBill, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(billID)
+	if err != nil {
+		return nil, errors.New("invalid sub-bill ID")
+	}
+
+	var bill models.SubBill
+	err = subBillCollection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&bill)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("sub-bill not found")
+		}
+		return nil, err
+	}
+
+	return &bill, nil
+}
+
+// IsDiningSessionCustomer reports whether a user placed one of the orders of a dining session
+func IsDiningSessionCustomer(sessionID, userID primitive.ObjectID) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	count, err := orderCollection.CountDocuments(ctx, bson.M{"session_id": sessionID, "customer_id": userID})
+	return err == nil && count > 0
+}
+
+// splitByItem builds the sub-bills of a by-item split. Every line of the orders
+// must be given to at least one sub-bill; a line given to several is shared evenly.
+func splitByItem(orders []models.Order, shares []models.SplitBillShareRequest) ([]models.SubBill, error) {
+	if len(shares) < 2 {
+		return nil, fmt.Errorf("%w: a by-item split needs at least two bills", ErrInvalidSplit)
+	}
+
+	orderIndex := make(map[string]int, len(orders))
+	owners := make([][][]int, len(orders))
+	for o, order := range orders {
+		orderIndex[order.ID.Hex()] = o
+		owners[o] = make([][]int, len(order.Items))
+	}
+
+	for b, share := range shares {
+		if len(share.Items) == 0 {
+			return nil, fmt.Errorf("%w: bill %d has no items", ErrInvalidSplit, b+1)
+		}
+		for _, item := range share.Items {
+			o, ok := orderIndex[item.OrderID]
+			if !ok {
+				return nil, fmt.Errorf("%w: order %s is not an open order of this dining session", ErrInvalidSplit, item.OrderID)
+			}
+			if item.ItemIndex >= len(orders[o].Items) {
+				return nil, fmt.Errorf("%w: order %s has no item %d", ErrInvalidSplit, item.OrderID, item.ItemIndex)
+			}
+			line := owners[o][item.ItemIndex]
+			if len(line) > 0 && line[len(line)-1] == b {
+				return nil, fmt.Errorf("%w: item %d of order %s is listed twice on bill %d", ErrInvalidSplit, item.ItemIndex, item.OrderID, b+1)
+			}
+			owners[o][item.ItemIndex] = append(line, b)
+		}
+	}
+
+	for o, order := range orders {
+		for i, item := range order.Items {
+			if len(owners[o][i]) == 0 {
+				return nil, fmt.Errorf("%w: %q (item %d of order %s) is not on any bill", ErrInvalidSplit, item.Name, i, order.ID.Hex())
+			}
+		}
+	}
+
+	return allocateOrders(orders, owners, len(shares), true), nil
+}
+
+// splitCustom builds the sub-bills of a split by amount. The amounts must add up to the bill.
+func splitCustom(orders []models.Order, shares []models.SplitBillShareRequest) ([]models.SubBill, error) {
+	if len(shares) < 2 {
+		return nil, fmt.Errorf("%w: a custom split needs at least two bills", ErrInvalidSplit)
+	}
+
+	whole := wholeBill(orders)
+
+	amounts := make([]money.Amount, len(shares))
+	var sum money.Amount
+	for b, share := range shares {
+		if share.Amount <= 0 {
+			return nil, fmt.Errorf("%w: bill %d needs an amount", ErrInvalidSplit, b+1)
+		}
+		amounts[b] = share.Amount
+		sum += share.Amount
+	}
+	if sum != whole.Total {
+		return nil, fmt.Errorf("%w: the amounts add up to %s but the bill is %s", ErrInvalidSplit,
+			money.Format(sum, orders[0].Currency, config.AppConfig.DefaultLocale),
+			money.Format(whole.Total, orders[0].Currency, config.AppConfig.DefaultLocale))
+	}
+
+	return divideBill(whole, amounts), nil
+}
+
+// wholeBill totals the items, service charges and taxes of orders as a single bill
+func wholeBill(orders []models.Order) models.SubBill {
+	owners := make([][][]int, len(orders))
+	for o, order := range orders {
+		owners[o] = make([][]int, len(order.Items))
+		for i := range order.Items {
+			owners[o][i] = []int{0}
+		}
+	}
+	return allocateOrders(orders, owners, 1, false)[0]
+}
+
+// divideBill divides a whole bill into sub-bills of the given amounts, which add up
+// to its total. The service charge and taxes are allocated in proportion to them.
+func divideBill(whole models.SubBill, amounts []money.Amount) []models.SubBill {
+	weights := make([]int64, len(amounts))
+	for b, amount := range amounts {
+		weights[b] = int64(amount)
+	}
+
+	// Exclusive taxes are on top of the subtotal; inclusive ones are part of it
+	exclusiveTax := whole.Total - whole.Subtotal - whole.ServiceCharge
+	charges := allocateAmount(whole.ServiceCharge, weights)
+	taxes := allocateAmount(whole.TaxTotal, weights)
+	exclusiveTaxes := allocateAmount(exclusiveTax, weights)
+
+	bills := make([]models.SubBill, len(amounts))
+	for b, amount := range amounts {
+		bills[b] = models.SubBill{
+			Subtotal:      amount - charges[b] - exclusiveTaxes[b],
+			ServiceCharge: charges[b],
+			TaxTotal:      taxes[b],
+			Total:         amount,
+		}
+	}
+	return bills
+}
+
+// allocateOrders divides orders between n sub-bills. owners lists, for each line of
+// each order, the sub-bills sharing it. A line and its taxes are divided evenly between
+// its sub-bills, and the service charge of an order follows its lines, so the sub-bills
+// add up exactly to the orders.
+func allocateOrders(orders []models.Order, owners [][][]int, n int, listItems bool) []models.SubBill {
+	bills := make([]models.SubBill, n)
+
+	for o, order := range orders {
+		lineShares := make([]int64, n)
+		for i, item := range order.Items {
+			shared := owners[o][i]
+			evenly := make([]int64, len(shared))
+			for k := range evenly {
+				evenly[k] = 1
+			}
+			nets := allocateAmount(item.LineTotal-item.Discount, evenly)
+			taxes := allocateAmount(sumLineTaxes(item.Taxes), evenly)
+
+			for k, b := range shared {
+				lineShares[b] += int64(nets[k])
+				addToSubBill(&bills[b], &order, nets[k], 0, taxes[k])
+				if listItems {
+					bills[b].Items = append(bills[b].Items, models.SubBillItem{
+						OrderID:   order.ID,
+						ItemIndex: i,
+						Name:      item.Name,
+						Quantity:  item.Quantity,
+						SharedBy:  len(shared),
+						Amount:    nets[k],
+					})
+				}
+			}
+		}
+
+		if charge := order.ServiceCharge; charge != nil {
+			charges := allocateAmount(charge.Amount, lineShares)
+			taxes := allocateAmount(sumLineTaxes(charge.Taxes), lineShares)
+			for b := range bills {
+				addToSubBill(&bills[b], &order, 0, charges[b], taxes[b])
+			}
+		}
+	}
+
+	return bills
+}
+
+// addToSubBill adds part of an order's items, service charge and taxes to a sub-bill
+func addToSubBill(bill *models.SubBill, order *models.Order, subtotal, charge, tax money.Amount) {
+	bill.Subtotal += subtotal
+	bill.ServiceCharge += charge
+	bill.TaxTotal += tax
+	bill.Total += subtotal + charge
+	// Inclusive taxes are already part of the prices
+	if !order.PricesIncludeTax {
+		bill.Total += tax
+	}
+}
+
+// sumLineTaxes totals the taxes of a line
+func sumLineTaxes(taxes []models.LineTax) money.Amount {
+	var total money.Amount
+	for _, tax := range taxes {
+		total += tax.Amount
+	}
+	return total
+}
+
+// allocateAmount divides an amount in proportion to weights, handing the minor units
+// lost to rounding to the largest remainders so the parts add up exactly. Without
+// any weight the amount is divided evenly.
+func allocateAmount(total money.Amount, weights []int64) []money.Amount {
+	parts := make([]money.Amount, len(weights))
+	if len(weights) == 0 || total == 0 {
+		return parts
+	}
+
+	var sum int64
+	for _, weight := range weights {
+		sum += weight
+	}
+	if sum <= 0 {
+		weights = make([]int64, len(parts))
+		for i := range weights {
+			weights[i] = 1
+		}
+		sum = int64(len(weights))
+	}
+
+	remainders := make([]int64, len(parts))
+	var allocated money.Amount
+	for i, weight := range weights {
+		parts[i] = money.Amount(int64(total) * weight / sum)
+		remainders[i] = int64(total) * weight % sum
+		allocated += parts[i]
+	}
+
+	// Earlier parts win ties, so an even split gives the extra units to the first sub-bills
+	for left := total - allocated; left > 0; left-- {
+		best := 0
+		for i := range remainders {
+			if remainders[i] > remainders[best] {
+				best = i
+			}
+		}
+		parts[best]++
+		remainders[best] = -1
+	}
+
+	return parts
+}
+
+// activeSessionOrders leaves out the cancelled and rejected orders of a dining session
+func activeSessionOrders(orders []models.Order) []models.Order {
+	active := []models.Order{}
+	for _, order := range orders {
+		if order.Status != models.OrderStatusCancelled && order.Status != models.OrderStatusRejected {
+			active = append(active, order)
+		}
+	}
+	return active
+}
+
+// checkSplitUnlocked makes sure nothing was paid on a session's sub-bills or orders,
+// so the bill can still be split again
+func checkSplitUnlocked(ctx context.Context, sessionID primitive.ObjectID, orders []models.Order) error {
+	bills, err := findSubBills(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+
+	count, err := paymentCollection.CountDocuments(ctx, bson.M{
+		"$or":    sessionPaymentFilter(orders, bills),
+		"status": bson.M{"$nin": []string{models.PaymentStatusFailed, models.PaymentStatusVoided}},
+	})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrSplitLocked
+	}
+	return nil
+}
+
+// sessionBalance totals what a dining session owes and what was paid against it,
+// through its orders or its sub-bills
+func sessionBalance(ctx context.Context, orders []models.Order, bills []models.SubBill) (total, tips, paid money.Amount, err error) {
+	for _, order := range orders {
+		total += order.Total
+	}
+	for _, bill := range bills {
+		tips += bill.Tip
+	}
+
+	cursor, err := paymentCollection.Find(ctx, bson.M{"$or": sessionPaymentFilter(orders, bills)})
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var sessionPayments []models.Payment
+	if err = cursor.All(ctx, &sessionPayments); err != nil {
+		return 0, 0, 0, err
+	}
+	for _, payment := range sessionPayments {
+		paid += payment.Net()
+	}
+
+	return total, tips, paid, nil
+}
+
+// sessionPaymentFilter matches the payments of a dining session's orders and sub-bills
+func sessionPaymentFilter(orders []models.Order, bills []models.SubBill) bson.A {
+	orderIDs := make([]primitive.ObjectID, len(orders))
+	for i, order := range orders {
+		orderIDs[i] = order.ID
+	}
+	billIDs := make([]primitive.ObjectID, len(bills))
+	for i, bill := range bills {
+		billIDs[i] = bill.ID
+	}
+	return bson.A{
+		bson.M{"order_id": bson.M{"$in": orderIDs}},
+		bson.M{"sub_bill_id": bson.M{"$in": billIDs}},
+	}
+}
+
+// subBillAmountDue returns what is left to pay on a sub-bill, counting payments
+// that are still pending or authorized as paid
+func subBillAmountDue(ctx context.Context, bill *models.SubBill) (money.Amount, error) {
+	billPayments, err := findSubBillPayments(ctx, bill.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	due := bill.Total
+	for _, payment := range billPayments {
+		if payment.IsOpen() {
+			due -= payment.Amount
+		} else {
+			due -= payment.Net()
+		}
+	}
+	return max(due, 0), nil
+}
+
+// refreshSubBillPayment works out the amount paid and status of a sub-bill from its payments
+func refreshSubBillPayment(ctx context.Context, billID primitive.ObjectID) error {
+	var bill models.SubBill
+	if err := subBillCollection.FindOne(ctx, bson.M{"_id": billID}).Decode(&bill); err != nil {
+		return err
+	}
+
+	billPayments, err := findSubBillPayments(ctx, billID)
+	if err != nil {
+		return err
+	}
+	var paid money.Amount
+	for _, payment := range billPayments {
+		paid += payment.Net()
+	}
+
+	status := models.SubBillOpen
+	if paid >= bill.Total {
+		status = models.SubBillPaid
+	}
+
+	_, err = subBillCollection.UpdateOne(ctx, bson.M{"_id": billID}, bson.M{
+		"$set": bson.M{
+			"amount_paid": paid,
+			"status":      status,
+			"updated_at":  time.Now(),
+		},
+	})
+	return err
+}
+
+// findSubBills loads the sub-bills of a dining session in split order
+func findSubBills(ctx context.Context, sessionID primitive.ObjectID) ([]models.SubBill, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := subBillCollection.Find(ctx, bson.M{"session_id": sessionID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	bills := []models.SubBill{}
+	if err = cursor.All(ctx, &bills); err != nil {
+		return nil, err
+	}
+	return bills, nil
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"testing"
+
+	"ordernew/models"
+	"ordernew/money"
+)
+
+func TestAllocateAmount(t *testing.T) {
+	tests := []struct {
+		name    string
+		total   money.Amount
+		weights []int64
+		want    []money.Amount
+	}{
+		{"even split gives extra units to the first parts", 100, []int64{1, 1, 1}, []money.Amount{34, 33, 33}},
+		{"tie goes to the earlier part", 1, []int64{1, 1}, []money.Amount{1, 0}},
+		{"largest remainder wins", 1000, []int64{1, 2, 3}, []money.Amount{167, 333, 500}},
+		{"largest remainder is not always first", 10, []int64{1, 2}, []money.Amount{3, 7}},
+		{"zero weights divide evenly", 5, []int64{0, 0}, []money.Amount{3, 2}},
+		{"zero weight part gets nothing", 7, []int64{0, 1}, []money.Amount{0, 7}},
+		{"zero total", 0, []int64{1, 2}, []money.Amount{0, 0}},
+		{"no parts", 100, nil, []money.Amount{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := allocateAmount(tt.total, tt.weights)
+			if len(got) != len(tt.want) {
+				t.Fatalf("allocateAmount(%d, %v) = %v, want %v", tt.total, tt.weights, got, tt.want)
+			}
+			var sum money.Amount
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("allocateAmount(%d, %v) = %v, want %v", tt.total, tt.weights, got, tt.want)
+				}
+				sum += got[i]
+			}
+			if len(got) > 0 && sum != tt.total {
+				t.Errorf("parts add up to %d, want %d", sum, tt.total)
+			}
+		})
+	}
+}
+
+func TestDivideBill(t *testing.T) {
+	tests := []struct {
+		name      string
+		whole     models.SubBill
+		inclusive bool
+		count     int
+	}{
+		{"exclusive tax", models.SubBill{Subtotal: 1000, ServiceCharge: 100, TaxTotal: 110, Total: 1210}, false, 3},
+		{"inclusive tax", models.SubBill{Subtotal: 1000, ServiceCharge: 0, TaxTotal: 91, Total: 1000}, true, 3},
+		{"exclusive tax in many small parts", models.SubBill{Subtotal: 10, ServiceCharge: 1, TaxTotal: 1, Total: 12}, false, 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bills := divideBill(tt.whole, allocateAmount(tt.whole.Total, make([]int64, tt.count)))
+			if len(bills) != tt.count {
+				t.Fatalf("got %d bills, want %d", len(bills), tt.count)
+			}
+
+			var sum models.SubBill
+			for _, bill := range bills {
+				sum.Subtotal += bill.Subtotal
+				sum.ServiceCharge += bill.ServiceCharge
+				sum.TaxTotal += bill.TaxTotal
+				sum.Total += bill.Total
+
+				want := bill.Subtotal + bill.ServiceCharge
+				if !tt.inclusive {
+					want += bill.TaxTotal
+				}
+				if bill.Total != want {
+					t.Errorf("bill %+v does not add up to its total", bill)
+				}
+			}
+			if sum.Subtotal != tt.whole.Subtotal || sum.ServiceCharge != tt.whole.ServiceCharge ||
+				sum.TaxTotal != tt.whole.TaxTotal || sum.Total != tt.whole.Total {
+				t.Errorf("bills add up to %+v, want %+v", sum, tt.whole)
+			}
+		})
+	}
+}
+
+func TestAllocateOrders(t *testing.T) {
+	exclusive := models.Order{
+		Items: []models.OrderItem{
+			{Name: "Starter", Quantity: 1, LineTotal: 1000, Taxes: []models.LineTax{{Code: "vat", Amount: 100}}},
+			{Name: "Main", Quantity: 1, LineTotal: 433, Discount: 100, Taxes: []models.LineTax{{Code: "vat", Amount: 33}}},
+		},
+		ServiceCharge: &models.AppliedServiceCharge{Amount: 133, Taxes: []models.LineTax{{Code: "vat", Amount: 13}}},
+	}
+	inclusive := models.Order{
+		PricesIncludeTax: true,
+		Items: []models.OrderItem{
+			{Name: "Dessert", Quantity: 2, LineTotal: 500, Taxes: []models.LineTax{{Code: "vat", Amount: 45}}},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		orders []models.Order
+		owners [][][]int
+		n      int
+		want   []models.SubBill
+	}{
+		{
+			name:   "shared line and service charge follow the lines",
+			orders: []models.Order{exclusive},
+			owners: [][][]int{{{0, 1}, {0}}},
+			n:      2,
+			want: []models.SubBill{
+				{Subtotal: 833, ServiceCharge: 83, TaxTotal: 91, Total: 1007},
+				{Subtotal: 500, ServiceCharge: 50, TaxTotal: 55, Total: 605},
+			},
+		},
+		{
+			name:   "inclusive tax is not added to the total",
+			orders: []models.Order{exclusive, inclusive},
+			owners: [][][]int{{{0, 1}, {0}}, {{1}}},
+			n:      2,
+			want: []models.SubBill{
+				{Subtotal: 833, ServiceCharge: 83, TaxTotal: 91, Total: 1007},
+				{Subtotal: 1000, ServiceCharge: 50, TaxTotal: 100, Total: 1105},
+			},
+		},
+		{
+			name:   "line shared by three",
+			orders: []models.Order{inclusive},
+			owners: [][][]int{{{0, 1, 2}}},
+			n:      3,
+			want: []models.SubBill{
+				{Subtotal: 167, TaxTotal: 15, Total: 167},
+				{Subtotal: 167, TaxTotal: 15, Total: 167},
+				{Subtotal: 166, TaxTotal: 15, Total: 166},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bills := allocateOrders(tt.orders, tt.owners, tt.n, true)
+			if len(bills) != len(tt.want) {
+				t.Fatalf("got %d bills, want %d", len(bills), len(tt.want))
+			}
+
+			var total money.Amount
+			for b, bill := range bills {
+				want := tt.want[b]
+				if bill.Subtotal != want.Subtotal || bill.ServiceCharge != want.ServiceCharge ||
+					bill.TaxTotal != want.TaxTotal || bill.Total != want.Total {
+					t.Errorf("bill %d = {Subtotal:%d ServiceCharge:%d TaxTotal:%d Total:%d}, want %+v",
+						b, bill.Subtotal, bill.ServiceCharge, bill.TaxTotal, bill.Total, want)
+				}
+				total += bill.Total
+			}
+
+			whole := wholeBill(tt.orders)
+			if total != whole.Total {
+				t.Errorf("bills add up to %d, want %d", total, whole.Total)
+			}
+		})
+	}
+}
+
+func TestAllocateOrdersListsItems(t *testing.T) {
+	order := models.Order{Items: []models.OrderItem{
+		{Name: "Pizza", Quantity: 1, LineTotal: 1001},
+		{Name: "Soda", Quantity: 2, LineTotal: 400},
+	}}
+
+	bills := allocateOrders([]models.Order{order}, [][][]int{{{0, 1}, {1}}}, 2, true)
+
+	if len(bills[0].Items) != 1 || len(bills[1].Items) != 2 {
+		t.Fatalf("got %d and %d items, want 1 and 2", len(bills[0].Items), len(bills[1].Items))
+	}
+	shared := bills[0].Items[0]
+	if shared.ItemIndex != 0 || shared.SharedBy != 2 || shared.Amount != 501 {
+		t.Errorf("shared item = %+v, want index 0 shared by 2 for 501", shared)
+	}
+	if own := bills[1].Items[1]; own.ItemIndex != 1 || own.SharedBy != 1 || own.Amount != 400 {
+		t.Errorf("own item = %+v, want index 1 shared by 1 for 400", own)
+	}
+}
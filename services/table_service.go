@@ -0,0 +1,428 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"ordernew/config"
+	"ordernew/models"
+	"ordernew/money"
+	"ordernew/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var tableCollection *mongo.Collection
+var diningSessionCollection *mongo.Collection
+
+// InitTableCollection initializes the table and dining session collections
+func InitTableCollection() {
+	tableCollection = config.GetCollection("tables")
+	diningSessionCollection = config.GetCollection("dining_sessions")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// A table can only have one open dining session at a time
+	_, err := diningSessionCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "table_id", Value: 1}},
+		Options: options.Index().
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"status": models.DiningSessionOpen}),
+	})
+	if err != nil {
+		log.Println("Warning: failed to create dining session index:", err)
+	}
+}
+
+// CreateTable creates a new table for a store
+func CreateTable(req models.CreateTableRequest) (*models.Table, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	storeID, err := primitive.ObjectIDFromHex(req.StoreID)
+	if err != nil {
+		return nil, errors.New("invalid store ID")
+	}
+
+	// Verify store exists
+	_, err = GetStoreByID(req.StoreID)
+	if err != nil {
+		return nil, errors.New("store not found")
+	}
+
+	nonce, err := utils.GenerateRandomToken(8)
+	if err != nil {
+		return nil, errors.New("failed to generate table token")
+	}
+
+	table := &models.Table{
+		ID:         primitive.NewObjectID(),
+		StoreID:    storeID,
+		Name:       req.Name,
+		Capacity:   req.Capacity,
+		IsActive:   true,
+		TokenNonce: nonce,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+	table.QRCodeData = TableMenuURL(table)
+
+	_, err = tableCollection.InsertOne(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+
+	return table, nil
+}
+
+// GetTableByID retrieves a table by ID
+func GetTableByID(tableID string) (*models.Table, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(tableID)
+	if err != nil {
+		return nil, errors.New("invalid table ID")
+	}
+
+	var table models.Table
+	err = tableCollection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&table)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("table not found")
+		}
+		return nil, err
+	}
+
+	return &table, nil
+}
+
+// GetTablesByStore retrieves all tables for a specific store
+func GetTablesByStore(storeID string) ([]models.Table, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(storeID)
+	if err != nil {
+		return nil, errors.New("invalid store ID")
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := tableCollection.Find(ctx, bson.M{"store_id": objectID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var tables []models.Table
+	if err = cursor.All(ctx, &tables); err != nil {
+		return nil, err
+	}
+
+	return tables, nil
+}
+
+// UpdateTable updates an existing table
+func UpdateTable(tableID string, req models.UpdateTableRequest) (*models.Table, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(tableID)
+	if err != nil {
+		return nil, errors.New("invalid table ID")
+	}
+
+	// Build update document
+	update := bson.M{
+		"$set": bson.M{
+			"updated_at": time.Now(),
+		},
+	}
+
+	if req.Name != "" {
+		update["$set"].(bson.M)["name"] = req.Name
+	}
+	if req.Capacity != nil {
+		update["$set"].(bson.M)["capacity"] = *req.Capacity
+	}
+	if req.IsActive != nil {
+		update["$set"].(bson.M)["is_active"] = *req.IsActive
+	}
+
+	result, err := tableCollection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, errors.New("table not found")
+	}
+
+	return GetTableByID(tableID)
+}
+
+// DeleteTable deletes a table
+func DeleteTable(tableID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(tableID)
+	if err != nil {
+		return errors.New("invalid table ID")
+	}
+
+	result, err := tableCollection.DeleteOne(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return errors.New("table not found")
+	}
+
+	return nil
+}
+
+// RegenerateTableToken invalidates a table's current QR code and issues a new one
+func RegenerateTableToken(tableID string) (*models.Table, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	table, err := GetTableByID(tableID)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := utils.GenerateRandomToken(8)
+	if err != nil {
+		return nil, errors.New("failed to generate table token")
+	}
+	table.TokenNonce = nonce
+
+	update := bson.M{
+		"$set": bson.M{
+			"token_nonce":  nonce,
+			"qr_code_data": TableMenuURL(table),
+			"updated_at":   time.Now(),
+		},
+	}
+	_, err = tableCollection.UpdateOne(ctx, bson.M{"_id": table.ID}, update)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetTableByID(tableID)
+}
+
+// ResolveTableToken verifies a scanned table token and returns its table
+func ResolveTableToken(token string) (*models.Table, error) {
+	tableID, err := utils.ParseTableToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	table, err := GetTableByID(tableID)
+	if err != nil {
+		return nil, errors.New("invalid table token")
+	}
+	if !utils.VerifyTableToken(token, table.StoreID.Hex(), table.ID.Hex(), table.TokenNonce) {
+		return nil, errors.New("invalid table token")
+	}
+	if !table.IsActive {
+		return nil, errors.New("table is not active")
+	}
+
+	return table, nil
+}
+
+// OpenDiningSession returns the table's open dining session, starting one if needed
+func OpenDiningSession(table *models.Table) (*models.DiningSession, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	filter := bson.M{"table_id": table.ID, "status": models.DiningSessionOpen}
+	update := bson.M{
+		"$setOnInsert": bson.M{
+			"store_id":   table.StoreID,
+			"table_name": table.Name,
+			"opened_at":  now,
+			"created_at": now,
+			"updated_at": now,
+		},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var session models.DiningSession
+	err := diningSessionCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&session)
+	if mongo.IsDuplicateKeyError(err) {
+		// Another request opened the session at the same time; use theirs
+		err = diningSessionCollection.FindOne(ctx, filter).Decode(&session)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// GetOpenDiningSessionByTable retrieves the open dining session of a table
+func GetOpenDiningSessionByTable(tableID string) (*models.DiningSession, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(tableID)
+	if err != nil {
+		return nil, errors.New("invalid table ID")
+	}
+
+	var session models.DiningSession
+	err = diningSessionCollection.FindOne(ctx, bson.M{
+		"table_id": objectID,
+		"status":   models.DiningSessionOpen,
+	}).Decode(&session)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("table has no open dining session")
+		}
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// GetDiningSessionByID retrieves a dining session by ID
+func GetDiningSessionByID(sessionID string) (*models.DiningSession, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return nil, errors.New("invalid dining session ID")
+	}
+
+	var session models.DiningSession
+	err = diningSessionCollection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&session)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("dining session not found")
+		}
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// BuildDiningSessionResponse loads the orders and sub-bills of a dining session and
+// works out its balance. Cancelled and rejected orders are listed but not counted.
+func BuildDiningSessionResponse(session *models.DiningSession) (*models.DiningSessionResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	orders, err := GetOrdersBySession(session.ID)
+	if err != nil {
+		return nil, err
+	}
+	bills, err := findSubBills(ctx, session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := GetStoreByID(session.StoreID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.DiningSessionResponse{
+		DiningSession: *session,
+		Orders:        []models.OrderResponse{},
+		Bills:         []models.SubBillResponse{},
+		Currency:      store.Currency,
+	}
+	for _, order := range orders {
+		response.Orders = append(response.Orders, order.ToOrderResponse())
+	}
+	for _, bill := range bills {
+		response.Bills = append(response.Bills, bill.ToSubBillResponse())
+	}
+
+	total, tips, paid, err := sessionBalance(ctx, activeSessionOrders(orders), bills)
+	if err != nil {
+		return nil, err
+	}
+	response.Total = total
+	response.TipTotal = tips
+	response.AmountPaid = paid
+	response.BalanceDue = max(total+tips-paid, 0)
+
+	return response, nil
+}
+
+// CloseDiningSession closes the bill of a dining session. The session only closes
+// once its orders, or its sub-bills when the bill is split, are fully paid.
+func CloseDiningSession(sessionID string, closedBy primitive.ObjectID) (*models.DiningSession, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	session, err := GetDiningSessionByID(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status != models.DiningSessionOpen {
+		return nil, errors.New("dining session is already closed")
+	}
+
+	orders, err := GetOrdersBySession(session.ID)
+	if err != nil {
+		return nil, err
+	}
+	bills, err := findSubBills(ctx, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	total, tips, paid, err := sessionBalance(ctx, activeSessionOrders(orders), bills)
+	if err != nil {
+		return nil, err
+	}
+	if balance := total + tips - paid; balance > 0 {
+		store, err := GetStoreByID(session.StoreID.Hex())
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %s is still to be paid", ErrSessionBalanceDue, money.Format(balance, store.Currency, config.AppConfig.DefaultLocale))
+	}
+
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"status":     models.DiningSessionClosed,
+			"closed_at":  now,
+			"closed_by":  closedBy,
+			"updated_at": now,
+		},
+	}
+	result, err := diningSessionCollection.UpdateOne(ctx, bson.M{
+		"_id":    session.ID,
+		"status": models.DiningSessionOpen,
+	}, update)
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, errors.New("dining session is already closed")
+	}
+
+	return GetDiningSessionByID(sessionID)
+}
+
+// TableMenuURL builds the QR payload of a table: the store menu URL with a signed table token
+func TableMenuURL(table *models.Table) string {
+	token := utils.GenerateTableToken(table.StoreID.Hex(), table.ID.Hex(), table.TokenNonce)
+	return utils.BuildMenuURL(table.StoreID.Hex(), token)
+}
@@ -0,0 +1,332 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"ordernew/models"
+	"ordernew/money"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var (
+	// ErrInvalidTaxSettings is returned for an inconsistent store tax configuration
+	ErrInvalidTaxSettings = errors.New("invalid tax settings")
+	// ErrInvalidReportRange is returned for a missing or malformed report date range
+	ErrInvalidReportRange = errors.New("invalid report date range")
+)
+
+// taxableLine is a priced cart or order line to tax
+type taxableLine struct {
+	Category string
+	Total    money.Amount
+}
+
+// taxResult is the outcome of taxing the lines of a cart or order
+type taxResult struct {
+	Categories    []string           // effective tax category of each line
+	LineTaxes     [][]models.LineTax // taxes of each line
+	ServiceCharge *models.AppliedServiceCharge
+	Taxes         []models.TaxBreakdown
+	TaxTotal      money.Amount
+	Total         money.Amount
+}
+
+// calculateTaxes works out the service charge and taxes of priced lines.
+// Every tax is rounded per line, and the per-rate totals are sums of those
+// rounded amounts, so a receipt's lines always add up to its totals.
+func calculateTaxes(settings *models.TaxSettings, orderType string, lines []taxableLine) taxResult {
+	result := taxResult{
+		Categories: make([]string, len(lines)),
+		LineTaxes:  make([][]models.LineTax, len(lines)),
+		Taxes:      []models.TaxBreakdown{},
+	}
+
+	var subtotal money.Amount
+	for i, line := range lines {
+		result.Categories[i] = line.Category
+		subtotal += line.Total
+	}
+	result.Total = subtotal
+	if settings == nil {
+		return result
+	}
+
+	breakdown := make([]models.TaxBreakdown, len(settings.Rates))
+	used := make([]bool, len(settings.Rates))
+	taxLine := func(category string, total money.Amount) []models.LineTax {
+		rates := ratesForCategory(settings, category)
+		if len(rates) == 0 {
+			return nil
+		}
+		taxes, net := splitLineTaxes(settings, rates, total)
+		for j, index := range rates {
+			breakdown[index].TaxableAmount += net
+			breakdown[index].Amount += taxes[j].Amount
+			used[index] = true
+		}
+		return taxes
+	}
+
+	for i, line := range lines {
+		category := line.Category
+		if category == "" {
+			category = settings.DefaultCategory
+		}
+		result.Categories[i] = category
+		result.LineTaxes[i] = taxLine(category, line.Total)
+	}
+
+	if charge := settings.ServiceCharge; charge != nil && charge.AppliesTo(orderType) && subtotal > 0 {
+		amount := money.Amount(math.Round(float64(subtotal) * charge.Rate / 100))
+		result.ServiceCharge = &models.AppliedServiceCharge{
+			Name:        charge.Name,
+			Rate:        charge.Rate,
+			Amount:      amount,
+			TaxCategory: charge.TaxCategory,
+		}
+		if charge.TaxCategory != "" {
+			result.ServiceCharge.Taxes = taxLine(charge.TaxCategory, amount)
+		}
+		result.Total += amount
+	}
+
+	for i, rate := range settings.Rates {
+		if !used[i] {
+			continue
+		}
+		breakdown[i].Code = rate.Code
+		breakdown[i].Name = rate.Name
+		breakdown[i].Rate = rate.Rate
+		result.Taxes = append(result.Taxes, breakdown[i])
+		result.TaxTotal += breakdown[i].Amount
+	}
+
+	// Inclusive taxes are already part of the prices
+	if !settings.PricesIncludeTax {
+		result.Total += result.TaxTotal
+	}
+
+	return result
+}
+
+// ratesForCategory returns the indexes of the store tax rates that apply to a category
+func ratesForCategory(settings *models.TaxSettings, category string) []int {
+	var indexes []int
+	for i, rate := range settings.Rates {
+		if containsString(rate.Categories, category) {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// splitLineTaxes works out each tax of a line total and the total net of tax.
+// With inclusive pricing the taxes are carved out of the total; otherwise
+// they are charged on top of it.
+func splitLineTaxes(settings *models.TaxSettings, rates []int, total money.Amount) ([]models.LineTax, money.Amount) {
+	divisor := 100.0
+	if settings.PricesIncludeTax {
+		for _, index := range rates {
+			divisor += settings.Rates[index].Rate
+		}
+	}
+
+	taxes := make([]models.LineTax, 0, len(rates))
+	var taxTotal money.Amount
+	for _, index := range rates {
+		rate := settings.Rates[index]
+		amount := money.Amount(math.Round(float64(total) * rate.Rate / divisor))
+		taxes = append(taxes, models.LineTax{Code: rate.Code, Rate: rate.Rate, Amount: amount})
+		taxTotal += amount
+	}
+
+	if settings.PricesIncludeTax {
+		return taxes, total - taxTotal
+	}
+	return taxes, total
+}
+
+// applyOrderTaxes fills in the line taxes, service charge, tax breakdown and total of a new order.
+// Lines are taxed on what the customer pays for them, after discounts.
+func applyOrderTaxes(order *models.Order, settings *models.TaxSettings) {
+	lines := make([]taxableLine, len(order.Items))
+	for i, item := range order.Items {
+		lines[i] = taxableLine{Category: item.TaxCategory, Total: item.LineTotal - item.Discount}
+	}
+
+	result := calculateTaxes(settings, order.OrderType, lines)
+	for i := range order.Items {
+		order.Items[i].TaxCategory = result.Categories[i]
+		order.Items[i].Taxes = result.LineTaxes[i]
+	}
+	order.PricesIncludeTax = settings != nil && settings.PricesIncludeTax
+	order.ServiceCharge = result.ServiceCharge
+	order.Taxes = result.Taxes
+	order.TaxTotal = result.TaxTotal
+	order.Total = result.Total
+}
+
+// applyCartTaxes fills in the line taxes, service charge, tax breakdown and total of a priced cart.
+// Unavailable lines are not taxed, as they are not part of the subtotal; the others are taxed after discounts.
+func applyCartTaxes(response *models.CartResponse, settings *models.TaxSettings, orderType string) {
+	var lines []taxableLine
+	var indexes []int
+	for i, item := range response.Items {
+		if item.IsAvailable {
+			lines = append(lines, taxableLine{Category: item.TaxCategory, Total: item.LineTotal - item.Discount})
+			indexes = append(indexes, i)
+		}
+	}
+
+	result := calculateTaxes(settings, orderType, lines)
+	for j, i := range indexes {
+		response.Items[i].TaxCategory = result.Categories[j]
+		response.Items[i].Taxes = result.LineTaxes[j]
+	}
+	response.PricesIncludeTax = settings != nil && settings.PricesIncludeTax
+	response.ServiceCharge = result.ServiceCharge
+	response.Taxes = result.Taxes
+	response.TaxTotal = result.TaxTotal
+	response.Total = result.Total
+}
+
+// validateTaxSettings checks the rates and service charge of a store tax configuration
+func validateTaxSettings(settings *models.TaxSettings) error {
+	seen := make(map[string]bool)
+	for _, rate := range settings.Rates {
+		if rate.Code == "" {
+			return fmt.Errorf("%w: every rate needs a code", ErrInvalidTaxSettings)
+		}
+		if seen[rate.Code] {
+			return fmt.Errorf("%w: duplicate rate code %q", ErrInvalidTaxSettings, rate.Code)
+		}
+		seen[rate.Code] = true
+		if rate.Rate <= 0 || rate.Rate > 100 {
+			return fmt.Errorf("%w: rate %s must be between 0 and 100 percent", ErrInvalidTaxSettings, rate.Code)
+		}
+		if len(rate.Categories) == 0 {
+			return fmt.Errorf("%w: rate %s applies to no tax category", ErrInvalidTaxSettings, rate.Code)
+		}
+	}
+
+	if charge := settings.ServiceCharge; charge != nil {
+		if charge.Rate <= 0 || charge.Rate > 100 {
+			return fmt.Errorf("%w: service charge must be between 0 and 100 percent", ErrInvalidTaxSettings)
+		}
+		for _, orderType := range charge.OrderTypes {
+			if orderType != models.OrderTypeDineIn && orderType != models.OrderTypeTakeaway {
+				return fmt.Errorf("%w: unknown order type %q", ErrInvalidTaxSettings, orderType)
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetStoreTaxReport totals the taxes of a store's orders placed between two
+// YYYY-MM-DD dates in the store's time zone, both days included.
+// Cancelled and rejected orders are left out.
+func GetStoreTaxReport(store *models.Store, fromDate, toDate string) (*models.TaxReport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	from, to, err := taxReportRange(store, fromDate, toDate)
+	if err != nil {
+		return nil, err
+	}
+
+	match := bson.M{"$match": bson.M{
+		"store_id":   store.ID,
+		"created_at": bson.M{"$gte": from, "$lt": to},
+		"status":     bson.M{"$nin": bson.A{models.OrderStatusCancelled, models.OrderStatusRejected}},
+	}}
+
+	report := &models.TaxReport{
+		StoreID:  store.ID,
+		Currency: store.Currency,
+		From:     fromDate,
+		To:       toDate,
+		Taxes:    []models.TaxBreakdown{},
+	}
+
+	var totals []struct {
+		OrderCount     int          `bson:"order_count"`
+		Subtotal       money.Amount `bson:"subtotal"`
+		Discounts      money.Amount `bson:"discounts"`
+		ServiceCharges money.Amount `bson:"service_charges"`
+		TaxTotal       money.Amount `bson:"tax_total"`
+		Total          money.Amount `bson:"total"`
+	}
+	cursor, err := orderCollection.Aggregate(ctx, bson.A{
+		match,
+		bson.M{"$group": bson.M{
+			"_id":             nil,
+			"order_count":     bson.M{"$sum": 1},
+			"subtotal":        bson.M{"$sum": "$subtotal"},
+			"discounts":       bson.M{"$sum": "$discount_total"},
+			"service_charges": bson.M{"$sum": "$service_charge.amount"},
+			"tax_total":       bson.M{"$sum": "$tax_total"},
+			"total":           bson.M{"$sum": "$total"},
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(ctx, &totals); err != nil {
+		return nil, err
+	}
+	if len(totals) == 0 {
+		return report, nil
+	}
+	report.OrderCount = totals[0].OrderCount
+	report.Subtotal = totals[0].Subtotal
+	report.Discounts = totals[0].Discounts
+	report.ServiceCharges = totals[0].ServiceCharges
+	report.TaxTotal = totals[0].TaxTotal
+	report.Total = totals[0].Total
+
+	// One row per rate; a rate whose percentage changed gets a row per percentage
+	cursor, err = orderCollection.Aggregate(ctx, bson.A{
+		match,
+		bson.M{"$unwind": "$taxes"},
+		bson.M{"$group": bson.M{
+			"_id":            bson.M{"code": "$taxes.code", "rate": "$taxes.rate"},
+			"code":           bson.M{"$first": "$taxes.code"},
+			"name":           bson.M{"$last": "$taxes.name"},
+			"rate":           bson.M{"$first": "$taxes.rate"},
+			"taxable_amount": bson.M{"$sum": "$taxes.taxable_amount"},
+			"amount":         bson.M{"$sum": "$taxes.amount"},
+		}},
+		bson.M{"$sort": bson.D{{Key: "code", Value: 1}, {Key: "rate", Value: 1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(ctx, &report.Taxes); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// taxReportRange turns two YYYY-MM-DD dates into the instants that bound them in the store's time zone
+func taxReportRange(store *models.Store, fromDate, toDate string) (time.Time, time.Time, error) {
+	loc := store.Location()
+	from, err := time.ParseInLocation(models.OverrideDateLayout, fromDate, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: from must be a date in YYYY-MM-DD format", ErrInvalidReportRange)
+	}
+	to, err := time.ParseInLocation(models.OverrideDateLayout, toDate, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: to must be a date in YYYY-MM-DD format", ErrInvalidReportRange)
+	}
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: to is before from", ErrInvalidReportRange)
+	}
+	return from, to.AddDate(0, 0, 1), nil
+}
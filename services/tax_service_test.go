@@ -0,0 +1,175 @@
+package services
+
+import (
+	"testing"
+
+	"ordernew/models"
+	"ordernew/money"
+)
+
+func TestSplitLineTaxes(t *testing.T) {
+	tests := []struct {
+		name      string
+		inclusive bool
+		rates     []float64
+		total     money.Amount
+		want      []money.Amount
+		wantNet   money.Amount
+	}{
+		{"exclusive rounds half up", false, []float64{8.875}, 1099, []money.Amount{98}, 1099},
+		{"exclusive stacked rates", false, []float64{5, 7}, 1000, []money.Amount{50, 70}, 1000},
+		{"inclusive single rate", true, []float64{20}, 1200, []money.Amount{200}, 1000},
+		{"inclusive rounds the carved out tax", true, []float64{10}, 999, []money.Amount{91}, 908},
+		{"inclusive stacked rates share the divisor", true, []float64{5, 9.975}, 1000, []money.Amount{43, 87}, 870},
+		{"zero total", false, []float64{10}, 0, []money.Amount{0}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := &models.TaxSettings{PricesIncludeTax: tt.inclusive}
+			indexes := make([]int, len(tt.rates))
+			for i, rate := range tt.rates {
+				settings.Rates = append(settings.Rates, models.TaxRate{Code: string(rune('A' + i)), Rate: rate, Categories: []string{"food"}})
+				indexes[i] = i
+			}
+
+			taxes, net := splitLineTaxes(settings, indexes, tt.total)
+			if net != tt.wantNet {
+				t.Errorf("net = %d, want %d", net, tt.wantNet)
+			}
+			if len(taxes) != len(tt.want) {
+				t.Fatalf("got %d taxes, want %d", len(taxes), len(tt.want))
+			}
+			for i, tax := range taxes {
+				if tax.Amount != tt.want[i] || tax.Rate != tt.rates[i] {
+					t.Errorf("tax %d = %+v, want %d at %v%%", i, tax, tt.want[i], tt.rates[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCalculateTaxes(t *testing.T) {
+	salesTax := &models.TaxSettings{
+		DefaultCategory: "food",
+		Rates:           []models.TaxRate{{Code: "ST", Name: "Sales tax", Rate: 8.875, Categories: []string{"food"}}},
+	}
+	vat := &models.TaxSettings{
+		PricesIncludeTax: true,
+		DefaultCategory:  "food",
+		Rates: []models.TaxRate{
+			{Code: "VAT10", Name: "Reduced VAT", Rate: 10, Categories: []string{"food"}},
+			{Code: "VAT20", Name: "Standard VAT", Rate: 20, Categories: []string{"alcohol"}},
+		},
+		ServiceCharge: &models.ServiceCharge{Name: "Service", Rate: 10, OrderTypes: []string{"dine_in"}, TaxCategory: "food"},
+	}
+
+	tests := []struct {
+		name           string
+		settings       *models.TaxSettings
+		orderType      string
+		lines          []taxableLine
+		wantCategories []string
+		wantCharge     money.Amount
+		wantTaxes      map[string]money.Amount
+		wantTaxTotal   money.Amount
+		wantTotal      money.Amount
+	}{
+		{
+			name:           "exclusive tax is rounded per line",
+			settings:       salesTax,
+			lines:          []taxableLine{{Total: 333}, {Total: 333}, {Total: 334}},
+			wantCategories: []string{"food", "food", "food"},
+			wantTaxes:      map[string]money.Amount{"ST": 90},
+			wantTaxTotal:   90,
+			wantTotal:      1090,
+		},
+		{
+			name:           "inclusive tax with a taxed service charge",
+			settings:       vat,
+			orderType:      "dine_in",
+			lines:          []taxableLine{{Total: 550}, {Category: "alcohol", Total: 1200}},
+			wantCategories: []string{"food", "alcohol"},
+			wantCharge:     175,
+			wantTaxes:      map[string]money.Amount{"VAT10": 66, "VAT20": 200},
+			wantTaxTotal:   266,
+			wantTotal:      1925,
+		},
+		{
+			name:           "service charge only on its order types",
+			settings:       vat,
+			orderType:      "takeaway",
+			lines:          []taxableLine{{Total: 550}, {Category: "alcohol", Total: 1200}},
+			wantCategories: []string{"food", "alcohol"},
+			wantTaxes:      map[string]money.Amount{"VAT10": 50, "VAT20": 200},
+			wantTaxTotal:   250,
+			wantTotal:      1750,
+		},
+		{
+			name:           "category without rates is not taxed",
+			settings:       salesTax,
+			lines:          []taxableLine{{Category: "exempt", Total: 500}},
+			wantCategories: []string{"exempt"},
+			wantTaxes:      map[string]money.Amount{},
+			wantTotal:      500,
+		},
+		{
+			name:           "no tax settings",
+			lines:          []taxableLine{{Category: "food", Total: 500}},
+			wantCategories: []string{"food"},
+			wantTaxes:      map[string]money.Amount{},
+			wantTotal:      500,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := calculateTaxes(tt.settings, tt.orderType, tt.lines)
+
+			for i, category := range result.Categories {
+				if category != tt.wantCategories[i] {
+					t.Errorf("line %d category = %q, want %q", i, category, tt.wantCategories[i])
+				}
+			}
+			var charge money.Amount
+			if result.ServiceCharge != nil {
+				charge = result.ServiceCharge.Amount
+			}
+			if charge != tt.wantCharge {
+				t.Errorf("service charge = %d, want %d", charge, tt.wantCharge)
+			}
+			if result.TaxTotal != tt.wantTaxTotal || result.Total != tt.wantTotal {
+				t.Errorf("tax total, total = %d, %d, want %d, %d", result.TaxTotal, result.Total, tt.wantTaxTotal, tt.wantTotal)
+			}
+
+			// The per-rate totals are the sums of the rounded line taxes
+			lineSums := map[string]money.Amount{}
+			for _, taxes := range result.LineTaxes {
+				for _, tax := range taxes {
+					lineSums[tax.Code] += tax.Amount
+				}
+			}
+			if result.ServiceCharge != nil {
+				for _, tax := range result.ServiceCharge.Taxes {
+					lineSums[tax.Code] += tax.Amount
+				}
+			}
+			if len(result.Taxes) != len(tt.wantTaxes) {
+				t.Fatalf("got %d tax rates, want %d", len(result.Taxes), len(tt.wantTaxes))
+			}
+			var taxTotal money.Amount
+			for _, tax := range result.Taxes {
+				if tax.Amount != tt.wantTaxes[tax.Code] {
+					t.Errorf("%s = %d, want %d", tax.Code, tax.Amount, tt.wantTaxes[tax.Code])
+				}
+				if tax.Amount != lineSums[tax.Code] {
+					t.Errorf("%s = %d, but its lines add up to %d", tax.Code, tax.Amount, lineSums[tax.Code])
+				}
+				taxTotal += tax.Amount
+			}
+			if taxTotal != result.TaxTotal {
+				t.Errorf("rates add up to %d, want the tax total %d", taxTotal, result.TaxTotal)
+			}
+		})
+	}
+}
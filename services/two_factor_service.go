@@ -0,0 +1,522 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"ordernew/config"
+	"ordernew/models"
+	"ordernew/utils"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	// ErrInvalidTwoFactorCode is returned for wrong or reused two-factor codes
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	// ErrTwoFactorAlreadyEnabled is returned when enrolling a user who already uses two-factor authentication
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrTwoFactorNotEnabled is returned for two-factor actions on a user who has not enrolled
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+	// ErrTwoFactorSetupNotStarted is returned when confirming enrolment before requesting a secret
+	ErrTwoFactorSetupNotStarted = errors.New("two-factor setup has not been started")
+	// ErrTwoFactorRequired is returned when turning off two-factor authentication that the security settings require
+	ErrTwoFactorRequired = errors.New("two-factor authentication is required for this account")
+	// ErrInvalidTwoFactorChallenge is returned for unknown, expired or exhausted login challenges
+	ErrInvalidTwoFactorChallenge = errors.New("invalid or expired login challenge")
+)
+
+const (
+	twoFactorChallengeTTL = 5 * time.Minute
+	twoFactorMaxAttempts  = 5
+	recoveryCodeCount     = 10
+	totpPeriod            = 30 // seconds
+)
+
+var totpOpts = totp.ValidateOpts{Period: totpPeriod, Digits: otp.DigitsSix, Algorithm: otp.AlgorithmSHA1}
+
+// BeginTwoFactorSetup creates a TOTP secret for a user to add to their
+// authenticator app. It is only used once confirmed with EnableTwoFactor.
+func BeginTwoFactorSetup(userID primitive.ObjectID) (*models.TwoFactorSetupResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := getActiveUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	accountName := user.Email
+	if accountName == "" {
+		accountName = user.Phone
+	}
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      config.AppConfig.TOTPIssuer,
+		AccountName: accountName,
+		Period:      totpPeriod,
+		Digits:      otp.DigitsSix,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		return nil, errors.New("failed to generate two-factor secret")
+	}
+
+	_, err = userCollection.UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{"$set": bson.M{"two_factor_pending_secret": key.Secret(), "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	level, _ := utils.ParseQRRecoveryLevel("M")
+	png, err := utils.GenerateQRPNG(key.URL(), 256, level)
+	if err != nil {
+		return nil, errors.New("failed to generate QR code")
+	}
+
+	return &models.TwoFactorSetupResponse{
+		Secret:          key.Secret(),
+		ProvisioningURI: key.URL(),
+		QRCode:          "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	}, nil
+}
+
+// EnableTwoFactor confirms enrolment with a code from the authenticator app.
+// It returns the recovery codes, which are only shown this once, and signs out
+// the user's other sessions.
+func EnableTwoFactor(userID primitive.ObjectID, code, currentSessionID string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := getActiveUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if user.TwoFactorPendingSecret == "" {
+		return nil, ErrTwoFactorSetupNotStarted
+	}
+
+	step, ok := matchTOTPStep(user.TwoFactorPendingSecret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := userCollection.UpdateOne(ctx,
+		bson.M{"_id": userID, "two_factor_pending_secret": user.TwoFactorPendingSecret},
+		bson.M{
+			"$set": bson.M{
+				"two_factor_enabled":   true,
+				"two_factor_secret":    user.TwoFactorPendingSecret,
+				"two_factor_last_step": step,
+				"recovery_code_hashes": hashes,
+				"updated_at":           time.Now(),
+			},
+			"$unset": bson.M{"two_factor_pending_secret": ""},
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, ErrTwoFactorSetupNotStarted
+	}
+
+	// Sessions that never passed two-factor authentication stop working
+	if err := RevokeOtherSessions(userID, currentSessionID); err != nil {
+		log.Println("Warning: failed to revoke sessions after enabling two-factor authentication:", err)
+	}
+
+	return codes, nil
+}
+
+// DisableTwoFactor turns off two-factor authentication after checking a
+// TOTP or recovery code, unless the security settings require it
+func DisableTwoFactor(userID primitive.ObjectID, code string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := getActiveUser(userID)
+	if err != nil {
+		return err
+	}
+	if !user.TwoFactorEnabled {
+		return ErrTwoFactorNotEnabled
+	}
+	required, err := TwoFactorRequired(user)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrTwoFactorRequired
+	}
+	if err := verifyTwoFactorCode(user, code); err != nil {
+		return err
+	}
+
+	_, err = userCollection.UpdateOne(ctx, bson.M{"_id": userID}, disableTwoFactorUpdate())
+	return err
+}
+
+// RegenerateRecoveryCodes replaces a user's recovery codes after checking a TOTP code
+func RegenerateRecoveryCodes(userID primitive.ObjectID, code string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := getActiveUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TwoFactorEnabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if err := verifyTOTPCode(ctx, user, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = userCollection.UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{"$set": bson.M{"recovery_code_hashes": hashes, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// ResetTwoFactor turns off two-factor authentication for a user who lost
+// their device and recovery codes, and signs them out everywhere
+func ResetTwoFactor(userID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user ID")
+	}
+
+	result, err := userCollection.UpdateOne(ctx, bson.M{"_id": objectID}, disableTwoFactorUpdate())
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("user not found")
+	}
+
+	return RevokeAllSessions(objectID)
+}
+
+// TwoFactorRequired reports whether the security settings require a user to
+// use two-factor authentication. It applies to admins and store owners.
+// An error means the policy could not be checked, and callers must refuse.
+func TwoFactorRequired(user *models.User) (bool, error) {
+	settings, err := GetSecuritySettings()
+	if err != nil {
+		return false, err
+	}
+	if !settings.RequireOwnerTwoFactor {
+		return false, nil
+	}
+	if user.Role == models.RoleAdmin {
+		return true, nil
+	}
+	return ownsStores(user.ID)
+}
+
+// Whether a user owns stores is checked on privileged requests while the
+// two-factor policy is on, so the answer is cached as briefly as the settings.
+// generation changes whenever an entry is forgotten, so a load that raced
+// with an ownership change is not cached.
+var storeOwnerCache struct {
+	sync.RWMutex
+	owners     map[primitive.ObjectID]storeOwnerEntry
+	generation uint64
+}
+
+// storeOwnerSweeper starts the loop that drops expired cache entries
+var storeOwnerSweeper sync.Once
+
+type storeOwnerEntry struct {
+	ownsStores bool
+	loadedAt   time.Time
+}
+
+// ownsStores reports whether a user owns at least one store
+func ownsStores(userID primitive.ObjectID) (bool, error) {
+	storeOwnerCache.RLock()
+	entry, ok := storeOwnerCache.owners[userID]
+	generation := storeOwnerCache.generation
+	storeOwnerCache.RUnlock()
+	if ok && time.Since(entry.loadedAt) < securitySettingsCacheTTL {
+		return entry.ownsStores, nil
+	}
+
+	// The lock is not held while loading, so a miss does not hold up other requests
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	loadedAt := time.Now()
+	count, err := storeCollection.CountDocuments(ctx, bson.M{"owner_id": userID})
+	if err != nil {
+		return false, err
+	}
+
+	storeOwnerCache.Lock()
+	if storeOwnerCache.generation == generation {
+		if storeOwnerCache.owners == nil {
+			storeOwnerCache.owners = make(map[primitive.ObjectID]storeOwnerEntry)
+		}
+		storeOwnerCache.owners[userID] = storeOwnerEntry{ownsStores: count > 0, loadedAt: loadedAt}
+	}
+	storeOwnerCache.Unlock()
+	storeOwnerSweeper.Do(func() { go sweepStoreOwnerCache() })
+
+	return count > 0, nil
+}
+
+// sweepStoreOwnerCache drops expired store ownership entries in the background,
+// so the cache only holds recently active users
+func sweepStoreOwnerCache() {
+	ticker := time.NewTicker(securitySettingsCacheTTL)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		storeOwnerCache.Lock()
+		for id, entry := range storeOwnerCache.owners {
+			if time.Since(entry.loadedAt) >= securitySettingsCacheTTL {
+				delete(storeOwnerCache.owners, id)
+			}
+		}
+		storeOwnerCache.Unlock()
+	}
+}
+
+// forgetStoreOwner drops a user's cached store ownership after it changed
+func forgetStoreOwner(userID primitive.ObjectID) {
+	storeOwnerCache.Lock()
+	defer storeOwnerCache.Unlock()
+	delete(storeOwnerCache.owners, userID)
+	storeOwnerCache.generation++
+}
+
+// startLogin finishes the first login step: users with two-factor
+// authentication get a challenge, everyone else gets a session
+func startLogin(user *models.User, method, userAgent, ipAddress string) (*models.LoginResult, error) {
+	if user.TwoFactorEnabled {
+		// Failed logins are only reset once the second factor is verified too
+		token, err := issueAuthToken(user, models.AuthTokenTwoFactorLogin, twoFactorChallengeTTL)
+		if err != nil {
+			return nil, err
+		}
+		return &models.LoginResult{Challenge: &models.TwoFactorChallenge{
+			ChallengeToken: token,
+			ExpiresIn:      int64(twoFactorChallengeTTL.Seconds()),
+		}}, nil
+	}
+
+	return completeLogin(user, method, userAgent, ipAddress)
+}
+
+// completeLogin starts a session for a user who passed every login step
+func completeLogin(user *models.User, method, userAgent, ipAddress string) (*models.LoginResult, error) {
+	tokens, err := CreateSession(user, userAgent, ipAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	resetLoginFailures(user)
+	recordLoginEvent(models.LoginEvent{
+		UserID:    user.ID,
+		Email:     user.Email,
+		Phone:     user.Phone,
+		Method:    method,
+		Success:   true,
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+	})
+
+	response := user.ToUserResponse()
+	return &models.LoginResult{Tokens: tokens, User: &response}, nil
+}
+
+// CompleteTwoFactorLogin checks the two-factor code for a login challenge and starts a session
+func CompleteTwoFactorLogin(challengeToken, code, userAgent, ipAddress string) (*models.LoginResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var challenge models.AuthToken
+	err := authTokenCollection.FindOne(ctx, bson.M{
+		"token_hash": utils.HashToken(challengeToken),
+		"purpose":    models.AuthTokenTwoFactorLogin,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}).Decode(&challenge)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrInvalidTwoFactorChallenge
+		}
+		return nil, err
+	}
+
+	user, err := getActiveUser(challenge.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TwoFactorEnabled {
+		return nil, ErrInvalidTwoFactorChallenge
+	}
+	if err := checkAccountLock(user); err != nil {
+		return nil, err
+	}
+
+	if err := verifyTwoFactorCode(user, code); err != nil {
+		// Wrong codes count towards the account lock like wrong passwords
+		registerLoginFailure(user.ID)
+		recordLoginEvent(models.LoginEvent{
+			UserID:        user.ID,
+			Email:         user.Email,
+			Method:        models.LoginMethodTwoFactor,
+			FailureReason: models.LoginFailureInvalidCode,
+			IPAddress:     ipAddress,
+			UserAgent:     userAgent,
+		})
+
+		// A challenge allows a few tries before the password has to be entered again
+		if challenge.Attempts+1 >= twoFactorMaxAttempts {
+			authTokenCollection.DeleteOne(ctx, bson.M{"_id": challenge.ID})
+		} else {
+			authTokenCollection.UpdateOne(ctx, bson.M{"_id": challenge.ID}, bson.M{"$inc": bson.M{"attempts": 1}})
+		}
+		return nil, err
+	}
+
+	result, err := authTokenCollection.DeleteOne(ctx, bson.M{"_id": challenge.ID})
+	if err != nil {
+		return nil, err
+	}
+	if result.DeletedCount == 0 {
+		return nil, ErrInvalidTwoFactorChallenge
+	}
+
+	return completeLogin(user, models.LoginMethodTwoFactor, userAgent, ipAddress)
+}
+
+// verifyTwoFactorCode accepts a TOTP code, or else uses up one of the user's recovery codes
+func verifyTwoFactorCode(user *models.User, code string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	code = strings.TrimSpace(code)
+	if len(code) == int(otp.DigitsSix) {
+		return verifyTOTPCode(ctx, user, code)
+	}
+
+	hash := utils.HashToken(normalizeRecoveryCode(code))
+	result, err := userCollection.UpdateOne(ctx,
+		bson.M{"_id": user.ID, "recovery_code_hashes": hash},
+		bson.M{"$pull": bson.M{"recovery_code_hashes": hash}, "$set": bson.M{"updated_at": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// verifyTOTPCode checks a TOTP code and records its time step, so each code works only once
+func verifyTOTPCode(ctx context.Context, user *models.User, code string) error {
+	step, ok := matchTOTPStep(user.TwoFactorSecret, strings.TrimSpace(code), time.Now())
+	if !ok || step <= user.TwoFactorLastStep {
+		return ErrInvalidTwoFactorCode
+	}
+
+	result, err := userCollection.UpdateOne(ctx,
+		bson.M{"_id": user.ID, "two_factor_last_step": bson.M{"$lt": step}},
+		bson.M{"$set": bson.M{"two_factor_last_step": step}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// matchTOTPStep returns the time step a TOTP code belongs to, allowing one
+// step of clock drift either way
+func matchTOTPStep(secret, code string, now time.Time) (int64, bool) {
+	for _, skew := range []int64{0, -1, 1} {
+		at := now.Add(time.Duration(skew*totpPeriod) * time.Second)
+		expected, err := totp.GenerateCodeCustom(secret, at, totpOpts)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return at.Unix() / totpPeriod, true
+		}
+	}
+	return 0, false
+}
+
+// generateRecoveryCodes returns new recovery codes with the hashes to store
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw, err := utils.GenerateRandomToken(5)
+		if err != nil {
+			return nil, nil, errors.New("failed to generate recovery codes")
+		}
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, utils.HashToken(raw))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode strips the formatting users may type around a recovery code
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// disableTwoFactorUpdate clears every two-factor field of a user
+func disableTwoFactorUpdate() bson.M {
+	return bson.M{
+		"$set": bson.M{"two_factor_enabled": false, "updated_at": time.Now()},
+		"$unset": bson.M{
+			"two_factor_secret":         "",
+			"two_factor_pending_secret": "",
+			"two_factor_last_step":      "",
+			"recovery_code_hashes":      "",
+		},
+	}
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+)
+
+func TestMatchTOTPStep(t *testing.T) {
+	const secret = "JBSWY3DPEHPK3PXP"
+	now := time.Unix(1_000_000_040, 0) // 20 seconds into step 33333334
+	step := now.Unix() / totpPeriod
+
+	codeAt := func(offset time.Duration) string {
+		code, err := totp.GenerateCodeCustom(secret, now.Add(offset), totpOpts)
+		if err != nil {
+			t.Fatalf("failed to generate code: %v", err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", secret, codeAt(0), step, true},
+		{"earlier in the current step", secret, codeAt(-20 * time.Second), step, true},
+		{"previous step", secret, codeAt(-30 * time.Second), step - 1, true},
+		{"next step", secret, codeAt(30 * time.Second), step + 1, true},
+		{"two steps behind", secret, codeAt(-60 * time.Second), 0, false},
+		{"two steps ahead", secret, codeAt(60 * time.Second), 0, false},
+		{"wrong code", secret, "000000", 0, false},
+		{"code with extra digits", secret, codeAt(0) + "0", 0, false},
+		{"invalid secret", "not base32!", "123456", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, ok := matchTOTPStep(tt.secret, tt.code, now)
+			if ok != tt.wantOK || gotStep != tt.wantStep {
+				t.Errorf("matchTOTPStep() = %d, %v, want %d, %v", gotStep, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"errors"
+	"log"
 	"time"
 
 	"ordernew/config"
@@ -12,6 +13,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 var userCollection *mongo.Collection
@@ -24,6 +26,29 @@ func init() {
 // InitUserCollection initializes the user collection
 func InitUserCollection() {
 	userCollection = config.GetCollection("users")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Phone numbers identify OTP logins, so each belongs to one user. Users without a phone are not indexed.
+	_, err := userCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "phone", Value: 1}},
+		Options: options.Index().SetUnique(true).
+			SetPartialFilterExpression(bson.M{"phone": bson.M{"$gt": ""}}),
+	})
+	if err != nil {
+		log.Println("Warning: failed to create user phone index:", err)
+	}
+
+	// Email addresses identify password logins. Phone-only users have no email and are not indexed.
+	_, err = userCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "email", Value: 1}},
+		Options: options.Index().SetUnique(true).
+			SetPartialFilterExpression(bson.M{"email": bson.M{"$gt": ""}}),
+	})
+	if err != nil {
+		log.Println("Warning: failed to create user email index:", err)
+	}
 }
 
 type UserService struct {
@@ -61,7 +86,7 @@ func (s *UserService) Register(req models.RegisterRequest) (*models.UserResponse
 		Name:      req.Name,
 		Email:     req.Email,
 		Password:  hashedPassword,
-		Role:      "user",
+		Role:      models.RoleUser,
 		IsActive:  true,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
@@ -72,40 +97,64 @@ func (s *UserService) Register(req models.RegisterRequest) (*models.UserResponse
 		return nil, errors.New("failed to create user")
 	}
 
+	// The account works without it, so a mail failure does not fail registration
+	if err := SendVerificationEmail(&user); err != nil {
+		log.Println("Warning: failed to send verification email:", err)
+	}
+
 	response := user.ToUserResponse()
 	return &response, nil
 }
 
-// Login authenticates a user and returns a token
-func (s *UserService) Login(req models.LoginRequest) (string, *models.UserResponse, error) {
+// Login authenticates a user and starts a session for them, or returns a
+// two-factor challenge if the user has two-factor authentication enabled
+func (s *UserService) Login(req models.LoginRequest, userAgent, ipAddress string) (*models.LoginResult, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	event := models.LoginEvent{Email: req.Email, Method: models.LoginMethodPassword, IPAddress: ipAddress, UserAgent: userAgent}
+
+	// Throttle addresses with many failed logins
+	if err := checkLoginIPThrottle(ipAddress); err != nil {
+		event.FailureReason = models.LoginFailureRateLimited
+		recordLoginEvent(event)
+		return nil, err
+	}
+
 	// Find user by email
 	var user models.User
 	err := s.collection.FindOne(ctx, bson.M{"email": req.Email}).Decode(&user)
 	if err != nil {
-		return "", nil, errors.New("invalid email or password")
+		event.FailureReason = models.LoginFailureUnknownEmail
+		recordLoginEvent(event)
+		return nil, errors.New("invalid email or password")
+	}
+	event.UserID = user.ID
+
+	// A locked account does not get its password checked at all
+	if err := checkAccountLock(&user); err != nil {
+		event.FailureReason = models.LoginFailureLocked
+		recordLoginEvent(event)
+		return nil, err
 	}
 
 	// Check password
 	if !utils.CheckPasswordHash(req.Password, user.Password) {
-		return "", nil, errors.New("invalid email or password")
+		registerLoginFailure(user.ID)
+		event.FailureReason = models.LoginFailureInvalidCredentials
+		recordLoginEvent(event)
+		return nil, errors.New("invalid email or password")
 	}
 
 	// Check if user is active
 	if !user.IsActive {
-		return "", nil, errors.New("user account is inactive")
+		event.FailureReason = models.LoginFailureInactive
+		recordLoginEvent(event)
+		return nil, errors.New("user account is inactive")
 	}
 
-	// Generate token
-	token, err := utils.GenerateToken(user.ID.Hex(), user.Email, user.Role)
-	if err != nil {
-		return "", nil, errors.New("failed to generate token")
-	}
-
-	response := user.ToUserResponse()
-	return token, &response, nil
+	// Start a session with a short-lived access token and a refresh token
+	return startLogin(&user, models.LoginMethodPassword, userAgent, ipAddress)
 }
 
 // GetUserByID retrieves a user by ID
@@ -152,7 +201,8 @@ func (s *UserService) GetAllUsers() ([]models.UserResponse, error) {
 	return responses, nil
 }
 
-// UpdateUser updates user information
+// UpdateUser updates user information. Users change their own email address
+// with ChangeEmail, which checks their password.
 func (s *UserService) UpdateUser(userID string, req models.UpdateUserRequest) (*models.UserResponse, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -170,8 +220,25 @@ func (s *UserService) UpdateUser(userID string, req models.UpdateUserRequest) (*
 	if req.Name != "" {
 		update["name"] = req.Name
 	}
+	emailChanged := false
 	if req.Email != "" {
-		update["email"] = req.Email
+		var current models.User
+		if err := s.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&current); err != nil {
+			return nil, errors.New("user not found")
+		}
+		// A new address has to be verified again
+		if req.Email != current.Email {
+			count, err := s.collection.CountDocuments(ctx, bson.M{"email": req.Email, "_id": bson.M{"$ne": objectID}})
+			if err != nil {
+				return nil, err
+			}
+			if count > 0 {
+				return nil, ErrEmailInUse
+			}
+			emailChanged = true
+			update["email"] = req.Email
+			update["email_verified"] = false
+		}
 	}
 	if req.Role != "" {
 		update["role"] = req.Role
@@ -180,17 +247,40 @@ func (s *UserService) UpdateUser(userID string, req models.UpdateUserRequest) (*
 		update["is_active"] = *req.IsActive
 	}
 
+	changes := bson.M{"$set": update}
+	if emailChanged {
+		changes["$unset"] = bson.M{"email_verified_at": ""}
+	}
+
 	// Update user
-	result := s.collection.FindOneAndUpdate(
+	var updated models.User
+	err = s.collection.FindOneAndUpdate(
 		ctx,
 		bson.M{"_id": objectID},
-		bson.M{"$set": update},
-	)
+		changes,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
 
-	if result.Err() != nil {
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrEmailInUse
+		}
 		return nil, errors.New("user not found")
 	}
 
+	if emailChanged {
+		if err := SendVerificationEmail(&updated); err != nil {
+			log.Println("Warning: failed to send verification email:", err)
+		}
+	}
+
+	// Disabling an account signs it out everywhere
+	if req.IsActive != nil && !*req.IsActive {
+		if err := RevokeAllSessions(objectID); err != nil {
+			return nil, errors.New("failed to revoke user sessions")
+		}
+	}
+
 	// Get updated user
 	return s.GetUserByID(userID)
 }
@@ -214,5 +304,16 @@ func (s *UserService) DeleteUser(userID string) error {
 		return errors.New("user not found")
 	}
 
+	// Remove the sessions, emailed tokens and store memberships of the deleted user
+	if _, err := sessionCollection.DeleteMany(ctx, bson.M{"user_id": objectID}); err != nil {
+		return errors.New("failed to delete user sessions")
+	}
+	if _, err := authTokenCollection.DeleteMany(ctx, bson.M{"user_id": objectID}); err != nil {
+		return errors.New("failed to delete user tokens")
+	}
+	if _, err := storeMemberCollection.DeleteMany(ctx, bson.M{"user_id": objectID}); err != nil {
+		return errors.New("failed to delete user store memberships")
+	}
+
 	return nil
 }
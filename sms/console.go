@@ -0,0 +1,12 @@
+package sms
+
+import "log"
+
+// ConsoleSender is a development sender that prints messages to the log
+type ConsoleSender struct{}
+
+// Send logs a message instead of delivering it
+func (s *ConsoleSender) Send(to, body string) error {
+	log.Printf("SMS (not sent) to %s: %s", to, body)
+	return nil
+}
@@ -0,0 +1,33 @@
+package sms
+
+import (
+	"fmt"
+	"strings"
+
+	"ordernew/config"
+)
+
+// Sender sends text messages to phone numbers in E.164 format
+type Sender interface {
+	Send(to, body string) error
+}
+
+// New builds the sender selected by SMS_DRIVER: "twilio" sends real messages,
+// "console" (the default) prints them to the log
+func New() (Sender, error) {
+	switch strings.ToLower(config.AppConfig.SMSDriver) {
+	case "twilio":
+		if config.AppConfig.TwilioAccountSID == "" || config.AppConfig.TwilioAuthToken == "" || config.AppConfig.TwilioFrom == "" {
+			return nil, fmt.Errorf("TWILIO_ACCOUNT_SID, TWILIO_AUTH_TOKEN and TWILIO_FROM are required when SMS_DRIVER=twilio")
+		}
+		return &TwilioSender{
+			AccountSID: config.AppConfig.TwilioAccountSID,
+			AuthToken:  config.AppConfig.TwilioAuthToken,
+			From:       config.AppConfig.TwilioFrom,
+		}, nil
+	case "", "console":
+		return &ConsoleSender{}, nil
+	default:
+		return nil, fmt.Errorf("unknown SMS_DRIVER %q", config.AppConfig.SMSDriver)
+	}
+}
@@ -126,7 +126,7 @@ async function runTests() {
     const productData = {
         name: 'Test Laptop',
         description: 'High performance test laptop',
-        price: 1299.99,
+        price: 129999,
         quantity: 50,
         category: 'Electronics',
         sku: `TEST-LAP-${Date.now()}`
@@ -166,7 +166,7 @@ async function runTests() {
         const updateProductData = {
             name: 'Test Gaming Laptop',
             description: 'Updated gaming laptop',
-            price: 1599.99,
+            price: 159999,
             quantity: 30
         };
         const updateProduct = await makeRequest(`/products/${productId}`, 'PUT', updateProductData, true);
@@ -177,7 +177,7 @@ async function runTests() {
     if (productId) {
         console.log(`\n${colors.blue}[13] Testing Update Product (PATCH)...${colors.reset}`);
         const patchProductData = {
-            price: 1499.99
+            price: 149999
         };
         const patchProduct = await makeRequest(`/products/${productId}`, 'PATCH', patchProductData, true);
         logTest(`PATCH /api/v1/products/${productId}`, patchProduct.ok, patchProduct.data?.message);
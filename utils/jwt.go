@@ -10,23 +10,31 @@ import (
 )
 
 type Claims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	SessionID string `json:"sid"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken generates a JWT token for a user
-func GenerateToken(userID, email, role string) (string, error) {
+// AccessTokenExpiry returns how long an access token is valid
+func AccessTokenExpiry() time.Duration {
 	expiryDuration, err := time.ParseDuration(config.AppConfig.JWTExpiry)
 	if err != nil {
-		expiryDuration = 24 * time.Hour // Default to 24 hours
+		expiryDuration = 15 * time.Minute // Default to 15 minutes
 	}
+	return expiryDuration
+}
+
+// GenerateToken generates a JWT access token for a user's session
+func GenerateToken(userID, email, role, sessionID string) (string, error) {
+	expiryDuration := AccessTokenExpiry()
 
 	claims := Claims{
-		UserID: userID,
-		Email:  email,
-		Role:   role,
+		UserID:    userID,
+		Email:     email,
+		Role:      role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiryDuration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -34,8 +42,7 @@ func GenerateToken(userID, email, role string) (string, error) {
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(config.AppConfig.JWTSecret))
+	tokenString, err := signToken(claims)
 	if err != nil {
 		return "", err
 	}
@@ -45,12 +52,7 @@ func GenerateToken(userID, email, role string) (string, error) {
 
 // ValidateToken validates a JWT token and returns the claims
 func ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("invalid signing method")
-		}
-		return []byte(config.AppConfig.JWTSecret), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, verificationKey)
 
 	if err != nil {
 		return nil, err
@@ -0,0 +1,217 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"ordernew/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwtKey is a key that signs or verifies access tokens, identified by its kid
+type jwtKey struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private crypto.Signer // nil for keys that only verify
+	Public  crypto.PublicKey
+}
+
+// jwtKeyRing holds the asymmetric keys loaded from JWT_KEYS_DIR
+type jwtKeyRing struct {
+	keys    map[string]*jwtKey
+	signing *jwtKey
+}
+
+// jwtKeys is nil when no key directory is configured; tokens are then signed
+// with HS256 and JWT_SECRET
+var jwtKeys *jwtKeyRing
+
+// JWK is a public key in JSON Web Key format
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKSet is the document served at /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// LoadSigningKeys loads the PEM keys in JWT_KEYS_DIR. Each file is one key and
+// its name without extension is the kid. Private keys (RSA or Ed25519) can sign
+// and verify; public keys only verify, which keeps tokens of a retired key valid
+// until they expire. JWT_SIGNING_KID selects the key that signs new tokens.
+func LoadSigningKeys() error {
+	dir := config.AppConfig.JWTKeysDir
+	if dir == "" {
+		jwtKeys = nil
+		return nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	ring := &jwtKeyRing{keys: map[string]*jwtKey{}}
+	for _, path := range paths {
+		kid := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		key, err := loadJWTKey(kid, path)
+		if err != nil {
+			return fmt.Errorf("jwt key %s: %w", kid, err)
+		}
+		ring.keys[kid] = key
+	}
+	if len(ring.keys) == 0 {
+		return fmt.Errorf("no .pem keys found in %s", dir)
+	}
+
+	kid := config.AppConfig.JWTSigningKID
+	if kid == "" {
+		return errors.New("JWT_SIGNING_KID is required when JWT_KEYS_DIR is set")
+	}
+	signing, ok := ring.keys[kid]
+	if !ok {
+		return fmt.Errorf("signing key %s not found in %s", kid, dir)
+	}
+	if signing.Private == nil {
+		return fmt.Errorf("signing key %s has no private key", kid)
+	}
+	ring.signing = signing
+
+	jwtKeys = ring
+	return nil
+}
+
+// loadJWTKey parses a PEM file holding an RSA or Ed25519 private or public key
+func loadJWTKey(kid, path string) (*jwtKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &jwtKey{ID: kid}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.Private, key.Public = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.Public = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Method, key.Private, key.Public = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Method, key.Public = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, errors.New("only RSA and Ed25519 keys are supported")
+	}
+
+	if rsaKey, ok := key.Public.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < 2048 {
+		return nil, errors.New("RSA keys must be at least 2048 bits")
+	}
+
+	return key, nil
+}
+
+// signToken signs a token with the active key, or with JWT_SECRET when no keys are configured
+func signToken(claims jwt.Claims) (string, error) {
+	if jwtKeys == nil {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return token.SignedString([]byte(config.AppConfig.JWTSecret))
+	}
+
+	token := jwt.NewWithClaims(jwtKeys.signing.Method, claims)
+	token.Header["kid"] = jwtKeys.signing.ID
+	return token.SignedString(jwtKeys.signing.Private)
+}
+
+// verificationKey picks the key that verifies a token, by its kid and algorithm
+func verificationKey(token *jwt.Token) (interface{}, error) {
+	if jwtKeys == nil {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("invalid signing method")
+		}
+		return []byte(config.AppConfig.JWTSecret), nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := jwtKeys.keys[kid]
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, errors.New("invalid signing method")
+	}
+	return key.Public, nil
+}
+
+// GetJWKS returns the public keys that verify access tokens. It is empty when
+// tokens are signed with the shared JWT_SECRET.
+func GetJWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	if jwtKeys == nil {
+		return set
+	}
+
+	kids := make([]string, 0, len(jwtKeys.keys))
+	for kid := range jwtKeys.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	for _, kid := range kids {
+		key := jwtKeys.keys[kid]
+		jwk := JWK{Kid: kid, Use: "sig", Alg: key.Method.Alg()}
+		switch pub := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set
+}
@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"strings"
+
+	"ordernew/config"
+)
+
+// GenerateNumericCode returns a cryptographically random code of n digits
+func GenerateNumericCode(n int) (string, error) {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		digit, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(byte('0' + digit.Int64()))
+	}
+	return b.String(), nil
+}
+
+// HashOTP returns a keyed hash of a one-time code. Codes are short enough to
+// brute force from a plain hash, so the hash is an HMAC with the server secret.
+func HashOTP(phone, code string) string {
+	mac := hmac.New(sha256.New, []byte(config.AppConfig.JWTSecret))
+	mac.Write([]byte("otp:" + phone + ":" + code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// CompareOTPHash checks a one-time code against a stored hash in constant time
+func CompareOTPHash(phone, code, hash string) bool {
+	return hmac.Equal([]byte(HashOTP(phone, code)), []byte(hash))
+}
@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"ordernew/config"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/skip2/go-qrcode"
+)
+
+// QR code size limits in pixels
+const (
+	DefaultQRSize = 256
+	MinQRSize     = 64
+	MaxQRSize     = 2048
+)
+
+// QRSheetEntry is one labelled code on a printable QR sheet
+type QRSheetEntry struct {
+	Label   string
+	Content string
+}
+
+// BuildMenuURL builds the customer menu URL encoded in store and table QR codes
+func BuildMenuURL(storeID, tableToken string) string {
+	menuURL := strings.TrimRight(config.AppConfig.MenuBaseURL, "/") + "/" + storeID
+	if tableToken != "" {
+		menuURL += "?table_token=" + url.QueryEscape(tableToken)
+	}
+	return menuURL
+}
+
+// ParseQRRecoveryLevel converts an error-correction level (L, M, Q or H) to a QR recovery level
+func ParseQRRecoveryLevel(level string) (qrcode.RecoveryLevel, error) {
+	switch strings.ToUpper(level) {
+	case "L":
+		return qrcode.Low, nil
+	case "", "M":
+		return qrcode.Medium, nil
+	case "Q":
+		return qrcode.High, nil
+	case "H":
+		return qrcode.Highest, nil
+	}
+	return qrcode.Medium, errors.New("error correction level must be one of L, M, Q, H")
+}
+
+// GenerateQRPNG renders content as a square PNG QR code of size pixels
+func GenerateQRPNG(content string, size int, level qrcode.RecoveryLevel) ([]byte, error) {
+	code, err := qrcode.New(content, level)
+	if err != nil {
+		return nil, err
+	}
+	return code.PNG(size)
+}
+
+// GenerateQRSVG renders content as a square SVG QR code of size pixels
+func GenerateQRSVG(content string, size int, level qrcode.RecoveryLevel) ([]byte, error) {
+	code, err := qrcode.New(content, level)
+	if err != nil {
+		return nil, err
+	}
+
+	bitmap := code.Bitmap()
+	modules := len(bitmap)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size, modules, modules)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#ffffff"/>`, modules, modules)
+	buf.WriteString(`<path fill="#000000" d="`)
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&buf, "M%d %dh1v1h-1z", x, y)
+			}
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+
+	return buf.Bytes(), nil
+}
+
+// GenerateQRSheetPDF lays out labelled QR codes on printable A4 pages, six per page
+func GenerateQRSheetPDF(title string, entries []QRSheetEntry) ([]byte, error) {
+	const (
+		columns  = 2
+		rows     = 3
+		cellW    = 95.0
+		cellH    = 85.0
+		codeSize = 60.0
+		marginX  = 10.0
+		marginY  = 20.0
+	)
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle(title, true)
+	translate := pdf.UnicodeTranslatorFromDescriptor("")
+
+	for i, entry := range entries {
+		if i%(columns*rows) == 0 {
+			pdf.AddPage()
+			pdf.SetFont("Helvetica", "B", 16)
+			pdf.CellFormat(0, 10, translate(title), "", 1, "C", false, 0, "")
+		}
+
+		png, err := GenerateQRPNG(entry.Content, 512, qrcode.High)
+		if err != nil {
+			return nil, err
+		}
+
+		slot := i % (columns * rows)
+		x := marginX + float64(slot%columns)*cellW
+		y := marginY + float64(slot/columns)*cellH
+
+		imageName := fmt.Sprintf("qr-%d", i)
+		pdf.RegisterImageOptionsReader(imageName, gofpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(png))
+		pdf.ImageOptions(imageName, x+(cellW-codeSize)/2, y, codeSize, codeSize, false, gofpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+
+		pdf.SetXY(x, y+codeSize+2)
+		pdf.SetFont("Helvetica", "B", 14)
+		pdf.CellFormat(cellW, 8, translate(entry.Label), "", 0, "C", false, 0, "")
+	}
+
+	if len(entries) == 0 {
+		pdf.AddPage()
+		pdf.SetFont("Helvetica", "", 12)
+		pdf.CellFormat(0, 10, "No tables to print", "", 1, "C", false, 0, "")
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"ordernew/config"
+)
+
+// GenerateTableToken signs a table so its QR code cannot be forged for another table.
+// The token has the form "<table_id>.<signature>".
+func GenerateTableToken(storeID, tableID, nonce string) string {
+	return tableID + "." + signTable(storeID, tableID, nonce)
+}
+
+// ParseTableToken extracts the table ID from a table token without verifying it
+func ParseTableToken(token string) (string, error) {
+	tableID, _, found := strings.Cut(token, ".")
+	if !found || tableID == "" {
+		return "", errors.New("invalid table token")
+	}
+	return tableID, nil
+}
+
+// VerifyTableToken checks a table token against the table it claims to belong to
+func VerifyTableToken(token, storeID, tableID, nonce string) bool {
+	expected := GenerateTableToken(storeID, tableID, nonce)
+	return hmac.Equal([]byte(token), []byte(expected))
+}
+
+// signTable computes the HMAC signature of a table
+func signTable(storeID, tableID, nonce string) string {
+	mac := hmac.New(sha256.New, []byte(config.AppConfig.TableTokenSecret))
+	mac.Write([]byte("table:" + storeID + ":" + tableID + ":" + nonce))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}